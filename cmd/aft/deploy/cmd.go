@@ -5,8 +5,16 @@ Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 package deploy
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/edgarsilva948/aftctl/pkg/aws"
+	"github.com/edgarsilva948/aftctl/pkg/config"
+	"github.com/edgarsilva948/aftctl/pkg/export"
 	"github.com/edgarsilva948/aftctl/pkg/initialcommit"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/notify"
+	"github.com/edgarsilva948/aftctl/pkg/output"
 	"github.com/spf13/cobra"
 )
 
@@ -28,7 +36,7 @@ var args struct {
 	aftFeatureEnterpriseSupport        bool
 	aftFeatureDeleteDefaultVPCsEnabled bool
 
-	// deployment resources args
+	// deployment resources args, the region is resolved by the AWS client
 	region                     string
 	branchName                 string
 	gitSourceRepo              string
@@ -41,6 +49,39 @@ var args struct {
 	codeBuildRoleName          string
 	projectName                string
 	pipelineName               string
+
+	// iam args
+	codeBuildManagedPolicyArns []string
+	rolePath                   string
+	rolePermissionsBoundary    string
+	roleMaxSessionDuration     int64
+	roleDescription            string
+
+	// codebuild project args
+	codeBuildComputeType             string
+	codeBuildEnvironmentType         string
+	codeBuildPrivilegedMode          bool
+	codeBuildTimeout                 int64
+	codeBuildQueuedTimeout           int64
+	codeBuildVpcID                   string
+	codeBuildSubnets                 []string
+	codeBuildSecurityGroupIDs        []string
+	codeBuildLogGroupName            string
+	codeBuildLogStreamName           string
+	codeBuildS3LogsLocation          string
+	codeBuildEnvironmentVariables    map[string]string
+	codeBuildParameterStoreVariables map[string]string
+	codeBuildSecretsManagerVariables map[string]string
+	codeBuildPluginCache             bool
+
+	// pipeline notification args
+	pipelineNotifications bool
+	notificationTopicName string
+	notificationEmails    []string
+
+	// export args
+	exportFormat string
+	exportDir    string
 }
 
 // Cmd is the exported command for the AFT prerequisites.
@@ -52,7 +93,7 @@ var Cmd = &cobra.Command{
 	  aftctl deploy prereqs -f deployment.yaml
 	
 	  aftctl deploy prereqs --region="us-east-1"`,
-	Run: run,
+	RunE: run,
 }
 
 func init() {
@@ -67,13 +108,6 @@ func init() {
 		"Name of the deployment terraform state bucket",
 	)
 
-	flags.StringVar(
-		&args.region,
-		"region",
-		"",
-		"The region where the aft deployment resources will be created",
-	)
-
 	flags.StringVar(
 		&args.aftManagementAccountID,
 		"aft-account-id",
@@ -253,121 +287,669 @@ func init() {
 		"Whether to enable enterprise support in created accounts",
 	)
 
+	flags.StringSliceVar(
+		&args.codeBuildManagedPolicyArns,
+		"codebuild-managed-policy-arns",
+		nil,
+		"Managed policies to attach to the CodeBuild role on top of the generated policy "+
+			"(e.g. arn:aws:iam::aws:policy/AdministratorAccess)",
+	)
+
+	flags.StringVar(
+		&args.rolePath,
+		"role-path",
+		"/",
+		"IAM path of the CodePipeline and CodeBuild roles",
+	)
+
+	flags.StringVar(
+		&args.rolePermissionsBoundary,
+		"role-permissions-boundary",
+		"",
+		"ARN of the managed policy set as permissions boundary of the CodePipeline and CodeBuild roles",
+	)
+
+	flags.Int64Var(
+		&args.roleMaxSessionDuration,
+		"role-max-session-duration",
+		3600,
+		"Maximum session duration in seconds of the CodePipeline and CodeBuild roles",
+	)
+
+	flags.StringVar(
+		&args.roleDescription,
+		"role-description",
+		"Created by aftctl to deploy AFT",
+		"Description of the CodePipeline and CodeBuild roles",
+	)
+
+	flags.StringVar(
+		&args.codeBuildComputeType,
+		"codebuild-compute-type",
+		"BUILD_GENERAL1_SMALL",
+		"CodeBuild compute type",
+	)
+
+	flags.StringVar(
+		&args.codeBuildEnvironmentType,
+		"codebuild-environment-type",
+		"LINUX_CONTAINER",
+		"CodeBuild environment type: LINUX_CONTAINER/ARM_CONTAINER (use an aarch64 --docker-image with ARM)",
+	)
+
+	flags.BoolVar(
+		&args.codeBuildPrivilegedMode,
+		"codebuild-privileged-mode",
+		true,
+		"Whether to run the CodeBuild project in privileged mode",
+	)
+
+	flags.Int64Var(
+		&args.codeBuildTimeout,
+		"codebuild-build-timeout",
+		60,
+		"CodeBuild build timeout in minutes",
+	)
+
+	flags.Int64Var(
+		&args.codeBuildQueuedTimeout,
+		"codebuild-queued-timeout",
+		480,
+		"CodeBuild queued timeout in minutes",
+	)
+
+	flags.StringVar(
+		&args.codeBuildVpcID,
+		"codebuild-vpc-id",
+		"",
+		"VPC where CodeBuild runs, e.g. to reach private module registries",
+	)
+
+	flags.StringSliceVar(
+		&args.codeBuildSubnets,
+		"codebuild-subnets",
+		nil,
+		"Subnets where CodeBuild runs, requires --codebuild-vpc-id",
+	)
+
+	flags.StringSliceVar(
+		&args.codeBuildSecurityGroupIDs,
+		"codebuild-security-group-ids",
+		nil,
+		"Security groups attached to CodeBuild, requires --codebuild-vpc-id",
+	)
+
+	flags.StringVar(
+		&args.codeBuildLogGroupName,
+		"codebuild-log-group",
+		"",
+		"CloudWatch Logs group of the CodeBuild project",
+	)
+
+	flags.StringVar(
+		&args.codeBuildLogStreamName,
+		"codebuild-log-stream",
+		"",
+		"CloudWatch Logs stream of the CodeBuild project",
+	)
+
+	flags.StringVar(
+		&args.codeBuildS3LogsLocation,
+		"codebuild-s3-logs-location",
+		"",
+		"Bucket and prefix (bucket/prefix) where CodeBuild stores its logs",
+	)
+
+	flags.StringToStringVar(
+		&args.codeBuildEnvironmentVariables,
+		"codebuild-env",
+		nil,
+		"Plain text environment variables of the CodeBuild project (NAME=value)",
+	)
+
+	flags.StringToStringVar(
+		&args.codeBuildParameterStoreVariables,
+		"codebuild-parameter-store-env",
+		nil,
+		"CodeBuild environment variables read from Parameter Store (NAME=/parameter/name)",
+	)
+
+	flags.StringToStringVar(
+		&args.codeBuildSecretsManagerVariables,
+		"codebuild-secrets-manager-env",
+		nil,
+		"CodeBuild environment variables read from Secrets Manager (NAME=secret-id)",
+	)
+
+	flags.BoolVar(
+		&args.codeBuildPluginCache,
+		"codebuild-plugin-cache",
+		false,
+		"Whether to cache the Terraform plugins in the CodeBuild local file-system cache",
+	)
+
+	flags.BoolVar(
+		&args.pipelineNotifications,
+		"pipeline-notifications",
+		false,
+		"Whether to send the pipeline execution started, succeeded and failed events to an SNS topic",
+	)
+
+	flags.StringVar(
+		&args.notificationTopicName,
+		"notification-topic-name",
+		"aft-deployment-notifications",
+		"SNS topic receiving the pipeline notifications",
+	)
+
+	flags.StringSliceVar(
+		&args.notificationEmails,
+		"notification-emails",
+		nil,
+		"Email addresses subscribed to the pipeline notifications topic",
+	)
+
+	flags.StringVar(
+		&args.exportFormat,
+		"export",
+		"",
+		fmt.Sprintf("Write the deployment resources as infrastructure as code instead of creating them. "+
+			"Allowed options are %s", export.Formats),
+	)
+
+	flags.StringVar(
+		&args.exportDir,
+		"out",
+		".",
+		"Directory where the export is written",
+	)
+
+	Cmd.RegisterFlagCompletionFunc("export", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return export.Formats, cobra.ShellCompDirectiveDefault
+	})
+
 }
 
-func run(cmd *cobra.Command, _ []string) {
-	awsClient := aws.NewClient("")
+func run(cmd *cobra.Command, _ []string) error {
+	if err := output.CheckFormat(); err != nil {
+		return err
+	}
 
-	interpolatedCodeSuiteBucketName := args.aftManagementAccountID + "-" + args.codePipelineBucketName
-	interpolatedTerraformBucketName := args.aftManagementAccountID + "-" + args.terraformStateBucketName
-	interpolatedZIPFileName := args.gitSourceRepo + ".zip"
+	if err := applyContext(cmd); err != nil {
+		return err
+	}
 
-	interpolatedCloudformationStackName := args.gitSourceRepo + "-cloudformation-stack"
+	result := &Result{
+		Account: args.aftManagementAccountID,
+		Region:  aws.FlagOptions().Region,
+	}
+
+	start := time.Now()
+
+	var err error
+	if args.exportFormat != "" {
+		err = exportDeployment(result)
+	} else {
+		err = deployAndNotify(result)
+	}
+
+	result.Duration = output.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if printErr := output.Print(cmd.OutOrStdout(), result); printErr != nil {
+		return printErr
+	}
+
+	return err
+}
+
+// deployAndNotify deploys the AFT prerequisites, posting the progress to the webhook
+func deployAndNotify(result *Result) error {
+	notifier, err := notify.FromFlags()
+	if err != nil {
+		return err
+	}
 
-	codebuildTrustRelationshipService := "codebuild.amazonaws.com"
-	codePipelineTrustRelationshipService := "codepipeline.amazonaws.com"
+	fields := map[string]string{
+		"account": args.aftManagementAccountID,
+		"region":  aws.FlagOptions().Region,
+	}
+
+	notifyEvent(notifier, notify.DeployStarted, "Deploying the AFT prerequisites", fields)
+
+	if err := deploy(result); err != nil {
+		notifyEvent(notifier, notify.DeployFailed, err.Error(), fields)
+		return err
+	}
+
+	notifyEvent(notifier, notify.DeploySucceeded, "AFT prerequisites deployed", fields)
+
+	return nil
+}
+
+// applyContext fills the flags not given on the command line from the active context
+func applyContext(cmd *cobra.Command) error {
+	name, ctx, err := config.Active()
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		return nil
+	}
+
+	flags := cmd.Flags()
+
+	for flag, value := range map[string]string{
+		"profile":                   ctx.Profile,
+		"region":                    ctx.Region,
+		"aft-account-id":            ctx.AftManagementAccountID,
+		"ct-management-account-id":  ctx.CtManagementAccountID,
+		"ct-log-archive-account-id": ctx.LogArchiveAccountID,
+		"ct-audit-account-id":       ctx.AuditAccountID,
+		"ct-home-region":            ctx.CtHomeRegion,
+	} {
+		if value == "" || flags.Lookup(flag) == nil || flags.Changed(flag) {
+			continue
+		}
+
+		if err := flags.Set(flag, value); err != nil {
+			return err
+		}
+	}
+
+	logging.CustomLog("🔀", "green", fmt.Sprintf("Using context %q", name))
+
+	return nil
+}
+
+// notifyEvent posts the event to the webhook, a failing webhook doesn't fail the deployment
+func notifyEvent(notifier *notify.Notifier, eventType notify.EventType, message string, fields map[string]string) {
+	err := notifier.Notify(notify.Event{
+		Type:    eventType,
+		Message: message,
+		Fields:  fields,
+	})
+
+	if err != nil {
+		logging.Warnf("%v", err)
+	}
+}
+
+func deploy(result *Result) error {
+	awsClient := aws.NewClient(aws.FlagOptions())
+	args.region = awsClient.GetRegion()
+	result.Region = args.region
+
+	deployment, err := newDeployment(flagNames())
+	if err != nil {
+		return err
+	}
+
+	interpolatedZIPFileName := args.gitSourceRepo + ".zip"
+	interpolatedCloudformationStackName := args.gitSourceRepo + "-cloudformation-stack"
 
 	// Ensure the Code Pipeline Service Role is created
-	aws.EnsureIamRoleExists(
-		awsClient.GetIamClient(),
-		args.codePipelineRoleName,
-		codePipelineTrustRelationshipService,
-		args.codePipelineRolePolicyName,
-		args.region,
-		args.aftManagementAccountID,
-		args.gitSourceRepo,
-		interpolatedCodeSuiteBucketName,
-		interpolatedTerraformBucketName,
-	)
+	if err := result.step("iam-role", deployment.PipelineRole.Name, output.Ensured, func() error {
+		_, err := aws.EnsureIamRoleExists(
+			awsClient.GetIamClient(),
+			deployment.PipelineRole.Name,
+			deployment.PipelineRole.TrustService,
+			deployment.PipelineRole.PolicyName,
+			deployment.PipelineRole.Policy,
+			deployment.PipelineRole.ManagedPolicyArns,
+			deployment.PipelineRole.Options,
+		)
+		return err
+	}); err != nil {
+		return err
+	}
 
 	// Ensure the Code Build Service Role is created
-	aws.EnsureIamRoleExists(
-		awsClient.GetIamClient(),
-		args.codeBuildRoleName,
-		codebuildTrustRelationshipService,
-		args.codeBuildRolePolicyName,
-		args.region,
-		args.aftManagementAccountID,
-		args.gitSourceRepo,
-		interpolatedCodeSuiteBucketName,
-		interpolatedTerraformBucketName,
-	)
+	if err := result.step("iam-role", deployment.CodeBuildRole.Name, output.Ensured, func() error {
+		_, err := aws.EnsureIamRoleExists(
+			awsClient.GetIamClient(),
+			deployment.CodeBuildRole.Name,
+			deployment.CodeBuildRole.TrustService,
+			deployment.CodeBuildRole.PolicyName,
+			deployment.CodeBuildRole.Policy,
+			deployment.CodeBuildRole.ManagedPolicyArns,
+			deployment.CodeBuildRole.Options,
+		)
+		return err
+	}); err != nil {
+		return err
+	}
 
 	// Ensure the tfstate bucket is created
-	aws.EnsureS3BucketExists(
-		awsClient.GetS3Client(),
-		interpolatedTerraformBucketName,
-		args.aftManagementAccountID,
-		"test-kms-key-id",
-		args.codeBuildRoleName,
-	)
+	if err := result.step("s3-bucket", deployment.StateBucketName, output.Ensured, func() error {
+		_, err := aws.EnsureS3BucketExists(
+			awsClient.GetS3Client(),
+			deployment.StateBucketName,
+			deployment.AftManagementAccountID,
+			"test-kms-key-id",
+			deployment.RoleArn(deployment.CodeBuildRole),
+		)
+		return err
+	}); err != nil {
+		return err
+	}
 
 	// Ensure the codepipeline bucket is created
-	aws.EnsureS3BucketExists(
-		awsClient.GetS3Client(),
-		interpolatedCodeSuiteBucketName,
-		args.aftManagementAccountID,
-		"test-kms-key-id",
-		args.codeBuildRoleName,
-	)
+	if err := result.step("s3-bucket", deployment.ArtifactBucketName, output.Ensured, func() error {
+		_, err := aws.EnsureS3BucketExists(
+			awsClient.GetS3Client(),
+			deployment.ArtifactBucketName,
+			deployment.AftManagementAccountID,
+			"test-kms-key-id",
+			deployment.RoleArn(deployment.CodeBuildRole),
+		)
+		return err
+	}); err != nil {
+		return err
+	}
 
 	// Ensure the CodeCommit repo is created with initial code
-	initialcommit.GenerateCommitFiles(
-		args.gitSourceRepo,
-		interpolatedTerraformBucketName,
-		args.region,
-		args.tfVersion,
-		args.ctManagementAccountID,
-		args.logArchiveAccountID,
-		args.auditAccountID,
-		args.aftManagementAccountID,
-		args.ctHomeRegion,
-		args.tfBackendSecondaryRegion,
-		args.aftMetricsReporting,
-		args.aftFeatureCloudtrailDataEvents,
-		args.aftFeatureEnterpriseSupport,
-		args.aftFeatureDeleteDefaultVPCsEnabled,
-		args.terraformDistribution,
-	)
-
-	aws.UploadToS3(
-		awsClient.GetS3Client(),
-		interpolatedCodeSuiteBucketName,
-		interpolatedZIPFileName,
-		interpolatedZIPFileName,
-	)
+	result.step("commit-files", args.gitSourceRepo, output.Generated, func() error {
+		initialcommit.GenerateCommitFiles(
+			args.gitSourceRepo,
+			deployment.StateBucketName,
+			args.region,
+			args.tfVersion,
+			args.ctManagementAccountID,
+			args.logArchiveAccountID,
+			args.auditAccountID,
+			args.aftManagementAccountID,
+			args.ctHomeRegion,
+			args.tfBackendSecondaryRegion,
+			args.aftMetricsReporting,
+			args.aftFeatureCloudtrailDataEvents,
+			args.aftFeatureEnterpriseSupport,
+			args.aftFeatureDeleteDefaultVPCsEnabled,
+			args.terraformDistribution,
+			args.codeBuildPluginCache,
+		)
+		return nil
+	})
+
+	if err := result.step("s3-object", deployment.ArtifactBucketName+"/"+interpolatedZIPFileName, output.Uploaded, func() error {
+		return aws.UploadToS3(
+			awsClient.GetS3Client(),
+			deployment.ArtifactBucketName,
+			interpolatedZIPFileName,
+			interpolatedZIPFileName,
+		)
+	}); err != nil {
+		return err
+	}
 
 	// Ensure the repository is created
-	aws.EnsureCloudformationExists(
-		awsClient.GetCloudFormationClient(),
-		interpolatedCloudformationStackName,
-		args.gitSourceRepo,
-		args.gitSourceDescription,
-		interpolatedCodeSuiteBucketName,
-		interpolatedZIPFileName,
-	)
+	if err := result.step("cloudformation-stack", interpolatedCloudformationStackName, output.Ensured, func() error {
+		_, err := aws.EnsureCloudformationExists(
+			awsClient.GetCloudFormationClient(),
+			interpolatedCloudformationStackName,
+			deployment.Repository.Name,
+			deployment.Repository.Description,
+			deployment.ArtifactBucketName,
+			interpolatedZIPFileName,
+		)
+		return err
+	}); err != nil {
+		return err
+	}
 
 	// Ensure the Code Build Project is created
-	aws.EnsureCodeBuildProjectExists(
-		awsClient.GetCodeBuildClient(),
-		args.aftManagementAccountID,
-		args.codeBuildDockerImage,
-		args.projectName,
-		args.gitSourceRepo,
-		args.branchName,
-		args.codeBuildRoleName,
-	)
+	if err := result.step("codebuild-project", deployment.Project.Name, output.Ensured, func() error {
+		_, err := aws.EnsureCodeBuildProjectExists(
+			awsClient.GetCodeBuildClient(),
+			deployment.AftManagementAccountID,
+			deployment.Project.Name,
+			deployment.Repository.Name,
+			deployment.Repository.Branch,
+			deployment.CodeBuildRole.Name,
+			deployment.CodeBuildRole.Options.Path,
+			deployment.Project.Config,
+		)
+		return err
+	}); err != nil {
+		return err
+	}
 
 	// Ensure the Code Pipeline Pipe is created
-	aws.EnsureCodePipelineExists(
-		awsClient.GetCodePipelineClient(),
-		args.aftManagementAccountID,
-		args.codePipelineRoleName,
-		args.pipelineName,
-		interpolatedCodeSuiteBucketName,
-		args.gitSourceRepo,
-		args.branchName,
-		args.projectName,
-	)
+	if err := result.step("codepipeline-pipeline", deployment.PipelineName, output.Ensured, func() error {
+		_, err := aws.EnsureCodePipelineExists(
+			awsClient.GetCodePipelineClient(),
+			deployment.AftManagementAccountID,
+			deployment.PipelineRole.Name,
+			deployment.PipelineRole.Options.Path,
+			deployment.PipelineName,
+			deployment.ArtifactBucketName,
+			deployment.Repository.Name,
+			deployment.Repository.Branch,
+			deployment.Project.Name,
+		)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Ensure the pipeline events are sent to the notifications topic
+	if deployment.Notifications != nil {
+		if err := result.step("sns-topic", deployment.Notifications.TopicName, output.Ensured, func() error {
+			_, err := aws.EnsurePipelineNotificationsExist(
+				awsClient.GetSNSClient(),
+				awsClient.GetCodeStarNotificationsClient(),
+				deployment.Region,
+				deployment.AftManagementAccountID,
+				deployment.PipelineName,
+				deployment.Notifications.TopicName,
+				deployment.Notifications.Emails,
+			)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportDeployment writes the deployment as CloudFormation or Terraform instead of creating it
+func exportDeployment(result *Result) error {
+	deployment, err := newDeployment(exportNames())
+	if err != nil {
+		return err
+	}
+
+	var path string
+	if err := result.step(args.exportFormat, args.exportDir, output.Exported, func() error {
+		path, err = export.Write(deployment, exportParameters(), export.Format(args.exportFormat), args.exportDir)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	result.Export = path
+
+	logging.CustomLog("📄", "green", fmt.Sprintf("AFT deployment exported to %s", path))
+	logging.CustomLog("📄", "yellow", fmt.Sprintf("The %s repository is created empty, push the AFT deployment code to it once deployed", args.gitSourceRepo))
+
+	return nil
+}
+
+// deploymentNames holds the account IDs and names the deployment is built from:
+// the flag values, or references to the export parameters
+type deploymentNames struct {
+	region                 string
+	aftManagementAccountID string
+	ctManagementAccountID  string
+	logArchiveAccountID    string
+	auditAccountID         string
+	repoName               string
+	branchName             string
+	projectName            string
+	pipelineName           string
+	codePipelineRoleName   string
+	codeBuildRoleName      string
+}
+
+// flagNames returns the names given on the command line
+func flagNames() deploymentNames {
+	return deploymentNames{
+		region:                 args.region,
+		aftManagementAccountID: args.aftManagementAccountID,
+		ctManagementAccountID:  args.ctManagementAccountID,
+		logArchiveAccountID:    args.logArchiveAccountID,
+		auditAccountID:         args.auditAccountID,
+		repoName:               args.gitSourceRepo,
+		branchName:             args.branchName,
+		projectName:            args.projectName,
+		pipelineName:           args.pipelineName,
+		codePipelineRoleName:   args.codePipelineRoleName,
+		codeBuildRoleName:      args.codeBuildRoleName,
+	}
+}
+
+// exportNames returns references to the export parameters
+func exportNames() deploymentNames {
+	return deploymentNames{
+		region:                 export.RegionRef,
+		aftManagementAccountID: export.Ref("AftManagementAccountId"),
+		ctManagementAccountID:  export.Ref("CtManagementAccountId"),
+		logArchiveAccountID:    export.Ref("LogArchiveAccountId"),
+		auditAccountID:         export.Ref("AuditAccountId"),
+		repoName:               export.Ref("RepositoryName"),
+		branchName:             export.Ref("BranchName"),
+		projectName:            export.Ref("ProjectName"),
+		pipelineName:           export.Ref("PipelineName"),
+		codePipelineRoleName:   export.Ref("PipelineRoleName"),
+		codeBuildRoleName:      export.Ref("CodeBuildRoleName"),
+	}
+}
+
+// exportParameters returns the export parameters, defaulting to the flag values
+func exportParameters() []export.Parameter {
+	return []export.Parameter{
+		{Name: "AftManagementAccountId", Description: "AFT Management account ID", Default: args.aftManagementAccountID},
+		{Name: "CtManagementAccountId", Description: "CT Management account ID", Default: args.ctManagementAccountID},
+		{Name: "LogArchiveAccountId", Description: "CT Log Archive account ID", Default: args.logArchiveAccountID},
+		{Name: "AuditAccountId", Description: "CT Audit account ID", Default: args.auditAccountID},
+		{Name: "RepositoryName", Description: "CodeCommit repository name", Default: args.gitSourceRepo},
+		{Name: "BranchName", Description: "CodeCommit branch name", Default: args.branchName},
+		{Name: "ProjectName", Description: "CodeBuild project name", Default: args.projectName},
+		{Name: "PipelineName", Description: "CodePipeline pipeline name", Default: args.pipelineName},
+		{Name: "PipelineRoleName", Description: "CodePipeline role name", Default: args.codePipelineRoleName},
+		{Name: "CodeBuildRoleName", Description: "CodeBuild role name", Default: args.codeBuildRoleName},
+	}
+}
 
+// newDeployment builds the model of the deployment resources shared by the API and export paths
+func newDeployment(names deploymentNames) (aws.Deployment, error) {
+	codeBuildProjectConfig := aws.CodeBuildProjectConfig{
+		Image:                   args.codeBuildDockerImage,
+		ComputeType:             args.codeBuildComputeType,
+		EnvironmentType:         args.codeBuildEnvironmentType,
+		PrivilegedMode:          args.codeBuildPrivilegedMode,
+		BuildTimeout:            args.codeBuildTimeout,
+		QueuedTimeout:           args.codeBuildQueuedTimeout,
+		VpcID:                   args.codeBuildVpcID,
+		Subnets:                 args.codeBuildSubnets,
+		SecurityGroupIDs:        args.codeBuildSecurityGroupIDs,
+		LogGroupName:            args.codeBuildLogGroupName,
+		LogStreamName:           args.codeBuildLogStreamName,
+		S3LogsLocation:          args.codeBuildS3LogsLocation,
+		PlainTextVariables:      args.codeBuildEnvironmentVariables,
+		ParameterStoreVariables: args.codeBuildParameterStoreVariables,
+		SecretsManagerVariables: args.codeBuildSecretsManagerVariables,
+	}
+
+	if args.codeBuildPluginCache {
+		codeBuildProjectConfig.PluginCacheDir = initialcommit.TerraformPluginCacheDir
+	}
+
+	// Validate the CodeBuild project settings before creating anything
+	if err := codeBuildProjectConfig.Validate(); err != nil {
+		return aws.Deployment{}, err
+	}
+
+	interpolatedCodeSuiteBucketName := names.aftManagementAccountID + "-" + args.codePipelineBucketName
+	interpolatedTerraformBucketName := names.aftManagementAccountID + "-" + args.terraformStateBucketName
+
+	policyScope := aws.PolicyScope{
+		Region:                 names.region,
+		AftManagementAccountID: names.aftManagementAccountID,
+		RepoName:               names.repoName,
+		ProjectName:            names.projectName,
+		ArtifactBucketName:     interpolatedCodeSuiteBucketName,
+		StateBucketName:        interpolatedTerraformBucketName,
+		ControlTowerAccountIDs: []string{
+			names.ctManagementAccountID,
+			names.logArchiveAccountID,
+			names.auditAccountID,
+		},
+		BuildLogGroupName:   args.codeBuildLogGroupName,
+		BuildLogsS3Location: args.codeBuildS3LogsLocation,
+		BuildInVpc:          args.codeBuildVpcID != "",
+		BuildSecretIDs:      codeBuildProjectConfig.SecretIDs(),
+	}
+
+	codePipelinePolicy, err := aws.NewRolePolicy(aws.PipelineRolePurpose, policyScope)
+	if err != nil {
+		return aws.Deployment{}, err
+	}
+
+	codeBuildPolicy, err := aws.NewRolePolicy(aws.ApplyRolePurpose, policyScope)
+	if err != nil {
+		return aws.Deployment{}, err
+	}
+
+	roleOptions := aws.RoleOptions{
+		Path:                args.rolePath,
+		PermissionsBoundary: args.rolePermissionsBoundary,
+		MaxSessionDuration:  args.roleMaxSessionDuration,
+		Description:         args.roleDescription,
+	}
+
+	deployment := aws.Deployment{
+		Region:                 names.region,
+		AftManagementAccountID: names.aftManagementAccountID,
+		PipelineRole: aws.DeploymentRole{
+			Name:         names.codePipelineRoleName,
+			TrustService: "codepipeline.amazonaws.com",
+			PolicyName:   args.codePipelineRolePolicyName,
+			Policy:       codePipelinePolicy,
+			Options:      roleOptions,
+		},
+		CodeBuildRole: aws.DeploymentRole{
+			Name:              names.codeBuildRoleName,
+			TrustService:      "codebuild.amazonaws.com",
+			PolicyName:        args.codeBuildRolePolicyName,
+			Policy:            codeBuildPolicy,
+			ManagedPolicyArns: args.codeBuildManagedPolicyArns,
+			Options:           roleOptions,
+		},
+		StateBucketName:    interpolatedTerraformBucketName,
+		ArtifactBucketName: interpolatedCodeSuiteBucketName,
+		Repository: aws.DeploymentRepository{
+			Name:        names.repoName,
+			Description: args.gitSourceDescription,
+			Branch:      names.branchName,
+		},
+		Project: aws.DeploymentProject{
+			Name:   names.projectName,
+			Config: codeBuildProjectConfig,
+		},
+		PipelineName: names.pipelineName,
+	}
+
+	if args.pipelineNotifications {
+		deployment.Notifications = &aws.DeploymentNotifications{
+			TopicName: args.notificationTopicName,
+			Emails:    args.notificationEmails,
+		}
+	}
+
+	return deployment, nil
 }
@@ -0,0 +1,63 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package deploy
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/edgarsilva948/aftctl/pkg/output"
+)
+
+// Result is the outcome of the deploy command.
+type Result struct {
+	Account   string            `json:"account" yaml:"account"`
+	Region    string            `json:"region" yaml:"region"`
+	Export    string            `json:"export,omitempty" yaml:"export,omitempty"`
+	Resources []output.Resource `json:"resources" yaml:"resources"`
+	Duration  output.Duration   `json:"duration" yaml:"duration"`
+	Error     string            `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// PrintTable writes the resources and the outcome of the deployment.
+func (r *Result) PrintTable(w io.Writer) error {
+	if err := output.ResourcesTable(w, r.Resources); err != nil {
+		return err
+	}
+
+	switch {
+	case r.Error != "":
+		fmt.Fprintf(w, "\nDeployment failed after %s: %s\n", r.Duration, r.Error)
+	case r.Export != "":
+		fmt.Fprintf(w, "\nDeployment exported to %s in %s\n", r.Export, r.Duration)
+	default:
+		fmt.Fprintf(w, "\nDeployment completed in %s\n", r.Duration)
+	}
+
+	return nil
+}
+
+// step runs fn and records its resource in the result
+func (r *Result) step(resourceType string, name string, action output.Action, fn func() error) error {
+	start := time.Now()
+	err := fn()
+
+	resource := output.Resource{
+		Type:     resourceType,
+		Name:     name,
+		Action:   action,
+		Duration: output.Since(start),
+	}
+
+	if err != nil {
+		resource.Action = output.Failed
+		resource.Error = err.Error()
+	}
+
+	r.Resources = append(r.Resources, resource)
+
+	return err
+}
@@ -13,11 +13,20 @@ import (
 
 	"github.com/edgarsilva948/aftctl/cmd/aft"
 	"github.com/edgarsilva948/aftctl/cmd/completion"
+	"github.com/edgarsilva948/aftctl/cmd/context"
+	"github.com/edgarsilva948/aftctl/cmd/credentials"
 	"github.com/edgarsilva948/aftctl/cmd/docs"
+	"github.com/edgarsilva948/aftctl/cmd/initialize"
 	"github.com/edgarsilva948/aftctl/cmd/local"
+	"github.com/edgarsilva948/aftctl/cmd/validate"
 	"github.com/edgarsilva948/aftctl/cmd/version"
 
+	"github.com/edgarsilva948/aftctl/pkg/aws"
 	"github.com/edgarsilva948/aftctl/pkg/color"
+	"github.com/edgarsilva948/aftctl/pkg/config"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/notify"
+	"github.com/edgarsilva948/aftctl/pkg/output"
 )
 
 var root = &cobra.Command{
@@ -26,11 +35,19 @@ var root = &cobra.Command{
 	Long: "Command line tool for Amazon Account Factory for Terraform (AFT).\n" +
 		"For further documentation visit " +
 		"https://docs.aws.amazon.com/controltower/latest/userguide/aft-getting-started.html\n",
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		return logging.Setup(cmd)
+	},
 }
 
 func init() {
 	// Add the command line flags:
 	color.AddFlag(root)
+	aws.AddFlags(root)
+	logging.AddFlags(root)
+	output.AddFlag(root)
+	notify.AddFlags(root)
+	config.AddFlag(root)
 
 	// Register the subcommands:
 	root.AddCommand(completion.Cmd)
@@ -38,12 +55,17 @@ func init() {
 	root.AddCommand(version.Cmd)
 	root.AddCommand(aft.Cmd)
 	root.AddCommand(local.Cmd)
+	root.AddCommand(context.Cmd)
+	root.AddCommand(initialize.Cmd)
+	root.AddCommand(validate.Cmd)
+	root.AddCommand(credentials.Cmd)
 }
 
 func main() {
 	// Execute the root command:
 	root.SetArgs(os.Args[1:])
 	err := root.Execute()
+	logging.Sync()
 	if err != nil {
 		if !strings.Contains(err.Error(), "Did you mean this?") {
 			fmt.Fprintf(os.Stderr, "Failed to execute root command: %s\n", err)
@@ -0,0 +1,30 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package context
+
+import (
+	"github.com/edgarsilva948/aftctl/cmd/context/current"
+	"github.com/edgarsilva948/aftctl/cmd/context/list"
+	"github.com/edgarsilva948/aftctl/cmd/context/set"
+	"github.com/edgarsilva948/aftctl/cmd/context/use"
+	"github.com/spf13/cobra"
+)
+
+// Cmd represents the root command for the "context" functionality.
+var Cmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage the AFT environments aftctl works with",
+	Long: "Manage the named contexts of the aftctl config file (~/.aftctl/config or $AFTCTL_CONFIG).\n" +
+		"A context holds the profile, region, account IDs and SSM parameter values of one AFT deployment, " +
+		"the commands read them from the current context or the one given with --context.",
+}
+
+func init() {
+
+	Cmd.AddCommand(use.Cmd)
+	Cmd.AddCommand(list.Cmd)
+	Cmd.AddCommand(current.Cmd)
+	Cmd.AddCommand(set.Cmd)
+}
@@ -0,0 +1,37 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package current
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/edgarsilva948/aftctl/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// Cmd is the exported command printing the current context.
+var Cmd = &cobra.Command{
+	Use:   "current",
+	Short: "Print the current context",
+	Long:  "Print the name of the context used by the commands, taking --context into account.",
+	Args:  cobra.NoArgs,
+	RunE:  run,
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	name, _, err := config.Active()
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		return errors.New("current context is not set, set it with aftctl context use NAME")
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), name)
+
+	return nil
+}
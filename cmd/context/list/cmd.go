@@ -0,0 +1,73 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package list
+
+import (
+	"io"
+
+	"github.com/edgarsilva948/aftctl/pkg/config"
+	"github.com/edgarsilva948/aftctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// Cmd is the exported command listing the contexts.
+var Cmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the contexts",
+	Long:  "List the contexts of the aftctl config file, the current one is marked with *.",
+	Args:  cobra.NoArgs,
+	RunE:  run,
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	current, _, err := config.Active()
+	if err != nil {
+		return err
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	result := &Result{Current: current}
+	for _, name := range cfg.ContextNames() {
+		result.Contexts = append(result.Contexts, NamedContext{Name: name, Context: cfg.Contexts[name]})
+	}
+
+	return output.Print(cmd.OutOrStdout(), result)
+}
+
+// NamedContext is a context of the config file with its name.
+type NamedContext struct {
+	Name           string `json:"name" yaml:"name"`
+	config.Context `yaml:",inline"`
+}
+
+// Result lists the contexts of the config file.
+type Result struct {
+	Current  string         `json:"current" yaml:"current"`
+	Contexts []NamedContext `json:"contexts" yaml:"contexts"`
+}
+
+// PrintTable writes one row per context, the current one is marked with *.
+func (r *Result) PrintTable(w io.Writer) error {
+	rows := make([][]string, 0, len(r.Contexts))
+	for _, ctx := range r.Contexts {
+		marker := ""
+		if ctx.Name == r.Current {
+			marker = "*"
+		}
+
+		rows = append(rows, []string{marker, ctx.Name, ctx.Profile, ctx.Region, ctx.AftManagementAccountID})
+	}
+
+	return output.Table(w, []string{"CURRENT", "NAME", "PROFILE", "REGION", "AFT-ACCOUNT"}, rows)
+}
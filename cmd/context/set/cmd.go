@@ -0,0 +1,175 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package set
+
+import (
+	"fmt"
+
+	"github.com/edgarsilva948/aftctl/pkg/config"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+	"github.com/spf13/cobra"
+)
+
+var args struct {
+	profile                string
+	region                 string
+	aftManagementAccountID string
+	ctManagementAccountID  string
+	logArchiveAccountID    string
+	auditAccountID         string
+	ctHomeRegion           string
+	ssmParameters          map[string]string
+}
+
+// Cmd is the exported command creating or updating a context.
+var Cmd = &cobra.Command{
+	Use:   "set NAME",
+	Short: "Create or update a context",
+	Long: "Create or update a context, only the given flags are changed on an existing context.\n" +
+		"SSM parameter values set on the context are used instead of the values stored in the AFT management account.",
+	Example: `  aftctl context set prod --context-profile prod-admin --context-region us-east-1 --aft-account-id 123456789012
+
+  aftctl context set sandbox --ssm-parameter /aft/config/terraform/distribution=oss`,
+	Args: cobra.ExactArgs(1),
+	RunE: run,
+}
+
+func init() {
+	flags := Cmd.Flags()
+	flags.SortFlags = false
+
+	flags.StringVar(
+		&args.profile,
+		"context-profile",
+		"",
+		"AWS profile used to reach the AFT management account, the global --profile flag overrides it per command",
+	)
+
+	flags.StringVar(
+		&args.region,
+		"context-region",
+		"",
+		"Region of the AFT deployment, the global --region flag overrides it per command",
+	)
+
+	flags.StringVar(
+		&args.aftManagementAccountID,
+		"aft-account-id",
+		"",
+		"AFT Management account ID",
+	)
+
+	flags.StringVar(
+		&args.ctManagementAccountID,
+		"ct-management-account-id",
+		"",
+		"CT Management account id (aka payer/root/master account)",
+	)
+
+	flags.StringVar(
+		&args.logArchiveAccountID,
+		"ct-log-archive-account-id",
+		"",
+		"CT Log Archive account id",
+	)
+
+	flags.StringVar(
+		&args.auditAccountID,
+		"ct-audit-account-id",
+		"",
+		"CT Audit account id",
+	)
+
+	flags.StringVar(
+		&args.ctHomeRegion,
+		"ct-home-region",
+		"",
+		"CT main region",
+	)
+
+	flags.StringToStringVar(
+		&args.ssmParameters,
+		"ssm-parameter",
+		nil,
+		"AFT SSM parameter value to use instead of the stored one (/aft/parameter/name=value)",
+	)
+}
+
+func run(cmd *cobra.Command, argv []string) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	name := argv[0]
+	ctx := cfg.Contexts[name]
+
+	flags := cmd.Flags()
+
+	for flag, field := range map[string]*string{
+		"context-profile":           &ctx.Profile,
+		"context-region":            &ctx.Region,
+		"aft-account-id":            &ctx.AftManagementAccountID,
+		"ct-management-account-id":  &ctx.CtManagementAccountID,
+		"ct-log-archive-account-id": &ctx.LogArchiveAccountID,
+		"ct-audit-account-id":       &ctx.AuditAccountID,
+		"ct-home-region":            &ctx.CtHomeRegion,
+	} {
+		if flags.Changed(flag) {
+			*field = flags.Lookup(flag).Value.String()
+		}
+	}
+
+	if flags.Changed("ssm-parameter") {
+		if ctx.SSMParameters == nil {
+			ctx.SSMParameters = map[string]string{}
+		}
+		for key, value := range args.ssmParameters {
+			ctx.SSMParameters[key] = value
+		}
+	}
+
+	if err := checkContextCompliance(ctx); err != nil {
+		return err
+	}
+
+	if err := cfg.SetContext(name, ctx); err != nil {
+		return err
+	}
+
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+
+	logging.CustomLog("📝", "green", fmt.Sprintf("Context %q saved to %s", name, path))
+
+	return nil
+}
+
+// func to check the account IDs of the context
+func checkContextCompliance(ctx config.Context) error {
+	for _, accountID := range []string{
+		ctx.AftManagementAccountID,
+		ctx.CtManagementAccountID,
+		ctx.LogArchiveAccountID,
+		ctx.AuditAccountID,
+	} {
+		if accountID == "" {
+			continue
+		}
+
+		if _, err := validate.CheckAWSAccountID(accountID); err != nil {
+			return fmt.Errorf("invalid AWS Account ID %q: %w", accountID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package use
+
+import (
+	"fmt"
+
+	"github.com/edgarsilva948/aftctl/pkg/config"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+// Cmd is the exported command switching the current context.
+var Cmd = &cobra.Command{
+	Use:               "use NAME",
+	Short:             "Set the current context",
+	Long:              "Set the context used by the commands when --context is not given.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion,
+	RunE:              run,
+}
+
+func run(cmd *cobra.Command, argv []string) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Use(argv[0]); err != nil {
+		return err
+	}
+
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+
+	logging.CustomLog("🔀", "green", fmt.Sprintf("Switched to context %q", argv[0]))
+
+	return nil
+}
+
+func completion(cmd *cobra.Command, argv []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(argv) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	return cfg.ContextNames(), cobra.ShellCompDirectiveNoFileComp
+}
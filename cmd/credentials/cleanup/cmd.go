@@ -0,0 +1,107 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package cleanup
+
+import (
+	"fmt"
+	"io"
+
+	profile "github.com/edgarsilva948/aftctl/pkg/aws/profiles"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var args struct {
+	roleName string
+	yes      bool
+}
+
+// Cmd is the exported command removing the profiles written by older aftctl versions.
+var Cmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Remove the assumed role profiles older aftctl versions wrote",
+	Long: "List the <account>-<role> profiles of the AFT administrator role that older aftctl versions wrote to the AWS " +
+		"credentials file ($AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials), holding only the assumed credentials.\n" +
+		"With --yes, remove them and make the file readable by the user only.",
+	Example: `  aftctl credentials cleanup
+
+  aftctl credentials cleanup --yes`,
+	Args: cobra.NoArgs,
+	RunE: run,
+}
+
+func init() {
+	flags := Cmd.Flags()
+
+	flags.StringVar(
+		&args.roleName,
+		"role-name",
+		profile.DefaultAdminRoleName,
+		"Name of the AFT administrator role the profiles were written for",
+	)
+
+	flags.BoolVar(
+		&args.yes,
+		"yes",
+		false,
+		"Remove the profiles found",
+	)
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	path, err := profile.CredentialsFile()
+	if err != nil {
+		return err
+	}
+
+	profiles, err := profile.FindWrittenProfiles(path, args.roleName)
+	if err != nil {
+		return err
+	}
+
+	result := &Result{File: path, Profiles: profiles}
+
+	if len(profiles) > 0 && !args.yes {
+		logging.Infof("run aftctl credentials cleanup --yes to remove the %d profiles from %s", len(profiles), path)
+	}
+
+	if len(profiles) > 0 && args.yes {
+		if err := profile.RemoveProfiles(path, profiles); err != nil {
+			return fmt.Errorf("error removing the profiles: %w", err)
+		}
+		result.Removed = true
+		logging.Infof("removed %d profiles from %s", len(profiles), path)
+	}
+
+	return output.Print(cmd.OutOrStdout(), result)
+}
+
+// Result lists the profiles written by older aftctl versions.
+type Result struct {
+	File     string   `json:"file" yaml:"file"`
+	Profiles []string `json:"profiles" yaml:"profiles"`
+	Removed  bool     `json:"removed" yaml:"removed"`
+}
+
+// PrintTable writes one row per profile.
+func (r *Result) PrintTable(w io.Writer) error {
+	if len(r.Profiles) == 0 {
+		_, err := fmt.Fprintf(w, "No profile written by aftctl in %s\n", r.File)
+		return err
+	}
+
+	status := "found"
+	if r.Removed {
+		status = "removed"
+	}
+
+	rows := make([][]string, 0, len(r.Profiles))
+	for _, name := range r.Profiles {
+		rows = append(rows, []string{name, status})
+	}
+
+	return output.Table(w, []string{"PROFILE", "STATUS"}, rows)
+}
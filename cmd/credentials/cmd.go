@@ -0,0 +1,26 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package credentials
+
+import (
+	"github.com/edgarsilva948/aftctl/cmd/credentials/cleanup"
+	"github.com/edgarsilva948/aftctl/cmd/credentials/process"
+	"github.com/spf13/cobra"
+)
+
+// Cmd represents the root command for the "credentials" functionality.
+var Cmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manage the credentials aftctl assumes",
+	Long: "Manage the credentials of the AFT roles aftctl assumes.\n" +
+		"aftctl keeps the assumed credentials in memory and only gives them to Terraform, " +
+		"older versions wrote them to ~/.aws/credentials and cleanup removes them.",
+}
+
+func init() {
+
+	Cmd.AddCommand(process.Cmd)
+	Cmd.AddCommand(cleanup.Cmd)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	profile "github.com/edgarsilva948/aftctl/pkg/aws/profiles"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+	"github.com/spf13/cobra"
+)
+
+var args struct {
+	roleARN     string
+	sessionName string
+	externalID  string
+	duration    time.Duration
+	configFile  string
+}
+
+// Cmd is the exported command printing the credentials of an assumed role.
+var Cmd = &cobra.Command{
+	Use:   "process --role-arn ARN",
+	Short: "Print the credentials of an assumed role for the credential_process of an AWS profile",
+	Long: "Assume the role and print its credentials in the format of the credential_process setting of the AWS profiles, " +
+		"the AWS SDKs then run it again when the credentials expire. aftctl local --credential-process uses it.",
+	Example: `  [profile aft-admin]
+  credential_process = aftctl credentials process --role-arn arn:aws:iam::111111111111:role/AWSAFTAdmin --profile aft`,
+	Args: cobra.NoArgs,
+	RunE: run,
+}
+
+func init() {
+	flags := Cmd.Flags()
+
+	flags.StringVar(
+		&args.roleARN,
+		"role-arn",
+		"",
+		"ARN of the role to assume",
+	)
+	Cmd.MarkFlagRequired("role-arn")
+
+	flags.StringVar(
+		&args.sessionName,
+		"session-name",
+		"AWSAFT-Session",
+		"Name of the role session",
+	)
+
+	flags.StringVar(
+		&args.externalID,
+		"external-id",
+		"",
+		"External ID required by the trust policy of the role",
+	)
+
+	flags.DurationVar(
+		&args.duration,
+		"duration",
+		time.Hour,
+		"Duration of the role session, from 15m to 12h",
+	)
+
+	flags.StringVar(
+		&args.configFile,
+		"config-file",
+		"",
+		"AWS config file to read the profiles from instead of the AWS_CONFIG_FILE one, the profile of the environment is then ignored",
+	)
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	if _, err := validate.CheckSessionName(args.sessionName); err != nil {
+		return err
+	}
+	if _, err := validate.CheckSessionDuration(args.duration); err != nil {
+		return err
+	}
+
+	// the process runs with the environment of the SDK running it, which may point to the profile running the process
+	if args.configFile != "" {
+		os.Setenv("AWS_CONFIG_FILE", args.configFile)
+		os.Unsetenv("AWS_PROFILE")
+		os.Unsetenv("AWS_DEFAULT_PROFILE")
+	}
+
+	// the SDK running the process reads stdout, the logs go to stderr
+	awsClient := aws.NewClient(aws.FlagOptions())
+
+	credentials, err := profile.AssumeRole(awsClient.GetSTSClient(), args.roleARN, profile.Options{
+		SessionName: args.sessionName,
+		ExternalID:  args.externalID,
+		Duration:    args.duration,
+	})
+	if err != nil {
+		return fmt.Errorf("error assuming the role %s: %w", args.roleARN, err)
+	}
+
+	logging.AddSecret(credentials.AccessKeyID, credentials.SecretAccessKey, credentials.SessionToken)
+
+	content, err := credentials.ProcessOutput()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(content))
+	return err
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/edgarsilva948/aftctl/pkg/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 )
@@ -83,7 +84,7 @@ func Run(cmd *cobra.Command, _ []string) (err error) {
 		return err
 	}
 
-	fmt.Println("Documents generated successfully on", args.dir)
+	logging.Infof("Documents generated successfully on %s", args.dir)
 
 	return
 }
@@ -0,0 +1,609 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package initialize provides the init command generating the deployment manifest
+package initialize
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/manifest"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+	"github.com/spf13/cobra"
+)
+
+// names Control Tower gives to its shared accounts
+const (
+	logArchiveAccountName = "Log Archive"
+	auditAccountName      = "Audit"
+)
+
+var args struct {
+	file           string
+	nonInteractive bool
+	force          bool
+	noDetect       bool
+
+	manifest manifest.Manifest
+}
+
+// Cmd is the exported command generating the deployment manifest.
+var Cmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generate the deployment manifest of an AFT deployment",
+	Long: "Generate the deployment manifest (deployment.yaml) of an AFT deployment.\n" +
+		"The account IDs and the CT home region are detected from Organizations, Control Tower and the current " +
+		"credentials, then every value is asked with the detected one as default.\n" +
+		"With --non-interactive the values are only taken from the flags and the detection.",
+	Example: `  aftctl init
+
+  aftctl init --non-interactive --no-detect \
+  --region us-east-1 \
+  --aft-account-id 111111111111 \
+  --ct-management-account-id 222222222222 \
+  --ct-log-archive-account-id 333333333333 \
+  --ct-audit-account-id 444444444444 \
+  --ct-home-region us-east-1`,
+	Args: cobra.NoArgs,
+	RunE: run,
+}
+
+func init() {
+	defaults := manifest.New()
+	args.manifest = defaults
+
+	flags := Cmd.Flags()
+	flags.SortFlags = false
+
+	flags.StringVarP(
+		&args.file,
+		"file",
+		"f",
+		manifest.DefaultFileName,
+		"Path of the generated deployment manifest",
+	)
+
+	flags.BoolVar(
+		&args.nonInteractive,
+		"non-interactive",
+		false,
+		"Take every value from the flags and the detection instead of asking for it",
+	)
+
+	flags.BoolVar(
+		&args.force,
+		"force",
+		false,
+		"Overwrite an existing deployment manifest",
+	)
+
+	flags.BoolVar(
+		&args.noDetect,
+		"no-detect",
+		false,
+		"Don't detect the account IDs and the CT home region with the AWS credentials",
+	)
+
+	flags.StringVar(
+		&args.manifest.AFT.ManagementAccountID,
+		"aft-account-id",
+		"",
+		"AFT Management account ID",
+	)
+
+	flags.StringVar(
+		&args.manifest.ControlTower.ManagementAccountID,
+		"ct-management-account-id",
+		"",
+		"CT Management account id (aka payer/root/master account)",
+	)
+
+	flags.StringVar(
+		&args.manifest.ControlTower.LogArchiveAccountID,
+		"ct-log-archive-account-id",
+		"",
+		"CT Log Archive account id",
+	)
+
+	flags.StringVar(
+		&args.manifest.ControlTower.AuditAccountID,
+		"ct-audit-account-id",
+		"",
+		"CT Audit account id",
+	)
+
+	flags.StringVar(
+		&args.manifest.ControlTower.HomeRegion,
+		"ct-home-region",
+		"",
+		"CT main region",
+	)
+
+	flags.StringVar(
+		&args.manifest.ControlTower.SecondaryRegion,
+		"ct-seccondary-region",
+		"",
+		"CT seccondary region",
+	)
+
+	flags.StringVar(
+		&args.manifest.Terraform.Version,
+		"terraform-version",
+		defaults.Terraform.Version,
+		"Terraform version to be used in the deployment and for AFT",
+	)
+
+	flags.StringVar(
+		&args.manifest.Terraform.Distribution,
+		"terraform-distribution",
+		defaults.Terraform.Distribution,
+		fmt.Sprintf("Terraform distribution. Allowed options are %s", validate.TerraformDistributions),
+	)
+
+	flags.StringVar(
+		&args.manifest.Terraform.OrganizationName,
+		"terraform-org-name",
+		"",
+		"Terraform Cloud or Enterprise organization, required by the tfc and tfe distributions",
+	)
+
+	flags.StringVar(
+		&args.manifest.Terraform.StateBucketName,
+		"terraform-state-bucket-name",
+		defaults.Terraform.StateBucketName,
+		"Name of the deployment terraform state bucket",
+	)
+
+	flags.BoolVar(
+		&args.manifest.AFT.Features.MetricsReporting,
+		"aft-enable-metrics-reporting",
+		defaults.AFT.Features.MetricsReporting,
+		"Whether to enable reporting metrics or not",
+	)
+
+	flags.BoolVar(
+		&args.manifest.AFT.Features.CloudtrailDataEvents,
+		"aft-enable-cloudtrail-data-events",
+		defaults.AFT.Features.CloudtrailDataEvents,
+		"Whether to enable cloudtrail data events",
+	)
+
+	flags.BoolVar(
+		&args.manifest.AFT.Features.EnterpriseSupport,
+		"aft-enable-enterprise-support",
+		defaults.AFT.Features.EnterpriseSupport,
+		"Whether to enable enterprise support in created accounts",
+	)
+
+	flags.BoolVar(
+		&args.manifest.AFT.Features.DeleteDefaultVPCs,
+		"aft-delete-default-vpc",
+		defaults.AFT.Features.DeleteDefaultVPCs,
+		"Whether to delete the default VPCs of the created accounts",
+	)
+
+	flags.StringVarP(
+		&args.manifest.Deployment.RepositoryName,
+		"repository-name",
+		"r",
+		defaults.Deployment.RepositoryName,
+		"CodeCommit default repository name",
+	)
+
+	flags.StringVarP(
+		&args.manifest.Deployment.Branch,
+		"branch",
+		"b",
+		defaults.Deployment.Branch,
+		"CodeCommit default branch name",
+	)
+
+	Cmd.RegisterFlagCompletionFunc("terraform-distribution", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return validate.TerraformDistributions, cobra.ShellCompDirectiveDefault
+	})
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	if _, err := os.Stat(args.file); err == nil && !args.force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", args.file)
+	}
+
+	m := args.manifest
+	m.Region = aws.FlagOptions().Region
+
+	if !args.noDetect {
+		client := aws.NewClient(aws.FlagOptions())
+		detect(&m, client.GetRegion(), client.GetSTSClient(), client.GetOrganizationsClient(), client.GetCloudFormationClient())
+	}
+
+	if m.Region == "" {
+		m.Region = m.ControlTower.HomeRegion
+	}
+
+	if args.nonInteractive {
+		if err := checkManifest(m); err != nil {
+			return err
+		}
+	} else {
+		p := newPrompter(cmd.InOrStdin(), cmd.ErrOrStderr())
+		if err := p.askManifest(&m); err != nil {
+			return err
+		}
+	}
+
+	if err := m.Save(args.file); err != nil {
+		return err
+	}
+
+	logging.CustomLog("📝", "green", fmt.Sprintf("Deployment manifest written to %s", args.file))
+
+	return nil
+}
+
+// detect fills the values not given on the command line from the AWS account, a failing detection is only a warning
+func detect(m *manifest.Manifest, region string, stsClient aws.STSClient, organizationsClient aws.OrganizationsClient, cloudformationClient aws.CloudformationClient) {
+	if m.Region == "" {
+		m.Region = region
+	}
+
+	callerAccountID, err := aws.GetCallerAccountID(stsClient)
+	if err != nil {
+		logging.Warnf("unable to detect the caller account: %v", err)
+	}
+
+	if m.ControlTower.ManagementAccountID == "" {
+		accountID, err := aws.GetManagementAccountID(organizationsClient)
+		if err != nil {
+			logging.Warnf("unable to detect the CT management account: %v", err)
+		}
+		m.ControlTower.ManagementAccountID = accountID
+	}
+
+	// AFT must run in its own account, the caller is only a good guess outside of the management account
+	if m.AFT.ManagementAccountID == "" && callerAccountID != m.ControlTower.ManagementAccountID {
+		m.AFT.ManagementAccountID = callerAccountID
+	}
+
+	for name, accountID := range map[string]*string{
+		logArchiveAccountName: &m.ControlTower.LogArchiveAccountID,
+		auditAccountName:      &m.ControlTower.AuditAccountID,
+	} {
+		if *accountID != "" {
+			continue
+		}
+
+		detected, err := aws.FindAccountIDByName(organizationsClient, name)
+		if err != nil {
+			logging.Warnf("unable to detect the %s account: %v", name, err)
+		}
+		*accountID = detected
+	}
+
+	if m.ControlTower.HomeRegion == "" {
+		homeRegion, err := aws.IsControlTowerHomeRegion(cloudformationClient)
+		if err != nil {
+			logging.Warnf("unable to detect the CT home region: %v", err)
+		}
+		if homeRegion {
+			m.ControlTower.HomeRegion = region
+		}
+	}
+}
+
+// question is a value of the manifest asked to the user
+type question struct {
+	flag  string
+	text  string
+	help  string
+	value *string
+	check func(string) error
+}
+
+// questions returns the string values of the manifest in the order they are asked
+func questions(m *manifest.Manifest) []question {
+	return []question{
+		{
+			flag:  "aft-account-id",
+			text:  "AFT management account ID",
+			help:  "Account where AFT is deployed, it must not be the CT management account.",
+			value: &m.AFT.ManagementAccountID,
+			check: checkAccountID,
+		},
+		{
+			flag:  "ct-management-account-id",
+			text:  "CT management account ID",
+			help:  "Account where Control Tower is set up, aka payer/root/master account.",
+			value: &m.ControlTower.ManagementAccountID,
+			check: checkAccountID,
+		},
+		{
+			flag:  "ct-log-archive-account-id",
+			text:  "CT Log Archive account ID",
+			help:  "Account created by Control Tower to store the logs, named \"Log Archive\" by default.",
+			value: &m.ControlTower.LogArchiveAccountID,
+			check: checkAccountID,
+		},
+		{
+			flag:  "ct-audit-account-id",
+			text:  "CT Audit account ID",
+			help:  "Account created by Control Tower for the security team, named \"Audit\" by default.",
+			value: &m.ControlTower.AuditAccountID,
+			check: checkAccountID,
+		},
+		{
+			flag:  "ct-home-region",
+			text:  "CT home region",
+			help:  "Region where Control Tower is set up.",
+			value: &m.ControlTower.HomeRegion,
+			check: checkRegion,
+		},
+		{
+			flag:  "ct-seccondary-region",
+			text:  "CT secondary region (empty for none)",
+			help:  "Region where AFT replicates its Terraform state, it must differ from the home region.",
+			value: &m.ControlTower.SecondaryRegion,
+			check: func(value string) error {
+				if value == "" {
+					return nil
+				}
+				if value == m.ControlTower.HomeRegion {
+					return errors.New("the secondary region must differ from the CT home region")
+				}
+				return checkRegion(value)
+			},
+		},
+		{
+			flag:  "region",
+			text:  "Region of the AFT deployment",
+			help:  "Region where the deployment repository, pipeline and AFT are created, usually the CT home region.",
+			value: &m.Region,
+			check: checkRegion,
+		},
+		{
+			flag:  "terraform-version",
+			text:  "Terraform version",
+			help:  "Terraform version used by the deployment and by AFT.",
+			value: &m.Terraform.Version,
+			check: func(value string) error {
+				_, err := validate.CheckTerraformVersion(value)
+				return err
+			},
+		},
+		{
+			flag:  "terraform-distribution",
+			text:  "Terraform distribution",
+			help:  fmt.Sprintf("One of %s: oss keeps the state in S3, tfc and tfe in Terraform Cloud or Enterprise.", validate.TerraformDistributions),
+			value: &m.Terraform.Distribution,
+			check: func(value string) error {
+				_, err := validate.CheckTerraformDistribution(value)
+				return err
+			},
+		},
+		{
+			flag:  "terraform-org-name",
+			text:  "Terraform organization name",
+			help:  "Terraform Cloud or Enterprise organization holding the AFT workspaces.",
+			value: &m.Terraform.OrganizationName,
+			check: func(value string) error {
+				if value == "" && m.Terraform.Distribution != "oss" {
+					return fmt.Errorf("the %s distribution requires an organization name", m.Terraform.Distribution)
+				}
+				return nil
+			},
+		},
+		{
+			flag:  "repository-name",
+			text:  "Deployment repository name",
+			help:  "CodeCommit repository holding the AFT deployment files.",
+			value: &m.Deployment.RepositoryName,
+			check: checkRequired,
+		},
+		{
+			flag:  "branch",
+			text:  "Deployment branch",
+			help:  "Branch of the deployment repository built by the pipeline.",
+			value: &m.Deployment.Branch,
+			check: checkRequired,
+		},
+	}
+}
+
+// feature is an AFT feature flag asked to the user
+type feature struct {
+	text  string
+	help  string
+	value *bool
+}
+
+// features returns the AFT feature flags in the order they are asked
+func features(m *manifest.Manifest) []feature {
+	return []feature{
+		{
+			text:  "Enable metrics reporting",
+			help:  "Sends anonymous AFT usage metrics to AWS.",
+			value: &m.AFT.Features.MetricsReporting,
+		},
+		{
+			text:  "Enable CloudTrail data events",
+			help:  "Logs the S3 object and Lambda function data events of every account, it increases the CloudTrail costs.",
+			value: &m.AFT.Features.CloudtrailDataEvents,
+		},
+		{
+			text:  "Enable Enterprise Support",
+			help:  "Enrolls the created accounts in Enterprise Support, the organization must have Enterprise Support.",
+			value: &m.AFT.Features.EnterpriseSupport,
+		},
+		{
+			text:  "Delete the default VPCs",
+			help:  "Deletes the default VPC of every region of the created accounts.",
+			value: &m.AFT.Features.DeleteDefaultVPCs,
+		},
+	}
+}
+
+// checkManifest returns every missing or invalid value at once
+func checkManifest(m manifest.Manifest) error {
+	var messages []string
+
+	for _, q := range questions(&m) {
+		if err := q.check(*q.value); err != nil {
+			messages = append(messages, fmt.Sprintf("--%s: %v", q.flag, err))
+		}
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("invalid deployment manifest values:\n  %s", strings.Join(messages, "\n  "))
+	}
+
+	return nil
+}
+
+func checkAccountID(value string) error {
+	if err := checkRequired(value); err != nil {
+		return err
+	}
+	_, err := validate.CheckAWSAccountID(value)
+	return err
+}
+
+func checkRegion(value string) error {
+	if err := checkRequired(value); err != nil {
+		return err
+	}
+	_, err := validate.CheckAWSRegion(value)
+	return err
+}
+
+func checkRequired(value string) error {
+	if value == "" {
+		return errors.New("value is required")
+	}
+	return nil
+}
+
+// prompter asks the questions on the given writer and reads the answers
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// askManifest asks every value of the manifest, the current values are the defaults
+func (p *prompter) askManifest(m *manifest.Manifest) error {
+	for _, q := range questions(m) {
+		// the organization is only asked for Terraform Cloud and Enterprise
+		if q.flag == "terraform-org-name" && m.Terraform.Distribution == "oss" {
+			m.Terraform.OrganizationName = ""
+			continue
+		}
+
+		answer, err := p.ask(q.text, q.help, *q.value, q.check)
+		if err != nil {
+			return err
+		}
+		*q.value = answer
+	}
+
+	for _, f := range features(m) {
+		answer, err := p.confirm(f.text, f.help, *f.value)
+		if err != nil {
+			return err
+		}
+		*f.value = answer
+	}
+
+	return nil
+}
+
+// ask reads an answer until it passes the check, an empty answer keeps the default
+func (p *prompter) ask(text, help, defaultValue string, check func(string) error) (string, error) {
+	fmt.Fprintf(p.out, "\n%s\n", help)
+
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", text, defaultValue)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", text)
+		}
+
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+
+		if answer == "" {
+			answer = defaultValue
+		}
+
+		if err := check(answer); err != nil {
+			fmt.Fprintf(p.out, "Invalid value: %v\n", err)
+			continue
+		}
+
+		return answer, nil
+	}
+}
+
+// confirm reads a yes or no answer, an empty answer keeps the default
+func (p *prompter) confirm(text, help string, defaultValue bool) (bool, error) {
+	choices := "y/N"
+	if defaultValue {
+		choices = "Y/n"
+	}
+
+	answer, err := p.ask(text+" ("+choices+")", help, "", func(value string) error {
+		if value == "" {
+			return nil
+		}
+		_, err := parseYesNo(value)
+		return err
+	})
+	if err != nil || answer == "" {
+		return defaultValue, err
+	}
+
+	return parseYesNo(answer)
+}
+
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+
+	if parsed, err := strconv.ParseBool(value); err == nil {
+		return parsed, nil
+	}
+
+	return false, fmt.Errorf("answer %q is not yes or no", value)
+}
+
+// readLine returns the next answer without the line break
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", errors.New("the answers ended before every value was given, use --non-interactive for scripting")
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
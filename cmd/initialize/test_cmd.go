@@ -0,0 +1,164 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package initialize
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	awsAft "github.com/edgarsilva948/aftctl/pkg/aws"
+	"github.com/edgarsilva948/aftctl/pkg/manifest"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/sts"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// validManifest returns a manifest passing every check
+func validManifest() manifest.Manifest {
+	m := manifest.New()
+	m.Region = "us-east-1"
+	m.AFT.ManagementAccountID = "111111111111"
+	m.ControlTower.ManagementAccountID = "222222222222"
+	m.ControlTower.LogArchiveAccountID = "333333333333"
+	m.ControlTower.AuditAccountID = "444444444444"
+	m.ControlTower.HomeRegion = "us-east-1"
+
+	return m
+}
+
+var _ = ginkgo.Describe("Generating the deployment manifest", func() {
+
+	ginkgo.Context("testing the checkManifest function", func() {
+		ginkgo.When("every value is valid", func() {
+			ginkgo.It("should return no error", func() {
+				gomega.Expect(checkManifest(validManifest())).To(gomega.Succeed())
+			})
+		})
+
+		ginkgo.When("several values are invalid", func() {
+			ginkgo.It("should report all of them", func() {
+				m := validManifest()
+				m.AFT.ManagementAccountID = ""
+				m.ControlTower.SecondaryRegion = "us-east-1"
+				m.Terraform.Distribution = "tfc"
+
+				err := checkManifest(m)
+				gomega.Expect(err).To(gomega.HaveOccurred())
+				gomega.Expect(err.Error()).To(gomega.ContainSubstring("--aft-account-id: value is required"))
+				gomega.Expect(err.Error()).To(gomega.ContainSubstring("--ct-seccondary-region: the secondary region must differ from the CT home region"))
+				gomega.Expect(err.Error()).To(gomega.ContainSubstring("--terraform-org-name: the tfc distribution requires an organization name"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the askManifest function", func() {
+		ginkgo.When("the defaults are accepted", func() {
+			ginkgo.It("should keep the values", func() {
+				m := validManifest()
+				answers := strings.Repeat("\n", len(questions(&m))+len(features(&m)))
+
+				p := newPrompter(strings.NewReader(answers), &bytes.Buffer{})
+				gomega.Expect(p.askManifest(&m)).To(gomega.Succeed())
+				gomega.Expect(m).To(gomega.Equal(validManifest()))
+			})
+		})
+
+		ginkgo.When("an answer is invalid", func() {
+			ginkgo.It("should ask again", func() {
+				m := validManifest()
+				m.AFT.ManagementAccountID = ""
+				answers := "12345\n555555555555\n" + strings.Repeat("\n", len(questions(&m))+len(features(&m)))
+
+				out := &bytes.Buffer{}
+				p := newPrompter(strings.NewReader(answers), out)
+				gomega.Expect(p.askManifest(&m)).To(gomega.Succeed())
+				gomega.Expect(m.AFT.ManagementAccountID).To(gomega.Equal("555555555555"))
+				gomega.Expect(out.String()).To(gomega.ContainSubstring("Invalid value: account id must be 12 characters long"))
+			})
+		})
+
+		ginkgo.When("a feature is declined", func() {
+			ginkgo.It("should disable it", func() {
+				m := validManifest()
+				// the organization name isn't asked with the oss distribution
+				answers := strings.Repeat("\n", len(questions(&m))-1) + "n\n\n\n\n"
+
+				p := newPrompter(strings.NewReader(answers), &bytes.Buffer{})
+				gomega.Expect(p.askManifest(&m)).To(gomega.Succeed())
+				gomega.Expect(m.AFT.Features.MetricsReporting).To(gomega.BeFalse())
+				gomega.Expect(m.AFT.Features.CloudtrailDataEvents).To(gomega.BeTrue())
+			})
+		})
+
+		ginkgo.When("the answers end too early", func() {
+			ginkgo.It("should return an error", func() {
+				m := validManifest()
+
+				p := newPrompter(strings.NewReader("\n"), &bytes.Buffer{})
+				gomega.Expect(p.askManifest(&m)).To(gomega.MatchError(gomega.ContainSubstring("use --non-interactive")))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the detect function", func() {
+		stsClient := &awsAft.MockDiscoverySTSClient{
+			GetCallerIdentityFunc: func(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+				return &sts.GetCallerIdentityOutput{Account: aws.String("111111111111")}, nil
+			},
+		}
+
+		organizationsClient := &awsAft.MockOrganizationsClient{
+			DescribeOrganizationFunc: func(*organizations.DescribeOrganizationInput) (*organizations.DescribeOrganizationOutput, error) {
+				return &organizations.DescribeOrganizationOutput{
+					Organization: &organizations.Organization{MasterAccountId: aws.String("222222222222")},
+				}, nil
+			},
+			Pages: []*organizations.ListAccountsOutput{
+				{Accounts: []*organizations.Account{
+					{Id: aws.String("333333333333"), Name: aws.String("Log Archive")},
+					{Id: aws.String("444444444444"), Name: aws.String("Audit")},
+				}},
+			},
+		}
+
+		ginkgo.When("the landing zone is found", func() {
+			ginkgo.It("should fill the missing values", func() {
+				cloudformationClient := &awsAft.MockCloudformationClient{
+					DescribeStackSetFunc: func(*cloudformation.DescribeStackSetInput) (*cloudformation.DescribeStackSetOutput, error) {
+						return &cloudformation.DescribeStackSetOutput{}, nil
+					},
+				}
+
+				m := manifest.New()
+				detect(&m, "us-east-1", stsClient, organizationsClient, cloudformationClient)
+
+				gomega.Expect(m).To(gomega.Equal(validManifest()))
+			})
+		})
+
+		ginkgo.When("values are given on the command line", func() {
+			ginkgo.It("should keep them", func() {
+				cloudformationClient := &awsAft.MockCloudformationClient{
+					DescribeStackSetFunc: func(*cloudformation.DescribeStackSetInput) (*cloudformation.DescribeStackSetOutput, error) {
+						return nil, errors.New("access denied")
+					},
+				}
+
+				m := manifest.New()
+				m.ControlTower.AuditAccountID = "555555555555"
+				detect(&m, "eu-west-1", stsClient, organizationsClient, cloudformationClient)
+
+				gomega.Expect(m.ControlTower.AuditAccountID).To(gomega.Equal("555555555555"))
+				gomega.Expect(m.ControlTower.HomeRegion).To(gomega.BeEmpty())
+				gomega.Expect(m.Region).To(gomega.Equal("eu-west-1"))
+			})
+		})
+	})
+})
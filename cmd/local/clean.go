@@ -0,0 +1,238 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/edgarsilva948/aftctl/pkg/gitignore"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// planFilePatterns match the plans saved with terraform plan -out
+var planFilePatterns = []string{"*.tfplan", "tfplan"}
+
+// statuses of a cleaned path
+const (
+	removedStatus      = "removed"
+	wouldRemoveStatus  = "would be removed"
+	trackedStatus      = "kept, tracked by git"
+	gitignoreBlockPath = gitignore.FileName + " (aftctl block)"
+)
+
+var cleanArgs struct {
+	dryRun bool
+}
+
+func init() {
+	flags := cleanCmd.Flags()
+
+	flags.BoolVar(
+		&cleanArgs.dryRun,
+		"dry-run",
+		false,
+		"Only list the files that would be removed",
+	)
+
+	Cmd.AddCommand(cleanCmd)
+}
+
+// cleanCmd removes the files aftctl local generates.
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Removes the files aftctl local generates",
+	Long: "Removes the files aftctl local generates in the current directory: the .tf files rendered from the *.jinja " +
+		"templates, the .terraform directory, the plan files and the aftctl block of the .gitignore file.\n" +
+		"The files tracked by git are kept.",
+	Example: `  aftctl local clean --dry-run
+
+  aftctl local clean`,
+	Args: cobra.NoArgs,
+	RunE: runClean,
+}
+
+// CleanResult lists the paths removed by the clean command.
+type CleanResult struct {
+	Directory string        `json:"directory" yaml:"directory"`
+	Paths     []CleanedPath `json:"paths" yaml:"paths"`
+}
+
+// CleanedPath is a generated path and what was done with it.
+type CleanedPath struct {
+	Path   string `json:"path" yaml:"path"`
+	Status string `json:"status" yaml:"status"`
+}
+
+// PrintTable writes one row per path.
+func (r *CleanResult) PrintTable(w io.Writer) error {
+	if len(r.Paths) == 0 {
+		_, err := fmt.Fprintf(w, "No file generated by aftctl in %s\n", r.Directory)
+		return err
+	}
+
+	rows := make([][]string, 0, len(r.Paths))
+	for _, p := range r.Paths {
+		rows = append(rows, []string{p.Path, p.Status})
+	}
+
+	return output.Table(w, []string{"PATH", "STATUS"}, rows)
+}
+
+func runClean(cmd *cobra.Command, _ []string) error {
+	if err := output.CheckFormat(); err != nil {
+		return err
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current directory: %v", err)
+	}
+
+	result, err := clean(pwd, cleanArgs.dryRun)
+	if err != nil {
+		return err
+	}
+
+	return output.Print(cmd.OutOrStdout(), result)
+}
+
+// clean removes the generated files of the directory which aren't tracked by git, then the aftctl block of the
+// .gitignore file
+func clean(dir string, dryRun bool) (*CleanResult, error) {
+	result := &CleanResult{Directory: dir}
+
+	paths, err := generatedPaths(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := trackedFiles(dir, append(paths, gitignore.FileName))
+
+	for _, path := range paths {
+		cleaned := CleanedPath{Path: path, Status: wouldRemoveStatus}
+
+		switch {
+		case tracked[path]:
+			cleaned.Status = trackedStatus
+		case !dryRun:
+			if err := os.RemoveAll(filepath.Join(dir, path)); err != nil {
+				return nil, fmt.Errorf("error removing %s: %w", path, err)
+			}
+			cleaned.Status = removedStatus
+		}
+
+		result.Paths = append(result.Paths, cleaned)
+	}
+
+	found, err := gitignore.HasBlock(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		cleaned := CleanedPath{Path: gitignoreBlockPath, Status: wouldRemoveStatus}
+		if !dryRun {
+			// a tracked .gitignore file is restored to its rules rather than removed
+			if _, err := gitignore.RemoveBlock(dir, tracked[gitignore.FileName]); err != nil {
+				return nil, err
+			}
+			cleaned.Status = removedStatus
+		}
+		result.Paths = append(result.Paths, cleaned)
+	}
+
+	if !dryRun {
+		logging.Infof("removed the files aftctl generated in %s", dir)
+	}
+
+	return result, nil
+}
+
+// generatedPaths returns the paths aftctl local generates which exist in the directory, relative to it
+func generatedPaths(dir string) ([]string, error) {
+	var paths []string
+
+	for _, name := range renderedNames(dir) {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			paths = append(paths, name)
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, ".terraform")); err == nil && info.IsDir() {
+		paths = append(paths, ".terraform")
+	}
+
+	for _, pattern := range planFilePatterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			paths = append(paths, filepath.Base(match))
+		}
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// renderedNames returns the names of the .tf files rendered from the jinja templates of the directory
+func renderedNames(dir string) []string {
+	templates, _ := filepath.Glob(filepath.Join(dir, "*.jinja"))
+
+	names := make([]string, 0, len(templates))
+	for _, template := range templates {
+		names = append(names, strings.TrimSuffix(filepath.Base(template), ".jinja")+".tf")
+	}
+
+	return names
+}
+
+// generatedPatterns returns the .gitignore patterns of the files aftctl local generates in the directory
+func generatedPatterns(dir string) []string {
+	patterns := append(renderedNames(dir), ".terraform*")
+
+	return append(patterns, planFilePatterns...)
+}
+
+// trackedFiles returns the paths tracked by git, none when git can't tell, e.g. outside of a clone
+func trackedFiles(dir string, paths []string) map[string]bool {
+	tracked := map[string]bool{}
+	if len(paths) == 0 {
+		return tracked
+	}
+
+	command := exec.Command("git", append([]string{"ls-files", "-z", "--"}, paths...)...)
+	command.Dir = dir
+
+	content, err := command.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			logging.Debugf("error listing the files tracked by git: %v", err)
+		}
+		return tracked
+	}
+
+	for _, path := range bytes.Split(content, []byte{0}) {
+		if len(path) > 0 {
+			// the files of a tracked directory are listed under it
+			tracked[strings.SplitN(string(path), "/", 2)[0]] = true
+		}
+	}
+
+	return tracked
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+)
+
+// SSM parameters of the Terraform Cloud and Terraform Enterprise distributions
+const (
+	tfOrgName     = "/aft/config/terraform/org-name"
+	tfAPIEndpoint = "/aft/config/terraform/api-endpoint"
+	tfToken       = "/aft/config/terraform/token"
+)
+
+// usesTerraformCloud reports whether the AFT distribution keeps the states in Terraform Cloud or Enterprise
+func usesTerraformCloud(distribution string) bool {
+	return distribution == "tfc" || distribution == "tfe"
+}
+
+// workspaceName returns the Terraform Cloud workspace of the AFT pipeline, the account customizations have a
+// workspace per customization and the repositories applied once a workspace of the AFT management account
+func (r repository) workspaceName(targetAccount string) string {
+	switch r.Type {
+	case accountCustomizationsRepository:
+		return fmt.Sprintf("%s-%s-%s", targetAccount, r.Type, r.Customization)
+	case globalCustomizationsRepository:
+		return fmt.Sprintf("%s-%s", targetAccount, r.Type)
+	}
+
+	return fmt.Sprintf("ct-%s", r.Type)
+}
+
+// stateKey returns where the pipeline keeps the state of the account, the S3 key or the Terraform Cloud workspace
+func (r *localRun) stateKey(targetAccount string) string {
+	if usesTerraformCloud(r.params[tfDistribution]) {
+		return r.repo.workspaceName(targetAccount)
+	}
+
+	return r.repo.stateKey(targetAccount)
+}
+
+// terraformCloudParameters adds the organization and the API endpoint of the tfc and tfe distributions to the
+// parameters
+func terraformCloudParameters(aftParams map[string]string, params map[string]string) error {
+	if !usesTerraformCloud(params[tfDistribution]) {
+		return nil
+	}
+
+	cloud, err := getSSMParameters(aftParams, []string{tfOrgName, tfAPIEndpoint})
+	if err != nil {
+		return err
+	}
+
+	for key, value := range cloud {
+		params[key] = value
+	}
+
+	return nil
+}
+
+// terraformCloudEnv returns the TF_TOKEN_<hostname> variable giving the API token of the AFT deployment to
+// Terraform, the token is never written to a file
+func terraformCloudEnv(client aws.SSMClient, params map[string]string) ([]string, error) {
+	if !usesTerraformCloud(params[tfDistribution]) {
+		return nil, nil
+	}
+
+	token, err := aws.GetSSMParameter(client, tfToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the Terraform API token %s: %w", tfToken, err)
+	}
+	logging.AddSecret(token)
+
+	hostname, err := apiHostname(params[tfAPIEndpoint])
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{tokenEnvVar(hostname) + "=" + token}, nil
+}
+
+// apiHostname returns the hostname of the API endpoint, e.g. app.terraform.io for https://app.terraform.io/api/v2/
+func apiHostname(endpoint string) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Hostname() == "" {
+		return "", fmt.Errorf("invalid Terraform API endpoint %q in %s", endpoint, tfAPIEndpoint)
+	}
+
+	return parsed.Hostname(), nil
+}
+
+// tokenEnvVar returns the variable Terraform reads the token of the hostname from, the dots are replaced by
+// underscores and the hyphens by double underscores
+func tokenEnvVar(hostname string) string {
+	name := strings.ReplaceAll(hostname, "-", "__")
+	name = strings.ReplaceAll(name, ".", "_")
+
+	return "TF_TOKEN_" + name
+}
@@ -6,23 +6,24 @@ Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 package local
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
-	"os/exec"
 	"time"
 
 	"os"
 
-	"path/filepath"
 	"strings"
 
 	"github.com/edgarsilva948/aftctl/pkg/aws"
 	profile "github.com/edgarsilva948/aftctl/pkg/aws/profiles"
+	"github.com/edgarsilva948/aftctl/pkg/cache"
+	"github.com/edgarsilva948/aftctl/pkg/config"
 	"github.com/edgarsilva948/aftctl/pkg/gitignore"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/notify"
+	"github.com/edgarsilva948/aftctl/pkg/output"
+	"github.com/edgarsilva948/aftctl/pkg/selector"
 	validate "github.com/edgarsilva948/aftctl/pkg/validator"
-	"github.com/flosch/pongo2"
-
-	"github.com/caarlos0/log"
 
 	"github.com/spf13/cobra"
 )
@@ -39,23 +40,113 @@ const (
 	tfDynamoDBTableName  = "/aft/config/oss-backend/table-id"
 	tfS3BucketID         = "/aft/config/oss-backend/bucket-id"
 	tfVarFile            = "aft-input.auto.tfvars"
+	sessionNamePrefix    = "AWSAFT-Session"
+
+	aftRequestMetadataTable = "/aft/resources/ddb/aft-request-metadata-table-name"
+
+	// aftParameterPath holds the SSM parameters of the AFT deployment
+	aftParameterPath = "/aft/"
 )
 
+// ssmKeys are the SSM parameters of the AFT deployment aftctl local needs
+var ssmKeys = []string{
+	aftMgmtAccountID,
+	tfDistribution,
+	ctMgmtRegion,
+	aftAdminRoleName,
+	aftExecutionRoleName,
+	tfBackendRegion,
+	tfKmsKeyID,
+	tfDynamoDBTableName,
+	tfS3BucketID,
+}
+
 var args struct {
-	targetAccount    string
-	terraformCommand string
+	targetAccounts    []string
+	accountsFile      string
+	selectors         []string
+	accountsCacheTTL  time.Duration
+	ssmCacheTTL       time.Duration
+	refresh           bool
+	all               bool
+	parallelism       int
+	terraformCommand  string
+	terraformArgs     []string
+	allowDestroy      bool
+	customization     string
+	terraformLogFile  string
+	credentialProcess bool
+	sessionName       string
+	externalID        string
+	sessionDuration   time.Duration
+	mfaSerial         string
+	mfaToken          string
+	skipHelpers       bool
+	terraformBin      string
+	strict            bool
 }
 
 func init() {
 	flags := Cmd.Flags()
 	flags.SortFlags = false
 
-	flags.StringVarP(
-		&args.targetAccount,
+	flags.StringSliceVarP(
+		&args.targetAccounts,
 		"target-account",
 		"a",
+		nil,
+		"Account IDs to be targeted during local execution, several accounts run concurrently",
+	)
+
+	flags.StringVar(
+		&args.accountsFile,
+		"accounts-file",
 		"",
-		"Account ID to be targeted during local execution",
+		"File listing the account IDs to be targeted, one per line",
+	)
+
+	flags.StringArrayVarP(
+		&args.selectors,
+		"selector",
+		"l",
+		nil,
+		"Target the accounts matching all the comma separated terms, e.g. ou=Root/Sandbox,tag:env=dev, "+
+			"the keys are ou, name, email, customization and tag:KEY, repeat the flag to add accounts",
+	)
+
+	flags.DurationVar(
+		&args.accountsCacheTTL,
+		"accounts-cache-ttl",
+		time.Hour,
+		"How long the accounts of the organization are cached for the selectors, 0 disables the cache",
+	)
+
+	flags.DurationVar(
+		&args.ssmCacheTTL,
+		"ssm-cache-ttl",
+		time.Hour,
+		"How long the SSM parameters of the AFT deployment are cached, 0 disables the cache",
+	)
+
+	flags.BoolVar(
+		&args.refresh,
+		"refresh",
+		false,
+		"Look up the SSM parameters and the accounts of the organization again instead of using the cache",
+	)
+
+	flags.BoolVar(
+		&args.all,
+		"all",
+		false,
+		"Target every active account of the organization",
+	)
+
+	flags.IntVar(
+		&args.parallelism,
+		"parallelism",
+		4,
+		"Maximum number of accounts running Terraform at the same time",
 	)
 
 	flags.StringVarP(
@@ -65,302 +156,733 @@ func init() {
 		"",
 		"The terraform command to be executed locally",
 	)
+	flags.MarkDeprecated("terraform-command", "give the Terraform arguments after --, e.g. aftctl local -a ACCOUNT -- plan")
+
+	flags.BoolVar(
+		&args.allowDestroy,
+		"allow-destroy",
+		false,
+		"Allow the Terraform commands destroying resources or changing the state of the target account, e.g. destroy or state rm",
+	)
+
+	flags.StringVar(
+		&args.customization,
+		"customization",
+		"",
+		"Folder of the account customization, detected from the current directory by default",
+	)
+
+	flags.StringVar(
+		&args.terraformLogFile,
+		"terraform-log-file",
+		"",
+		"Also write the Terraform output to this file",
+	)
+
+	flags.StringVar(
+		&args.terraformBin,
+		"terraform-bin",
+		"",
+		"Terraform binary to run, e.g. tofu for OpenTofu, terraform or else tofu from the PATH by default",
+	)
+
+	flags.BoolVar(
+		&args.strict,
+		"strict",
+		false,
+		"Fail instead of warning when the Terraform binary doesn't match the version and distribution of the AFT pipeline",
+	)
+
+	flags.BoolVar(
+		&args.credentialProcess,
+		"credential-process",
+		false,
+		"Give Terraform a credential_process assuming the AFT Admin role again when the credentials expire, "+
+			"instead of the credentials assumed once",
+	)
+
+	flags.StringVar(
+		&args.sessionName,
+		"session-name",
+		"",
+		"Name of the AFT Admin role session, AWSAFT-Session followed by the caller identity by default",
+	)
+
+	flags.StringVar(
+		&args.externalID,
+		"external-id",
+		"",
+		"External ID required by the trust policy of the AFT Admin role",
+	)
+
+	flags.DurationVar(
+		&args.sessionDuration,
+		"session-duration",
+		time.Hour,
+		"Duration of the AFT Admin role session, from 15m to 12h",
+	)
+
+	flags.StringVar(
+		&args.mfaSerial,
+		"mfa-serial",
+		"",
+		"ARN of the MFA device required by the trust policy of the AFT Admin role",
+	)
+
+	flags.StringVar(
+		&args.mfaToken,
+		"mfa-token",
+		"",
+		"Current code of the MFA device, prompted when --mfa-serial is given without it",
+	)
+
+	flags.BoolVar(
+		&args.skipHelpers,
+		"skip-helpers",
+		false,
+		"Don't run the pre and post api_helpers scripts around terraform apply",
+	)
 }
 
 // Cmd represents the Cobra command for the local AFT execution.
 var Cmd = &cobra.Command{
-	Use:   "local",
+	Use:   "local -a ACCOUNT[,ACCOUNT...] -- TERRAFORM_ARGS...",
 	Short: "Runs AFT locally",
-	Long:  "Runs AFT locally executing the same commands as the pipeline",
-	Run:   Run,
+	Long: "Runs AFT locally executing the same commands as the pipeline.\n" +
+		"The arguments after -- are given to Terraform as they are, the commands destroying resources or changing the state, " +
+		"and applying a saved plan, require --allow-destroy.\n" +
+		"Several target accounts run concurrently, each in its own working copy of the repository, " +
+		"and the run ends with a summary of the accounts.\n" +
+		"Like the AFT pipeline, terraform apply runs between the pre and post api_helpers scripts of the customization.",
+	Example: `  aftctl local -a 111111111111 -- init
+
+  aftctl local -a 111111111111 -- plan -target='module.x["a"]'
+
+  aftctl local -a 111111111111 --allow-destroy -- destroy
+
+  aftctl local -a 111111111111,222222222222 -- plan
+
+  aftctl local -l ou=Root/Sandbox -l customization=sandbox -- plan
+
+  aftctl local --all --parallelism 8 -- plan`,
+	Run: Run,
 }
 
 // Run executes the local command
 func Run(cmd *cobra.Command, argv []string) {
+	if err := output.CheckFormat(); err != nil {
+		logging.Fatalf("%v", err)
+	}
 
-	// client initialization with AFT Credentials
-	awsClient, ssmClient, err := initializeAWSandSSMClients()
+	terraformArgs, err := terraformArguments(cmd, argv)
 	if err != nil {
-		log.Errorf("error initializing AWS and SSM Clients: %v", err)
+		logging.Fatalf("%v", err)
+	}
+	args.terraformArgs = terraformArgs
+
+	start := time.Now()
+	run, err := prepareLocal()
+
+	// several accounts run concurrently, each in its own working copy
+	if err == nil && len(run.accounts) > 1 {
+		runFanOut(cmd, run, start)
 		return
 	}
 
-	// Generate the .gitignore file
-	gitIgnoreGenerated := gitignore.GenerateGitIgnore()
+	result := &Result{
+		Account: strings.Join(args.targetAccounts, ","),
+		Command: strings.Join(args.terraformArgs, " "),
+	}
 
-	// Check the result of the .gitignore generation
-	if gitIgnoreGenerated {
-		log.Info(".gitignore successfully generated")
-	} else {
-		log.Fatalf("error generating .gitignore file")
+	if err == nil {
+		result.Context = run.contextName
+		result.Account = run.accounts[0]
+		err = runLocal(run, result)
 	}
-	// defining the S3 key for the local execution
-	var tfS3Key string
 
-	// getting the current directory
-	pwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println("error getting current directory:", err)
-		return
+	result.Duration = output.Since(start)
+	result.finish(err)
+
+	if printErr := output.Print(cmd.OutOrStdout(), result); printErr != nil {
+		logging.Errorf("%v", printErr)
+	}
+
+	if run != nil {
+		run.cleanup()
+	}
+
+	// aftctl exits with the Terraform exit code, e.g. for plan -detailed-exitcode
+	var exitErr *terraformExitError
+	if errors.As(err, &exitErr) {
+		if result.Error != "" {
+			logging.Errorf("%v", err)
+		}
+		logging.Sync()
+		os.Exit(exitErr.code)
 	}
 
-	// Call the getTFS3Key function to determine the appropriate S3 key based on the current directory.
-	tfS3Key, err = getTFS3Key(pwd, args.targetAccount)
 	if err != nil {
-		return
+		logging.Fatalf("%v", err)
 	}
+}
+
+// localRun holds what the runs of the target accounts share
+type localRun struct {
+	contextName string
+	notifier    *notify.Notifier
+	accounts    []string
+	repo        repository
+
+	// dir is the directory aftctl local runs from, inside the repository.
+	dir    string
+	params map[string]string
+
+	// env gives the AFT credentials to Terraform.
+	env []string
+
+	// adminClient assumes the AFT execution role of the target accounts for the api_helpers scripts.
+	adminClient aws.STSClient
+	sessionName string
+
+	// cleanup removes the temporary files of the run.
+	cleanup func()
+}
+
+// prepareLocal resolves the AFT environment, the repository, the target accounts and the AFT credentials
+func prepareLocal() (*localRun, error) {
 
 	// Validate input
 	if err := validateInput(); err != nil {
-		log.Errorf("Validation failed: %v", err)
-		return
+		return nil, fmt.Errorf("Validation failed: %v", err)
 	}
 
-	// Define an array of SSM parameter keys that we need to fetch.
-	ssmKeys := []string{
-		aftMgmtAccountID,
-		tfDistribution,
-		ctMgmtRegion,
-		aftAdminRoleName,
-		aftExecutionRoleName,
-		tfBackendRegion,
-		tfKmsKeyID,
-		tfDynamoDBTableName,
-		tfS3BucketID,
+	notifier, err := notify.FromFlags()
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook settings: %v", err)
 	}
 
-	// Fetch the SSM parameters based on the keys defined above.
-	params := getSSMParameters(ssmClient, ssmKeys)
+	// resolving the AFT environment from the active context
+	contextName, aftContext, err := config.Active()
+	if err != nil {
+		return nil, fmt.Errorf("error loading the aftctl context: %v", err)
+	}
 
-	// Check for DynamoDB table name parameter.
-	tfDynamoDBTableNameParam := params[tfDynamoDBTableName]
+	if contextName != "" {
+		logging.Infow("using aftctl context", "context", contextName)
+	}
 
-	// Check for Management Account ID parameter.
-	aftMgmtAccountIDParam := params[aftMgmtAccountID]
+	clientOptions := contextClientOptions(aftContext)
 
-	// Check for Admin Role Name parameter.
-	aftAdminRoleNameParam := params[aftAdminRoleName]
+	// client initialization with AFT Credentials
+	awsClient, ssmClient, err := initializeAWSandSSMClients(clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing AWS and SSM Clients: %v", err)
+	}
 
-	// Check for Distribution Type parameter.
-	tfDistributionParam := params[tfDistribution]
+	// Make sure the credentials reach the AFT deployment of the context
+	if err := checkContextAccount(ssmClient, aftContext); err != nil {
+		return nil, err
+	}
 
-	// Check for Management Region parameter.
-	ctMgmtRegionParam := params[ctMgmtRegion]
+	// Fetch the SSM parameters of the AFT deployment in one batch, the context values take precedence.
+	cacheName, ttl := ssmCache(awsClient.GetSTSClient(), awsClient.GetRegion(), cacheTTL(args.ssmCacheTTL, args.refresh))
+	aftParams, err := loadSSMParameters(ssmClient, cacheName, ttl, aftContext.SSMParameters)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check for Backend Region parameter.
-	tfBackendRegionParam := params[tfBackendRegion]
+	// getting the current directory
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting current directory: %v", err)
+	}
 
-	// Check for Execution Role Name parameter.
-	aftExecutionRoleNameParam := params[aftExecutionRoleName]
+	// Keep the generated files out of git with the aftctl block of the .gitignore file, the other rules are kept
+	changed, err := gitignore.Merge(pwd, generatedPatterns(pwd))
+	if err != nil {
+		return nil, fmt.Errorf("error updating the .gitignore file: %v", err)
+	}
+	if changed {
+		logging.Infof(".gitignore successfully updated")
+	}
 
-	// Check for S3 Bucket ID parameter.
-	tfS3BucketIDParam := params[tfS3BucketID]
+	// Detect the AFT repository to determine the S3 key used by the AFT pipeline.
+	repo, err := detectRepository(pwd, args.customization, func() map[repositoryType]string {
+		return getRepositoryNames(aftParams)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Check for KMS Key ID parameter.
-	tfKmsKeyIDParam := params[tfKmsKeyID]
+	accounts, err := targetAccounts(awsClient, aftParams)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := getSSMParameters(aftParams, ssmKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tfc and tfe distributions keep the states in Terraform Cloud, the token is given to Terraform with the credentials
+	if err := terraformCloudParameters(aftParams, params); err != nil {
+		return nil, err
+	}
+	cloudEnv, err := terraformCloudEnv(ssmClient, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the Terraform binary the AFT pipeline runs, a different one is only a warning without --strict
+	terraformBinary, err = resolveTerraformBinary(args.terraformBin)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTerraformVersion(terraformBinary, aftParams, args.strict); err != nil {
+		return nil, err
+	}
+
+	run := &localRun{
+		contextName: contextName,
+		notifier:    notifier,
+		accounts:    accounts,
+		repo:        repo,
+		dir:         pwd,
+		params:      params,
+		cleanup:     func() {},
+	}
+
+	// Assume the roles from the caller to the target accounts, a denied hop fails before Terraform runs
+	options, err := adminSessionOptions(awsClient.GetSTSClient(), os.Stdin, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	adminRoleARN := profile.RoleARN(params[aftMgmtAccountID], params[aftAdminRoleName])
+	credentials, adminClient, err := assumeRoleChain(awsClient, adminRoleARN, accounts, params[aftExecutionRoleName], options)
+	if err != nil {
+		return nil, err
+	}
+	run.adminClient = adminClient
+	run.sessionName = options.SessionName
+
+	// Terraform gets the AFT Admin credentials from its environment, or from a credential_process refreshing them
+	run.env = credentials.Env()
+	if args.credentialProcess {
+		run.env, run.cleanup, err = credentialProcessEnv(adminRoleARN, options, clientOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	run.env = append(run.env, cloudEnv...)
+
+	return run, nil
+}
+
+// runLocal runs the Terraform command against the target account from the current directory
+func runLocal(run *localRun, result *Result) error {
+	result.StateKey = run.stateKey(result.Account)
 
 	// calling the function to process Jinja files
-	processJinjaFiles(
-		tfDistributionParam,
-		ctMgmtRegionParam,
-		tfBackendRegionParam,
-		aftMgmtAccountIDParam,
-		aftExecutionRoleNameParam,
-		tfS3BucketIDParam,
-		tfS3Key,
-		tfDynamoDBTableNameParam,
-		tfKmsKeyIDParam,
-	)
+	if err := processJinjaFiles(run.dir, result.Account, run.params, run.repo); err != nil {
+		return err
+	}
+
+	fields := map[string]string{
+		"account": result.Account,
+		"command": strings.Join(args.terraformArgs, " "),
+	}
 
-	// setup the AWS Profile and Assume Role
-	accessKey, secretKey, sessionToken, err := setupAWSProfileAndAssumeRole(awsClient, aftMgmtAccountIDParam, aftAdminRoleNameParam)
+	notifyEvent(run.notifier, notify.LocalRunStarted, "Running Terraform locally", fields)
+
+	streams, closeLog, err := teeStreams(standardStreams(!output.IsTable()), args.terraformLogFile)
 	if err != nil {
-		log.Errorf("Failed to setup AWS Profile and assume role: %v", err)
-		return
+		return err
+	}
+	defer closeLog()
+
+	// calling the function to execute Terraform command, between the api_helpers scripts for apply
+	err = run.withAPIHelpers(run.repo.Root, result.Account, streams, func() error {
+		logging.Infow("executing Terraform command", "args", args.terraformArgs)
+		return runTerraform(run.dir, args.terraformArgs, run.env, streams)
+	})
+
+	if isPlanWithChanges(err) {
+		notifyEvent(run.notifier, notify.LocalRunSucceeded, "Local Terraform plan has changes", fields)
+		return err
+	}
+
+	if err != nil {
+		notifyEvent(run.notifier, notify.LocalRunFailed, err.Error(), fields)
+		return err
+	}
+
+	notifyEvent(run.notifier, notify.LocalRunSucceeded, "Local Terraform run completed", fields)
+
+	return nil
+}
+
+// loadSSMParameters returns the SSM parameters of the AFT deployment under /aft/, from the cache when it's fresher
+// than the ttl, the context values take precedence
+func loadSSMParameters(client aws.SSMClient, cacheName string, ttl time.Duration, overrides map[string]string) (map[string]string, error) {
+	params := map[string]string{}
+
+	found := false
+	if ttl > 0 {
+		var err error
+		found, err = cache.Load(cacheName, ttl, &params)
+		if err != nil {
+			logging.Warnf("%v", err)
+		}
 	}
 
-	// calling the function to execute Terraform command
-	log.WithField("command", args.terraformCommand).Info("executing Terraform command")
-	executeTerraformCommand(args.terraformCommand, accessKey, secretKey, sessionToken)
+	if found {
+		logging.Debugf("using the SSM parameters cached in %s", cacheName)
+	} else {
+		fetched, err := aws.GetSSMParametersByPath(client, aftParameterPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the SSM parameters under %s: %w", aftParameterPath, err)
+		}
+		params = fetched
 
+		if ttl > 0 {
+			if err := cache.Save(cacheName, params); err != nil {
+				logging.Warnf("%v", err)
+			}
+		}
+	}
+
+	for key, value := range overrides {
+		params[key] = value
+	}
+
+	return params, nil
 }
 
-func getSSMParameters(client aws.SSMClient, paramKeys []string) map[string]string {
+// getSSMParameters returns the values of the keys, the missing parameters are reported together
+func getSSMParameters(aftParams map[string]string, paramKeys []string) (map[string]string, error) {
 	params := make(map[string]string)
 
+	var missing []string
 	for _, key := range paramKeys {
-		param, err := aws.GetSSMParameter(client, key)
-		if err != nil {
-			handleError(err, "failed to get SSM Parameter for key: "+key)
-			return nil
+		value, ok := aftParams[key]
+		if !ok {
+			missing = append(missing, key)
+			continue
 		}
-		params[key] = param
+		params[key] = value
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing SSM parameters %s, check the AFT deployment or set them on the context "+
+			"with aftctl context set --ssm-parameter", strings.Join(missing, ", "))
 	}
 
-	return params
+	return params, nil
 }
 
-func handleError(err error, msg string) {
-	if err != nil {
-		log.Fatalf("%s: %v", msg, err)
-		os.Exit(1)
+// ssmCache returns the cache entry of the SSM parameters of the AFT management account and region and its ttl, the
+// cache is disabled when the account is unknown as the entry could hold the parameters of another account
+func ssmCache(client aws.STSClient, region string, ttl time.Duration) (string, time.Duration) {
+	callerAccount, err := aws.GetCallerAccountID(client)
+	if err != nil || callerAccount == "" {
+		// the parameters are still fetched, only the cache is skipped
+		logging.Debugf("not caching the SSM parameters, error getting the caller account: %v", err)
+		return "", 0
 	}
+
+	return fmt.Sprintf("ssm-%s-%s.json", callerAccount, region), ttl
 }
 
-func executeTerraformCommand(terraformCommand, accessKey, secretKey, sessionToken string) {
-	commandWithArgs := strings.Fields(terraformCommand)
-	terraformCmd := exec.Command("terraform", commandWithArgs...)
-	terraformCmd.Env = append(os.Environ(),
-		"AWS_ACCESS_KEY_ID="+accessKey,
-		"AWS_SECRET_ACCESS_KEY="+secretKey,
-		"AWS_SESSION_TOKEN="+sessionToken,
-	)
+// cacheTTL returns the ttl of the caches, 0 disables them when they are refreshed
+func cacheTTL(ttl time.Duration, refresh bool) time.Duration {
+	if refresh {
+		return 0
+	}
 
-	var stdout, stderr bytes.Buffer
-	terraformCmd.Stdout = &stdout
-	terraformCmd.Stderr = &stderr
+	return ttl
+}
 
-	err := terraformCmd.Run()
-	handleError(err, "cmd.Run() failed")
+// contextClientOptions returns the AWS client options of the context, the profile and region given on the command
+// line take precedence
+func contextClientOptions(aftContext config.Context) aws.ClientOptions {
+	clientOptions := aws.FlagOptions()
+	if clientOptions.Profile == "" {
+		clientOptions.Profile = aftContext.Profile
+	}
+	if clientOptions.Region == "" {
+		clientOptions.Region = aftContext.Region
+	}
 
+	return clientOptions
+}
+
+// checkContextAccount makes sure the credentials reach the AFT management account of the context
+func checkContextAccount(client aws.SSMClient, aftContext config.Context) error {
+	if aftContext.AftManagementAccountID == "" {
+		return nil
+	}
+
+	accountID, err := aws.GetSSMParameter(client, aftMgmtAccountID)
 	if err != nil {
-		log.Fatalf("cmd.Run() failed: %s\nStderr: %s", err, stderr.String())
+		return fmt.Errorf("failed to get SSM Parameter for key %s: %w", aftMgmtAccountID, err)
+	}
+
+	if accountID != aftContext.AftManagementAccountID {
+		return fmt.Errorf("the credentials reach the AFT management account %s but the context expects %s, "+
+			"check the profile of the context", accountID, aftContext.AftManagementAccountID)
 	}
 
-	fmt.Printf("output:\n%s\n", stdout.String())
+	return nil
 }
 
-// Define function to set tfS3Key based on the current directory
-func getTFS3Key(pwd string, targetAccount string) (string, error) {
-	var tfS3Key string
+// notifyEvent posts the event to the webhook, a failing webhook doesn't fail the run
+func notifyEvent(notifier *notify.Notifier, eventType notify.EventType, message string, fields map[string]string) {
+	err := notifier.Notify(notify.Event{
+		Type:    eventType,
+		Message: message,
+		Fields:  fields,
+	})
 
-	// Check if the current directory is "aft-account-customizations" and set tfS3Key accordingly
-	if strings.Contains(pwd, "aft-account-customizations") {
-		tfS3Key = fmt.Sprintf("%s-aft-account-customizations/terraform.tfstate", targetAccount)
-	} else if strings.Contains(pwd, "aft-global-customizations") {
-		// Check if the current directory is "aft-global-customizations" and set tfS3Key accordingly
-		tfS3Key = fmt.Sprintf("%s-aft-global-customizations/terraform.tfstate", targetAccount)
-	} else { // If the directory is neither, return an error
-		log.Errorf("Run aftctl local from the aft-account-customizations or aft-global-customizations repository.")
-		return "", fmt.Errorf("invalid directory")
+	if err != nil {
+		logging.Warnf("%v", err)
+	}
+}
+
+// isPlanWithChanges reports whether Terraform exited with 2 on plan -detailed-exitcode, meaning the plan has changes
+func isPlanWithChanges(err error) bool {
+	var exitErr *terraformExitError
+	if !errors.As(err, &exitErr) || exitErr.code != 2 {
+		return false
 	}
 
-	return tfS3Key, nil
+	return hasDetailedExitCode(args.terraformArgs)
 }
 
-func processJinjaFiles(
-	tfDistributionParam string,
-	ctMgmtRegionParam string,
-	tfBackendRegionParam string,
-	aftMgmtAccountIDParam string,
-	aftExecutionRoleNameParam string,
-	tfS3BucketIDParam string,
-	tfS3Key string,
-	tfDynamoDBTableNameParam string,
-	tfKmsKeyIDParam string,
-) error {
-
-	// Read all files with the extension ".jinja"
-	files, err := filepath.Glob("*.jinja")
+// hasDetailedExitCode reports whether the Terraform arguments hold -detailed-exitcode
+func hasDetailedExitCode(terraformArgs []string) bool {
+	for _, arg := range terraformArgs {
+		if arg == "-detailed-exitcode" || arg == "--detailed-exitcode" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// terraformArguments returns the Terraform arguments given after --, or split from the deprecated --terraform-command
+func terraformArguments(cmd *cobra.Command, argv []string) ([]string, error) {
+	if !cmd.Flags().Changed("terraform-command") {
+		return argv, nil
+	}
+
+	if len(argv) > 0 {
+		return nil, errors.New("--terraform-command can't be used together with the arguments after --")
+	}
+
+	return strings.Fields(args.terraformCommand), nil
+}
+
+// processJinjaFiles renders the jinja templates of the directory for the target account
+func processJinjaFiles(dir string, targetAccount string, params map[string]string, repo repository) error {
+	files, err := renderTemplates(dir, templateContext(targetAccount, params, repo))
 	if err != nil {
-		return fmt.Errorf("error reading jinja files: %v", err)
+		return err
 	}
 
-	for _, f := range files {
-		// Read the jinja template file
-		input, err := os.ReadFile(f)
+	return writeRenderedFiles(files)
+}
+
+func validateInput() error {
+	if args.all && (len(args.targetAccounts) > 0 || args.accountsFile != "" || len(args.selectors) > 0) {
+		return errors.New("--all can't be used together with --target-account, --accounts-file or --selector")
+	}
+	if !args.all && len(args.targetAccounts) == 0 && args.accountsFile == "" && len(args.selectors) == 0 {
+		return errors.New("give the target accounts with --target-account, --accounts-file, --selector or --all")
+	}
+	for _, s := range args.selectors {
+		if _, err := selector.Parse(s); err != nil {
+			return err
+		}
+	}
+	for _, account := range args.targetAccounts {
+		_, err := validate.CheckAWSAccountID(account)
 		if err != nil {
-			fmt.Println("error reading file:", f, err)
-			continue
+			return fmt.Errorf("invalid AWS Account ID: %w", err)
 		}
+	}
+	if args.sessionName != "" {
+		if _, err := validate.CheckSessionName(args.sessionName); err != nil {
+			return err
+		}
+	}
+	if _, err := validate.CheckSessionDuration(args.sessionDuration); err != nil {
+		return err
+	}
+	if args.mfaToken != "" {
+		if args.mfaSerial == "" {
+			return errors.New("--mfa-token requires --mfa-serial")
+		}
+		if _, err := validate.CheckMFAToken(args.mfaToken); err != nil {
+			return err
+		}
+	}
+	if args.mfaSerial != "" && args.credentialProcess {
+		return errors.New("--credential-process can't prompt for the MFA token, it can't be used together with --mfa-serial")
+	}
+	if args.parallelism < 1 {
+		return fmt.Errorf("invalid parallelism %d, at least one account must run at a time", args.parallelism)
+	}
+	_, err := validate.CheckTerraformArgs(args.terraformArgs, args.allowDestroy)
+	if err != nil {
+		return fmt.Errorf("invalid Terraform arguments: %w", err)
+	}
+	return nil
+}
 
-		// Parse the jinja template
-		template, err := pongo2.FromString(string(input))
+// targetAccounts returns the accounts given with --target-account, --accounts-file and --selector without duplicates,
+// or every active account of the organization with --all
+func targetAccounts(awsClient *aws.Client, aftParams map[string]string) ([]string, error) {
+	if args.all {
+		accounts, err := aws.ListActiveAccountIDs(awsClient.GetOrganizationsClient())
 		if err != nil {
-			fmt.Println("error parsing template:", err)
-			continue
+			return nil, fmt.Errorf("error listing the accounts of the organization: %v", err)
 		}
+		if len(accounts) == 0 {
+			return nil, errors.New("the organization has no active account")
+		}
+		return accounts, nil
+	}
 
-		// Define constants and generate timestamp
-		partition := "aws"
-		timestamp := time.Now().Format(time.RFC3339)
-
-		// Execute the template with context
-		output, err := template.Execute(pongo2.Context{
-			"timestamp":             timestamp,
-			"tf_distribution_type":  tfDistributionParam,
-			"provider_region":       ctMgmtRegionParam,
-			"region":                tfBackendRegionParam,
-			"aft_admin_role_arn":    fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, aftMgmtAccountIDParam, aftExecutionRoleNameParam),
-			"target_admin_role_arn": fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, args.targetAccount, aftExecutionRoleNameParam),
-			"bucket":                tfS3BucketIDParam,
-			"key":                   tfS3Key,
-			"dynamodb_table":        tfDynamoDBTableNameParam,
-			"kms_key_id":            tfKmsKeyIDParam,
-		})
+	accounts := append([]string{}, args.targetAccounts...)
+	if args.accountsFile != "" {
+		fromFile, err := readAccountsFile(args.accountsFile)
 		if err != nil {
-			fmt.Println("error executing template:", err)
-			continue
+			return nil, err
 		}
+		accounts = append(accounts, fromFile...)
+	}
 
-		// Write the output to a new file with the same name but different extension
-		outputFile := fmt.Sprintf("./%s.tf", strings.TrimSuffix(f, ".jinja"))
-		if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
-			fmt.Println("error writing output file:", err)
-			continue
+	if len(args.selectors) > 0 {
+		selected, err := selectAccounts(awsClient, aftParams)
+		if err != nil {
+			return nil, err
 		}
+		accounts = append(accounts, selected...)
 	}
 
-	return nil
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		if !seen[account] {
+			seen[account] = true
+			unique = append(unique, account)
+		}
+	}
+
+	if len(unique) == 0 {
+		return nil, errors.New("no target account found")
+	}
+
+	return unique, nil
 }
 
-func validateInput() error {
-	_, err := validate.CheckAWSAccountID(args.targetAccount)
+// selectAccounts returns the accounts matching one of the selectors, the accounts of the organization are cached
+// for the AFT management account
+func selectAccounts(awsClient *aws.Client, aftParams map[string]string) ([]string, error) {
+	selectors := make([]selector.Selector, 0, len(args.selectors))
+	for _, s := range args.selectors {
+		parsed, err := selector.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, parsed)
+	}
+
+	callerAccount, err := aws.GetCallerAccountID(awsClient.GetSTSClient())
 	if err != nil {
-		return fmt.Errorf("invalid AWS Account ID: %w", err)
+		return nil, fmt.Errorf("error getting the caller account: %v", err)
 	}
-	_, err = validate.CheckTerraformCommand(args.terraformCommand)
+
+	accounts, err := selector.Accounts(fmt.Sprintf("accounts-%s.json", callerAccount), cacheTTL(args.accountsCacheTTL, args.refresh), func() ([]aws.Account, error) {
+		return listAccounts(awsClient.GetOrganizationsClient(), awsClient.GetDynamoDBClient(), aftParams)
+	})
 	if err != nil {
-		return fmt.Errorf("invalid Terraform Command: %w", err)
+		return nil, err
 	}
-	return nil
+
+	selected := selector.Select(accounts, selectors)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no account matches the selectors %s", strings.Join(args.selectors, " "))
+	}
+
+	logging.Infow("selected target accounts", "accounts", selected)
+
+	return selected, nil
 }
 
-func initializeAWSandSSMClients() (*aws.Client, aws.SSMClient, error) {
-	log.Info("initializing AWS Client using AFT Account credentials")
-	awsClient := aws.NewClient("")
-	if awsClient == nil {
-		return nil, nil, fmt.Errorf("failed to initialize AWS client")
+// listAccounts returns the accounts of the organization with the account customization of their AFT account request
+func listAccounts(orgClient aws.OrganizationsClient, ddbClient aws.DynamoDBClient, aftParams map[string]string) ([]aws.Account, error) {
+	accounts, err := aws.ListOrganizationAccounts(orgClient)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the accounts of the organization: %v", err)
 	}
 
-	ssmClient := awsClient.GetSSMClient()
-	if ssmClient == nil {
-		return nil, nil, fmt.Errorf("failed to initialize SSM client")
+	params, err := getSSMParameters(aftParams, []string{aftRequestMetadataTable})
+	if err != nil {
+		return nil, err
 	}
 
-	return awsClient, ssmClient, nil
-}
+	names, err := aws.GetAccountCustomizationsNames(ddbClient, params[aftRequestMetadataTable])
+	if err != nil {
+		return nil, fmt.Errorf("error reading the AFT request metadata table: %v", err)
+	}
 
-func setupAWSProfileAndAssumeRole(awsClient *aws.Client, aftMgmtAccountIDParam string, aftAdminRoleNameParam string) (string, string, string, error) {
-	// setting up the AWS profile for the AFT Account using the user current credentials
-	if err := profile.SetupProfile(awsClient.GetSTSClient(), aftMgmtAccountIDParam, aftAdminRoleNameParam, "AWSAFT-Session"); err != nil {
-		return "", "", "", fmt.Errorf("error setting up profile: %v", err)
+	for i := range accounts {
+		accounts[i].CustomizationsName = names[accounts[i].ID]
 	}
 
-	log.Infof("successfully set up AWS profile %s-%s", aftMgmtAccountIDParam, aftAdminRoleNameParam)
+	return accounts, nil
+}
 
-	// Assuming the AFT Admin Role in the AFT Account
-	profileVariable := aftMgmtAccountIDParam + "-" + aftAdminRoleNameParam
-	aws.NewClient(profileVariable)
-	accessKey, secretKey, sessionToken, err := aws.GetAWSCredentials(profileVariable)
+// readAccountsFile returns the account IDs of the file, one per line, the blank lines and the # comments are ignored
+func readAccountsFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return "", "", "", fmt.Errorf("error getting AFT Admin credentials: %v", err)
+		return nil, fmt.Errorf("error reading the accounts file: %v", err)
 	}
 
-	// Set the AWS_PROFILE environment variable
-	if err := os.Setenv("AWS_PROFILE", profileVariable); err != nil {
-		return "", "", "", fmt.Errorf("error setting env var AWS_PROFILE: %v", err)
+	var accounts []string
+	for i, line := range strings.Split(string(content), "\n") {
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+
+		account := strings.TrimSpace(line)
+		if account == "" {
+			continue
+		}
+
+		if _, err := validate.CheckAWSAccountID(account); err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid AWS Account ID: %w", path, i+1, err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+func initializeAWSandSSMClients(options aws.ClientOptions) (*aws.Client, aws.SSMClient, error) {
+	logging.Infof("initializing AWS Client using AFT Account credentials")
+	awsClient := aws.NewClient(options)
+	if awsClient == nil {
+		return nil, nil, fmt.Errorf("failed to initialize AWS client")
+	}
+
+	ssmClient := awsClient.GetSSMClient()
+	if ssmClient == nil {
+		return nil, nil, fmt.Errorf("failed to initialize SSM client")
 	}
 
-	return accessKey, secretKey, sessionToken, nil
+	return awsClient, ssmClient, nil
 }
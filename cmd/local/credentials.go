@@ -0,0 +1,202 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	profile "github.com/edgarsilva948/aftctl/pkg/aws/profiles"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+)
+
+// executionRoleCheckDuration is the shortest session, the credentials of the check are dropped
+const executionRoleCheckDuration = 15 * time.Minute
+
+// adminSessionOptions returns the settings of the AFT Admin role session, the session name defaults to
+// AWSAFT-Session followed by the caller identity and the MFA token is prompted when it's not given
+func adminSessionOptions(stsClient aws.STSClient, in io.Reader, out io.Writer) (profile.Options, error) {
+	options := profile.Options{
+		SessionName: args.sessionName,
+		ExternalID:  args.externalID,
+		Duration:    args.sessionDuration,
+		MFASerial:   args.mfaSerial,
+		MFAToken:    args.mfaToken,
+	}
+
+	if options.SessionName == "" {
+		callerARN, err := aws.GetCallerARN(stsClient)
+		if err != nil {
+			return profile.Options{}, fmt.Errorf("error getting the caller identity: %v", err)
+		}
+		options.SessionName = profile.SessionName(sessionNamePrefix, callerARN)
+	}
+
+	if options.MFASerial != "" && options.MFAToken == "" {
+		fmt.Fprintf(out, "MFA token for %s: ", options.MFASerial)
+
+		token, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && token == "" {
+			return profile.Options{}, fmt.Errorf("error reading the MFA token, give it with --mfa-token: %v", err)
+		}
+
+		options.MFAToken = strings.TrimSpace(token)
+		if _, err := validate.CheckMFAToken(options.MFAToken); err != nil {
+			return profile.Options{}, err
+		}
+	}
+
+	return options, nil
+}
+
+// assumeRoleChain assumes the AFT Admin role with the caller credentials, then checks the AFT Admin role can assume
+// the AFT execution role of every target account. The AFT Admin credentials are only kept in memory, the returned
+// client assumes the roles of the target accounts with them.
+func assumeRoleChain(awsClient *aws.Client, adminRoleARN string, accounts []string, executionRoleName string, options profile.Options) (profile.Credentials, aws.STSClient, error) {
+	credentials, err := profile.AssumeRole(awsClient.GetSTSClient(), adminRoleARN, options)
+	if err != nil {
+		return profile.Credentials{}, nil, fmt.Errorf("the caller can't assume the AFT Admin role %s: %v", adminRoleARN, err)
+	}
+
+	// Keep the assumed credentials out of the logs, e.g. when Terraform fails
+	logging.AddSecret(credentials.AccessKeyID, credentials.SecretAccessKey, credentials.SessionToken)
+	logging.Infow("assumed the AFT Admin role", "role", adminRoleARN, "session", options.SessionName, "expiration", credentials.Expiration)
+
+	adminClient, err := profile.NewSTSClient(credentials, awsClient.GetRegion(), aws.FlagOptions().EndpointURL)
+	if err != nil {
+		return profile.Credentials{}, nil, err
+	}
+
+	if err := checkExecutionRoles(adminClient, accounts, executionRoleName, options.SessionName); err != nil {
+		return profile.Credentials{}, nil, err
+	}
+
+	return credentials, adminClient, nil
+}
+
+// checkExecutionRoles assumes the AFT execution role of every account with the AFT Admin credentials, the accounts
+// denied are reported together
+func checkExecutionRoles(adminClient aws.STSClient, accounts []string, executionRoleName string, sessionName string) error {
+	var denied []string
+
+	for _, account := range accounts {
+		roleARN := profile.RoleARN(account, executionRoleName)
+
+		_, err := profile.AssumeRole(adminClient, roleARN, profile.Options{
+			SessionName: sessionName,
+			Duration:    executionRoleCheckDuration,
+		})
+		if err != nil {
+			denied = append(denied, fmt.Sprintf("%s: %v", roleARN, err))
+			continue
+		}
+
+		logging.Debugf("the AFT Admin role can assume %s", roleARN)
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("the AFT Admin role can't assume the AFT execution role of %d account(s):\n  %s",
+			len(denied), strings.Join(denied, "\n  "))
+	}
+
+	return nil
+}
+
+// credentialProcessProfile is the profile of the temporary AWS config file given to Terraform
+const credentialProcessProfile = "aftctl-local"
+
+// aftctlExecutable returns the path of the aftctl executable run by the credential_process
+var aftctlExecutable = os.Executable
+
+// credentialProcessEnv writes a temporary AWS config file whose profile runs aftctl credentials process, so that
+// Terraform gets the AFT Admin credentials again when they expire. The process reads the AWS config file and profile
+// of the user rather than the ones of the returned environment. The returned func removes the file.
+func credentialProcessEnv(roleARN string, session profile.Options, options aws.ClientOptions) ([]string, func(), error) {
+	// the credentials of the environment can't be handed to the credential_process without writing them down
+	if options.Profile == "" && os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return nil, nil, errors.New("--credential-process requires the credentials of a profile, " +
+			"the credentials of the environment variables can't be used")
+	}
+
+	executable, err := aftctlExecutable()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error finding the aftctl executable: %v", err)
+	}
+
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if configFile == "" {
+		configFile = defaults.SharedConfigFilename()
+	}
+
+	// the profile of the environment is replaced by the one running the process
+	awsProfile := options.Profile
+	if awsProfile == "" {
+		awsProfile = os.Getenv("AWS_PROFILE")
+	}
+	if awsProfile == "" {
+		awsProfile = os.Getenv("AWS_DEFAULT_PROFILE")
+	}
+
+	command := []string{executable, "credentials", "process", "--role-arn", roleARN, "--session-name", session.SessionName,
+		"--duration", session.Duration.String(), "--config-file", configFile}
+	if session.ExternalID != "" {
+		command = append(command, "--external-id", session.ExternalID)
+	}
+	if awsProfile != "" {
+		command = append(command, "--profile", awsProfile)
+	}
+	if options.Region != "" {
+		command = append(command, "--region", options.Region)
+	}
+	if options.EndpointURL != "" {
+		command = append(command, "--endpoint-url", options.EndpointURL)
+	}
+
+	file, err := os.CreateTemp("", "aftctl-aws-config-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating the AWS config file: %v", err)
+	}
+	defer file.Close()
+
+	remove := func() { os.Remove(file.Name()) }
+
+	content := fmt.Sprintf("[profile %s]\ncredential_process = %s\n", credentialProcessProfile, processCommand(command))
+	if _, err := file.WriteString(content); err != nil {
+		remove()
+		return nil, nil, fmt.Errorf("error writing the AWS config file: %v", err)
+	}
+
+	return []string{
+		"AWS_CONFIG_FILE=" + file.Name(),
+		"AWS_PROFILE=" + credentialProcessProfile,
+		"AWS_SDK_LOAD_CONFIG=1",
+		// the credentials of the environment would take precedence over the profile
+		"AWS_ACCESS_KEY_ID=",
+		"AWS_SECRET_ACCESS_KEY=",
+		"AWS_SESSION_TOKEN=",
+	}, remove, nil
+}
+
+// processCommand joins the command, quoting the arguments holding spaces as the credential_process is split like a
+// shell command
+func processCommand(command []string) string {
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		if strings.ContainsAny(arg, " \t\"") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+		quoted = append(quoted, arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,272 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/notify"
+	"github.com/edgarsilva948/aftctl/pkg/output"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+	"github.com/spf13/cobra"
+)
+
+// copyExcludes are the directories left out of the working copies of the accounts
+var copyExcludes = map[string]bool{
+	".git":       true,
+	".terraform": true,
+}
+
+// runFanOut runs the Terraform command against the target accounts concurrently and ends with their summary
+func runFanOut(cmd *cobra.Command, run *localRun, start time.Time) {
+	detailedExitCode := hasDetailedExitCode(args.terraformArgs)
+	if validate.TerraformSubcommand(args.terraformArgs) == "plan" && !detailedExitCode {
+		// tells the plans with changes from the plans without
+		args.terraformArgs = append(args.terraformArgs, "-detailed-exitcode")
+	}
+
+	summary := &Summary{
+		Context: run.contextName,
+		Command: strings.Join(args.terraformArgs, " "),
+	}
+
+	fields := map[string]string{
+		"accounts": strconv.Itoa(len(run.accounts)),
+		"command":  summary.Command,
+	}
+
+	notifyEvent(run.notifier, notify.LocalRunStarted, fmt.Sprintf("Running Terraform locally on %d accounts", len(run.accounts)), fields)
+
+	streams, closeLog, err := teeStreams(standardStreams(!output.IsTable()), args.terraformLogFile)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+
+	logging.Infow("executing Terraform command", "args", args.terraformArgs, "accounts", len(run.accounts), "parallelism", args.parallelism)
+	summary.Results = fanOut(run, args.parallelism, streams)
+	summary.Duration = output.Since(start)
+	closeLog()
+	run.cleanup()
+
+	if err := output.Print(cmd.OutOrStdout(), summary); err != nil {
+		logging.Errorf("%v", err)
+	}
+
+	switch failed := summary.count(errorStatus); {
+	case failed > 0:
+		notifyEvent(run.notifier, notify.LocalRunFailed,
+			fmt.Sprintf("Local Terraform run failed on %d of %d accounts", failed, len(run.accounts)), fields)
+	case summary.count(changesStatus) > 0:
+		notifyEvent(run.notifier, notify.LocalRunSucceeded,
+			fmt.Sprintf("Local Terraform plan has changes on %d of %d accounts", summary.count(changesStatus), len(run.accounts)), fields)
+	default:
+		notifyEvent(run.notifier, notify.LocalRunSucceeded, "Local Terraform run completed", fields)
+	}
+
+	if code := summary.exitCode(detailedExitCode); code != 0 {
+		logging.Sync()
+		os.Exit(code)
+	}
+}
+
+// fanOut runs the Terraform command against the accounts, at most parallelism at a time, the output lines are
+// prefixed with the account
+func fanOut(run *localRun, parallelism int, streams terraformStreams) []*Result {
+	results := make([]*Result, len(run.accounts))
+	semaphore := make(chan struct{}, parallelism)
+	lock := &sync.Mutex{}
+
+	var wg sync.WaitGroup
+	for i, account := range run.accounts {
+		wg.Add(1)
+
+		go func(i int, account string) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			stdout := newPrefixWriter(streams.stdout, account, lock)
+			stderr := newPrefixWriter(streams.stderr, account, lock)
+
+			results[i] = run.runAccount(account, terraformStreams{
+				stdout:       stdout,
+				stderr:       stderr,
+				fromTerminal: streams.fromTerminal,
+			})
+
+			stdout.Flush()
+			stderr.Flush()
+		}(i, account)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// runAccount runs the Terraform command against the account in its own working copy of the repository
+func (r *localRun) runAccount(account string, streams terraformStreams) *Result {
+	result := &Result{
+		Account:  account,
+		Command:  strings.Join(args.terraformArgs, " "),
+		StateKey: r.stateKey(account),
+	}
+
+	start := time.Now()
+	err := r.runWorkingCopy(result, streams)
+	result.Duration = output.Since(start)
+	result.finish(err)
+
+	return result
+}
+
+// runWorkingCopy renders the templates of the account in a copy of the repository and runs Terraform there,
+// after terraform init as the copy has no Terraform working directory
+func (r *localRun) runWorkingCopy(result *Result, streams terraformStreams) error {
+	copyRoot, err := os.MkdirTemp("", "aftctl-"+result.Account+"-")
+	if err != nil {
+		return fmt.Errorf("error creating the working copy: %w", err)
+	}
+	defer os.RemoveAll(copyRoot)
+
+	if err := copyRepository(r.repo.Root, copyRoot); err != nil {
+		return fmt.Errorf("error creating the working copy: %w", err)
+	}
+
+	relative, err := filepath.Rel(r.repo.Root, r.dir)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(copyRoot, relative)
+
+	if err := processJinjaFiles(dir, result.Account, r.params, r.repo); err != nil {
+		return err
+	}
+
+	// no account can be prompted for input while the others run
+	env := append(append([]string{}, r.env...), "TF_INPUT=0")
+
+	if validate.TerraformSubcommand(args.terraformArgs) != "init" {
+		if err := runTerraform(dir, []string{"init", "-input=false"}, env, streams); err != nil {
+			return fmt.Errorf("terraform init failed: %w", err)
+		}
+	}
+
+	return r.withAPIHelpers(copyRoot, result.Account, streams, func() error {
+		return runTerraform(dir, args.terraformArgs, env, streams)
+	})
+}
+
+// copyRepository copies the repository into the directory, leaving out the git and Terraform directories
+func copyRepository(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() && path != src && copyExcludes[entry.Name()] {
+			return filepath.SkipDir
+		}
+
+		relative, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relative)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+
+		case entry.Type().IsRegular():
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, content, info.Mode().Perm())
+		}
+
+		return nil
+	})
+}
+
+// prefixWriter writes every line prefixed with the account, whole lines are written under the lock shared by
+// the accounts so that their lines don't mix
+type prefixWriter struct {
+	w      io.Writer
+	prefix []byte
+	lock   *sync.Mutex
+	buffer []byte
+}
+
+func newPrefixWriter(w io.Writer, account string, lock *sync.Mutex) *prefixWriter {
+	return &prefixWriter{
+		w:      w,
+		prefix: []byte("[" + account + "] "),
+		lock:   lock,
+	}
+}
+
+// Write writes the complete lines and keeps the last partial line until it's completed
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buffer = append(p.buffer, b...)
+
+	for {
+		i := bytes.IndexByte(p.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := p.writeLine(p.buffer[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buffer = p.buffer[i+1:]
+	}
+
+	return len(b), nil
+}
+
+// Flush writes the partial line left, e.g. when Terraform exits without a final newline
+func (p *prefixWriter) Flush() error {
+	if len(p.buffer) == 0 {
+		return nil
+	}
+
+	line := append(p.buffer, '\n')
+	p.buffer = nil
+
+	return p.writeLine(line)
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	_, err := p.w.Write(append(append([]byte{}, p.prefix...), line...))
+	return err
+}
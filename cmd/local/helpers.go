@@ -0,0 +1,147 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	profile "github.com/edgarsilva948/aftctl/pkg/aws/profiles"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+)
+
+// api_helpers layout of the AFT customization repositories
+const (
+	apiHelpersDir      = "api_helpers"
+	preAPIHelpers      = "pre-api-helpers.sh"
+	postAPIHelpers     = "post-api-helpers.sh"
+	helperRequirements = "python/requirements.txt"
+)
+
+// pythonBinary creates the virtual environment of the api_helpers python requirements
+var pythonBinary = "python3"
+
+// apiHelpersPath returns the api_helpers directory of the repository copied in root, the AFT pipeline only runs
+// the api_helpers of the global and account customizations
+func (r repository) apiHelpersPath(root string) string {
+	switch r.Type {
+	case globalCustomizationsRepository:
+		return filepath.Join(root, apiHelpersDir)
+	case accountCustomizationsRepository:
+		return filepath.Join(root, r.Customization, apiHelpersDir)
+	}
+
+	return ""
+}
+
+// withAPIHelpers runs the pre-api-helpers.sh script before terraform apply and the post-api-helpers.sh script after
+// it succeeds, like the AFT pipeline. The other commands, or --skip-helpers, only run Terraform.
+func (r *localRun) withAPIHelpers(root string, account string, streams terraformStreams, apply func() error) error {
+	dir := r.repo.apiHelpersPath(root)
+	if args.skipHelpers || dir == "" || validate.TerraformSubcommand(args.terraformArgs) != "apply" {
+		return apply()
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return apply()
+	}
+
+	env, cleanup, err := r.helpersEnv(root, dir, account, streams)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := runHelper(root, filepath.Join(dir, preAPIHelpers), env, streams); err != nil {
+		return err
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+
+	return runHelper(root, filepath.Join(dir, postAPIHelpers), env, streams)
+}
+
+// helpersEnv returns the environment the AFT pipeline gives to the api_helpers scripts, with the credentials of the
+// AFT execution role of the target account and the virtual environment of the python requirements. The returned
+// func removes the virtual environment.
+func (r *localRun) helpersEnv(root string, dir string, account string, streams terraformStreams) ([]string, func(), error) {
+	roleARN := profile.RoleARN(account, r.params[aftExecutionRoleName])
+
+	credentials, err := profile.AssumeRole(r.adminClient, roleARN, profile.Options{SessionName: r.sessionName})
+	if err != nil {
+		return nil, nil, fmt.Errorf("the AFT Admin role can't assume %s for the api_helpers: %v", roleARN, err)
+	}
+	logging.AddSecret(credentials.AccessKeyID, credentials.SecretAccessKey, credentials.SessionToken)
+
+	env := append(credentials.Env(),
+		"DEFAULT_PATH="+root,
+		"VENDED_ACCOUNT_ID="+account,
+		"CUSTOMIZATION="+r.repo.Customization,
+		"AWS_PARTITION=aws",
+		"AFT_MGMT_ACCOUNT="+r.params[aftMgmtAccountID],
+		"CT_MGMT_REGION="+r.params[ctMgmtRegion],
+		"AWS_REGION="+r.params[ctMgmtRegion],
+		"AWS_DEFAULT_REGION="+r.params[ctMgmtRegion],
+	)
+
+	requirements := filepath.Join(dir, helperRequirements)
+	if _, err := os.Stat(requirements); errors.Is(err, os.ErrNotExist) {
+		return env, func() {}, nil
+	}
+
+	venv, err := os.MkdirTemp("", "aftctl-api-helpers-venv-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating the api_helpers virtual environment: %v", err)
+	}
+	cleanup := func() { os.RemoveAll(venv) }
+
+	logging.Infow("installing the api_helpers python requirements", "requirements", requirements)
+	if err := runCommand(root, nil, streams, pythonBinary, "-m", "venv", venv); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error creating the api_helpers virtual environment: %v", err)
+	}
+	if err := runCommand(root, nil, streams, filepath.Join(venv, "bin", "pip"), "install", "-r", requirements); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error installing the api_helpers python requirements: %v", err)
+	}
+
+	env = append(env,
+		"VIRTUAL_ENV="+venv,
+		"PATH="+filepath.Join(venv, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"),
+	)
+
+	return env, cleanup, nil
+}
+
+// runHelper runs the api_helpers script from the repository root when it exists
+func runHelper(root string, script string, env []string, streams terraformStreams) error {
+	if _, err := os.Stat(script); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	logging.Infow("running the api_helpers script", "script", filepath.Base(script))
+	if err := runCommand(root, env, streams, "bash", script); err != nil {
+		return fmt.Errorf("%s failed: %v", filepath.Base(script), err)
+	}
+
+	return nil
+}
+
+// runCommand runs the command in the directory with the output streams of Terraform
+func runCommand(dir string, env []string, streams terraformStreams, name string, arg ...string) error {
+	command := exec.Command(name, arg...)
+	command.Dir = dir
+	command.Env = append(os.Environ(), env...)
+	command.Stdout = streams.stdout
+	command.Stderr = streams.stderr
+
+	return command.Run()
+}
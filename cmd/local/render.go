@@ -0,0 +1,372 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edgarsilva948/aftctl/pkg/config"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/output"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+	"github.com/flosch/pongo2"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var renderArgs struct {
+	targetAccount string
+	customization string
+	stdout        bool
+	vars          map[string]string
+	varFile       string
+	ssmCacheTTL   time.Duration
+	refresh       bool
+}
+
+func init() {
+	flags := renderCmd.Flags()
+	flags.SortFlags = false
+
+	flags.StringVarP(
+		&renderArgs.targetAccount,
+		"target-account",
+		"a",
+		"",
+		"Account ID the templates are rendered for",
+	)
+	renderCmd.MarkFlagRequired("target-account")
+
+	flags.StringVar(
+		&renderArgs.customization,
+		"customization",
+		"",
+		"Folder of the account customization, detected from the current directory by default",
+	)
+
+	flags.BoolVar(
+		&renderArgs.stdout,
+		"stdout",
+		false,
+		"Print the rendered files instead of writing them",
+	)
+
+	flags.StringToStringVar(
+		&renderArgs.vars,
+		"var",
+		nil,
+		"Template variable to add or override (key=value)",
+	)
+
+	flags.StringVar(
+		&renderArgs.varFile,
+		"var-file",
+		"",
+		"YAML file of template variables to add or override, --var takes precedence",
+	)
+
+	flags.DurationVar(
+		&renderArgs.ssmCacheTTL,
+		"ssm-cache-ttl",
+		time.Hour,
+		"How long the SSM parameters of the AFT deployment are cached, 0 disables the cache",
+	)
+
+	flags.BoolVar(
+		&renderArgs.refresh,
+		"refresh",
+		false,
+		"Look up the SSM parameters again instead of using the cache",
+	)
+
+	Cmd.AddCommand(renderCmd)
+}
+
+// renderCmd renders the jinja templates of AFT without running Terraform.
+var renderCmd = &cobra.Command{
+	Use:   "render -a ACCOUNT",
+	Short: "Renders the AFT jinja templates without running Terraform",
+	Long: "Renders the *.jinja templates of the current directory, e.g. backend.jinja and providers.jinja, " +
+		"into the .tf files the AFT pipeline generates for the target account.\n" +
+		"The rendering fails on template syntax errors and on undefined variables.",
+	Example: `  aftctl local render -a 111111111111
+
+  aftctl local render -a 111111111111 --stdout
+
+  aftctl local render -a 111111111111 --var region=eu-west-1 --var-file vars.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runRender,
+}
+
+// RenderResult is the outcome of the render command.
+type RenderResult struct {
+	Context string   `json:"context,omitempty" yaml:"context,omitempty"`
+	Account string   `json:"account" yaml:"account"`
+	Files   []string `json:"files" yaml:"files"`
+}
+
+// PrintTable writes a line per rendered file.
+func (r *RenderResult) PrintTable(w io.Writer) error {
+	rows := make([][]string, 0, len(r.Files))
+	for _, file := range r.Files {
+		rows = append(rows, []string{r.Account, file})
+	}
+
+	return output.Table(w, []string{"ACCOUNT", "FILE"}, rows)
+}
+
+// renderedFile is a jinja template rendered for the target account
+type renderedFile struct {
+	Path    string
+	Content string
+}
+
+func runRender(cmd *cobra.Command, argv []string) error {
+	if err := output.CheckFormat(); err != nil {
+		return err
+	}
+
+	if _, err := validate.CheckAWSAccountID(renderArgs.targetAccount); err != nil {
+		return fmt.Errorf("invalid AWS Account ID: %w", err)
+	}
+
+	vars, err := readVarFile(renderArgs.varFile)
+	if err != nil {
+		return err
+	}
+	for key, value := range renderArgs.vars {
+		vars[key] = value
+	}
+
+	contextName, aftContext, err := config.Active()
+	if err != nil {
+		return fmt.Errorf("error loading the aftctl context: %v", err)
+	}
+
+	awsClient, ssmClient, err := initializeAWSandSSMClients(contextClientOptions(aftContext))
+	if err != nil {
+		return fmt.Errorf("error initializing AWS and SSM Clients: %v", err)
+	}
+
+	if err := checkContextAccount(ssmClient, aftContext); err != nil {
+		return err
+	}
+
+	cacheName, ttl := ssmCache(awsClient.GetSTSClient(), awsClient.GetRegion(), cacheTTL(renderArgs.ssmCacheTTL, renderArgs.refresh))
+	aftParams, err := loadSSMParameters(ssmClient, cacheName, ttl, aftContext.SSMParameters)
+	if err != nil {
+		return err
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current directory: %v", err)
+	}
+
+	repo, err := detectRepository(pwd, renderArgs.customization, func() map[repositoryType]string {
+		return getRepositoryNames(aftParams)
+	})
+	if err != nil {
+		return err
+	}
+
+	params, err := getSSMParameters(aftParams, ssmKeys)
+	if err != nil {
+		return err
+	}
+
+	if err := terraformCloudParameters(aftParams, params); err != nil {
+		return err
+	}
+
+	context := templateContext(renderArgs.targetAccount, params, repo)
+	for key, value := range vars {
+		context[key] = value
+	}
+
+	files, err := renderTemplates(pwd, context)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%s has no *.jinja template", pwd)
+	}
+
+	if renderArgs.stdout {
+		return printRenderedFiles(cmd.OutOrStdout(), files)
+	}
+
+	if err := writeRenderedFiles(files); err != nil {
+		return err
+	}
+
+	result := &RenderResult{Context: contextName, Account: renderArgs.targetAccount}
+	for _, file := range files {
+		result.Files = append(result.Files, filepath.Base(file.Path))
+	}
+
+	return output.Print(cmd.OutOrStdout(), result)
+}
+
+// templateContext returns the variables the AFT pipeline gives to the jinja templates for the target account, the
+// S3 backend of the oss distribution or the Terraform Cloud workspace of the tfc and tfe distributions
+func templateContext(targetAccount string, params map[string]string, repo repository) pongo2.Context {
+	partition := "aws"
+
+	return pongo2.Context{
+		"timestamp":                time.Now().Format(time.RFC3339),
+		"tf_distribution_type":     params[tfDistribution],
+		"provider_region":          params[ctMgmtRegion],
+		"region":                   params[tfBackendRegion],
+		"aft_admin_role_arn":       fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, params[aftMgmtAccountID], params[aftExecutionRoleName]),
+		"target_admin_role_arn":    fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, targetAccount, params[aftExecutionRoleName]),
+		"bucket":                   params[tfS3BucketID],
+		"key":                      repo.stateKey(targetAccount),
+		"dynamodb_table":           params[tfDynamoDBTableName],
+		"kms_key_id":               params[tfKmsKeyID],
+		"terraform_org_name":       params[tfOrgName],
+		"terraform_workspace_name": repo.workspaceName(targetAccount),
+		"terraform_api_endpoint":   params[tfAPIEndpoint],
+	}
+}
+
+// renderTemplates renders the jinja templates of the directory, a syntax error or an undefined variable fails the
+// rendering of every template
+func renderTemplates(dir string, context pongo2.Context) ([]renderedFile, error) {
+	templates, err := filepath.Glob(filepath.Join(dir, "*.jinja"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading jinja files: %v", err)
+	}
+
+	files := make([]renderedFile, 0, len(templates))
+	for _, path := range templates {
+		input, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", path, err)
+		}
+
+		template, err := pongo2.FromString(string(input))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", path, err)
+		}
+
+		content, undefined, err := executeTemplate(template, string(input), context)
+		if err != nil {
+			return nil, fmt.Errorf("error rendering %s: %v", path, err)
+		}
+
+		if len(undefined) > 0 {
+			return nil, fmt.Errorf("%s uses undefined variables %s", path, strings.Join(undefined, ", "))
+		}
+
+		files = append(files, renderedFile{
+			Path:    strings.TrimSuffix(path, ".jinja") + ".tf",
+			Content: content,
+		})
+	}
+
+	return files, nil
+}
+
+// writeRenderedFiles writes the rendered files next to their templates
+func writeRenderedFiles(files []renderedFile) error {
+	for _, file := range files {
+		if err := os.WriteFile(file.Path, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %v", file.Path, err)
+		}
+		logging.Debugf("rendered %s", file.Path)
+	}
+
+	return nil
+}
+
+// printRenderedFiles writes the rendered files, each one after a comment naming it
+func printRenderedFiles(w io.Writer, files []renderedFile) error {
+	for _, file := range files {
+		if _, err := fmt.Fprintf(w, "# %s\n%s\n", filepath.Base(file.Path), strings.TrimRight(file.Content, "\n")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readVarFile reads the YAML map of template variables, no file has no variable
+func readVarFile(path string) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	if path == "" {
+		return vars, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the variables file: %v", err)
+	}
+
+	if err := yaml.Unmarshal(content, &vars); err != nil {
+		return nil, fmt.Errorf("error reading the variables file %s: %v", path, err)
+	}
+	if vars == nil {
+		return nil, errors.New("the variables file " + path + " must hold a map of variables")
+	}
+
+	return vars, nil
+}
+
+var (
+	// templateTags matches the {{ variable }} and {% tag %} of a template
+	templateTags = regexp.MustCompile(`(?s)\{\{.*?\}\}|\{%.*?%\}`)
+
+	// templateNames matches the names of a tag
+	templateNames = regexp.MustCompile(`\b[A-Za-z_]\w*`)
+)
+
+// executeTemplate renders the template and returns the variables it reads which are missing from the context,
+// pongo2 renders them as empty strings which would give an invalid backend. The names of the tags missing from the
+// context are given a func recording the read, pongo2 calls it when it resolves the variable and renders its nil
+// value like an undefined variable. The names declared by the template itself shadow the context and aren't read.
+func executeTemplate(template *pongo2.Template, source string, context pongo2.Context) (string, []string, error) {
+	read := map[string]bool{}
+
+	recorded := make(pongo2.Context, len(context))
+	for key, value := range context {
+		recorded[key] = value
+	}
+
+	for _, tag := range templateTags.FindAllString(source, -1) {
+		for _, name := range templateNames.FindAllString(tag, -1) {
+			if _, ok := recorded[name]; ok {
+				continue
+			}
+
+			name := name
+			recorded[name] = func(...*pongo2.Value) *pongo2.Value {
+				read[name] = true
+				return pongo2.AsValue(nil)
+			}
+		}
+	}
+
+	content, err := template.Execute(recorded)
+	if err != nil {
+		return "", nil, err
+	}
+
+	undefined := make([]string, 0, len(read))
+	for name := range read {
+		undefined = append(undefined, name)
+	}
+	sort.Strings(undefined)
+
+	return content, undefined, nil
+}
@@ -0,0 +1,243 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"gopkg.in/ini.v1"
+)
+
+// repositoryType is one of the AFT repositories, named after its default repository name
+type repositoryType string
+
+// AFT repository types
+const (
+	accountRequestRepository                    repositoryType = "aft-account-request"
+	globalCustomizationsRepository              repositoryType = "aft-global-customizations"
+	accountCustomizationsRepository             repositoryType = "aft-account-customizations"
+	accountProvisioningCustomizationsRepository repositoryType = "aft-account-provisioning-customizations"
+)
+
+// repositoryTypes lists the AFT repository types in the order they are matched
+var repositoryTypes = []repositoryType{
+	accountRequestRepository,
+	globalCustomizationsRepository,
+	accountCustomizationsRepository,
+	accountProvisioningCustomizationsRepository,
+}
+
+// repositoryNameParameters are the SSM parameters holding the repository names configured in AFT
+var repositoryNameParameters = map[repositoryType]string{
+	accountRequestRepository:                    "/aft/config/account-request/repo-name",
+	globalCustomizationsRepository:              "/aft/config/global-customizations/repo-name",
+	accountCustomizationsRepository:             "/aft/config/account-customizations/repo-name",
+	accountProvisioningCustomizationsRepository: "/aft/config/account-provisioning-customizations/repo-name",
+}
+
+// markerFile holds the repository type, it takes precedence over the git remote
+const markerFile = ".aftctl-repository"
+
+// repository is the AFT repository aftctl local runs from
+type repository struct {
+	Type repositoryType
+
+	// Root is the top directory of the repository.
+	Root string
+
+	// Customization is the folder of the account customization, e.g. sandbox in sandbox/terraform.
+	Customization string
+}
+
+// stateKey returns the S3 key of the Terraform state used by the AFT pipeline
+func (r repository) stateKey(targetAccount string) string {
+	if r.Type == accountCustomizationsRepository {
+		return fmt.Sprintf("%s-%s/%s/terraform.tfstate", targetAccount, r.Type, r.Customization)
+	}
+
+	return fmt.Sprintf("%s-%s/terraform.tfstate", targetAccount, r.Type)
+}
+
+// detectRepository finds the repository holding the directory and its type, from the marker file or else from the
+// git remote matched against the repository names, which are only resolved when needed
+func detectRepository(dir string, customization string, repositoryNames func() map[repositoryType]string) (repository, error) {
+	root, err := findRepositoryRoot(dir)
+	if err != nil {
+		return repository{}, err
+	}
+
+	repo := repository{Root: root}
+
+	repo.Type, err = readMarkerFile(root)
+	if err != nil {
+		return repository{}, err
+	}
+
+	if repo.Type == "" {
+		remote, err := readRemoteURL(root)
+		if err != nil {
+			return repository{}, err
+		}
+
+		repo.Type, err = matchRemote(remote, repositoryNames())
+		if err != nil {
+			return repository{}, err
+		}
+	}
+
+	if repo.Type == accountCustomizationsRepository {
+		repo.Customization = customization
+		if repo.Customization == "" {
+			repo.Customization = customizationFolder(root, dir)
+		}
+
+		if repo.Customization == "" {
+			return repository{}, errors.New("run aftctl local from a customization folder of the account customizations " +
+				"repository, e.g. sandbox/terraform, or give the folder with --customization")
+		}
+	}
+
+	logging.Infow("detected AFT repository", "type", repo.Type, "customization", repo.Customization)
+
+	return repo, nil
+}
+
+// findRepositoryRoot returns the closest parent directory holding the marker file or the git directory
+func findRepositoryRoot(dir string) (string, error) {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		for _, name := range []string{markerFile, ".git"} {
+			if _, err := os.Stat(filepath.Join(current, name)); err == nil {
+				return current, nil
+			}
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("%s is not in a git repository, run aftctl local from a clone of an AFT repository "+
+				"or add a %s file naming its type", dir, markerFile)
+		}
+		current = parent
+	}
+}
+
+// readMarkerFile returns the repository type of the marker file, or an empty type without marker file
+func readMarkerFile(root string) (repositoryType, error) {
+	content, err := os.ReadFile(filepath.Join(root, markerFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", markerFile, err)
+	}
+
+	value := repositoryType(strings.TrimSpace(string(content)))
+	for _, t := range repositoryTypes {
+		if value == t {
+			return t, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s holds %q, accepted repository types are %s", markerFile, value, repositoryTypes)
+}
+
+// readRemoteURL returns the URL of the origin remote, or of the only remote
+func readRemoteURL(root string) (string, error) {
+	cfg, err := ini.Load(filepath.Join(root, ".git", "config"))
+	if err != nil {
+		return "", fmt.Errorf("error reading the git config of %s: %w", root, err)
+	}
+
+	var urls []string
+	for _, section := range cfg.Sections() {
+		if section.Name() == `remote "origin"` {
+			return section.Key("url").String(), nil
+		}
+		if strings.HasPrefix(section.Name(), "remote ") {
+			urls = append(urls, section.Key("url").String())
+		}
+	}
+
+	if len(urls) != 1 {
+		return "", fmt.Errorf("%s has no origin remote, add a %s file naming the repository type", root, markerFile)
+	}
+
+	return urls[0], nil
+}
+
+// remoteRepositoryName returns the repository name of a git remote URL, e.g. codecommit::us-east-1://profile@name,
+// https://git-codecommit.us-east-1.amazonaws.com/v1/repos/name or git@github.com:org/name.git
+func remoteRepositoryName(url string) string {
+	name := strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
+
+// matchRemote returns the type of the repository named in the remote URL
+func matchRemote(url string, repositoryNames map[repositoryType]string) (repositoryType, error) {
+	name := remoteRepositoryName(url)
+
+	for _, t := range repositoryTypes {
+		configured := repositoryNames[t]
+		if configured == "" {
+			configured = string(t)
+		}
+
+		if strings.EqualFold(name, configured) {
+			return t, nil
+		}
+	}
+
+	return "", fmt.Errorf("the git remote %s doesn't match an AFT repository, add a %s file naming its type, "+
+		"accepted repository types are %s", url, markerFile, repositoryTypes)
+}
+
+// customizationFolder returns the first folder of the directory inside the repository
+func customizationFolder(root string, dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	relative, err := filepath.Rel(root, absDir)
+	if err != nil || relative == "." || strings.HasPrefix(relative, "..") {
+		return ""
+	}
+
+	return strings.Split(filepath.ToSlash(relative), "/")[0]
+}
+
+// getRepositoryNames returns the repository names configured in AFT, the default names are kept when a parameter
+// is missing, e.g. on older AFT versions
+func getRepositoryNames(aftParams map[string]string) map[repositoryType]string {
+	names := map[repositoryType]string{}
+
+	for t, key := range repositoryNameParameters {
+		value, ok := aftParams[key]
+		if !ok {
+			logging.Debugf("using the default name of the %s repository", t)
+			continue
+		}
+		names[t] = value
+	}
+
+	return names
+}
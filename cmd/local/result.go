@@ -0,0 +1,112 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/edgarsilva948/aftctl/pkg/output"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+)
+
+// statuses of a Terraform run
+const (
+	succeededStatus = "succeeded"
+	noChangesStatus = "no changes"
+	changesStatus   = "changes"
+	errorStatus     = "error"
+)
+
+// Result is the outcome of the local command.
+type Result struct {
+	Context  string          `json:"context,omitempty" yaml:"context,omitempty"`
+	Account  string          `json:"account" yaml:"account"`
+	Command  string          `json:"command" yaml:"command"`
+	StateKey string          `json:"stateKey,omitempty" yaml:"stateKey,omitempty"`
+	Status   string          `json:"status" yaml:"status"`
+	ExitCode int             `json:"exitCode" yaml:"exitCode"`
+	Duration output.Duration `json:"duration" yaml:"duration"`
+	Error    string          `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// finish records the exit code and the status of the run
+func (r *Result) finish(err error) {
+	var exitErr *terraformExitError
+	if errors.As(err, &exitErr) {
+		r.ExitCode = exitErr.code
+	}
+
+	switch {
+	case isPlanWithChanges(err):
+		r.Status = changesStatus
+	case err != nil:
+		r.Status = errorStatus
+		r.Error = err.Error()
+	case validate.TerraformSubcommand(args.terraformArgs) == "plan" && hasDetailedExitCode(args.terraformArgs):
+		r.Status = noChangesStatus
+	default:
+		r.Status = succeededStatus
+	}
+}
+
+// PrintTable writes the outcome of the run, the Terraform output is streamed while it runs.
+func (r *Result) PrintTable(w io.Writer) error {
+	return output.Table(w,
+		[]string{"ACCOUNT", "COMMAND", "STATE KEY", "STATUS", "EXIT CODE", "DURATION"},
+		[][]string{{r.Account, r.Command, r.StateKey, r.Status, strconv.Itoa(r.ExitCode), r.Duration.String()}},
+	)
+}
+
+// Summary is the outcome of the local command run against several accounts.
+type Summary struct {
+	Context  string          `json:"context,omitempty" yaml:"context,omitempty"`
+	Command  string          `json:"command" yaml:"command"`
+	Results  []*Result       `json:"results" yaml:"results"`
+	Duration output.Duration `json:"duration" yaml:"duration"`
+}
+
+// count returns the number of accounts with the status
+func (s *Summary) count(status string) int {
+	count := 0
+	for _, result := range s.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+// exitCode is 1 when a run failed, else 2 when a plan has changes and -detailed-exitcode was asked for
+func (s *Summary) exitCode(detailedExitCode bool) int {
+	switch {
+	case s.count(errorStatus) > 0:
+		return 1
+	case s.count(changesStatus) > 0 && detailedExitCode:
+		return 2
+	}
+
+	return 0
+}
+
+// PrintTable writes a line per account followed by the totals, the Terraform output is streamed while it runs.
+func (s *Summary) PrintTable(w io.Writer) error {
+	rows := make([][]string, 0, len(s.Results))
+	for _, r := range s.Results {
+		rows = append(rows, []string{r.Account, r.StateKey, r.Status, strconv.Itoa(r.ExitCode), r.Duration.String(), r.Error})
+	}
+
+	if err := output.Table(w, []string{"ACCOUNT", "STATE KEY", "STATUS", "EXIT CODE", "DURATION", "ERROR"}, rows); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d accounts in %s: %d no changes, %d changes, %d succeeded, %d error\n",
+		len(s.Results), s.Duration, s.count(noChangesStatus), s.count(changesStatus), s.count(succeededStatus), s.count(errorStatus))
+
+	return err
+}
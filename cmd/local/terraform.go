@@ -0,0 +1,129 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// terraformBinary is the Terraform executable run by aftctl local
+var terraformBinary = "terraform"
+
+// forwardedSignals stop Terraform gracefully instead of killing aftctl alone
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// terraformExitError is returned when Terraform exits with a non-zero code
+type terraformExitError struct {
+	code int
+}
+
+func (e *terraformExitError) Error() string {
+	return fmt.Sprintf("terraform exited with code %d", e.code)
+}
+
+// terraformStreams are the standard streams of the Terraform process
+type terraformStreams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	// fromTerminal is set when stdin is a terminal, the terminal then interrupts Terraform by itself
+	fromTerminal bool
+}
+
+// standardStreams returns the streams of aftctl, the Terraform output goes to stderr when stdout holds the result
+func standardStreams(resultOnStdout bool) terraformStreams {
+	streams := terraformStreams{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	if resultOnStdout {
+		streams.stdout = os.Stderr
+	}
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		streams.fromTerminal = true
+	}
+
+	return streams
+}
+
+// teeStreams also writes the Terraform output to the log file, the returned func closes it
+func teeStreams(streams terraformStreams, logFile string) (terraformStreams, func(), error) {
+	if logFile == "" {
+		return streams, func() {}, nil
+	}
+
+	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return streams, nil, fmt.Errorf("error opening the terraform log file: %w", err)
+	}
+
+	streams.stdout = io.MultiWriter(streams.stdout, file)
+	streams.stderr = io.MultiWriter(streams.stderr, file)
+
+	return streams, func() { file.Close() }, nil
+}
+
+// runTerraform runs Terraform in the directory with the given streams, forwarding the signals received by aftctl
+// until it exits
+func runTerraform(dir string, terraformArgs []string, env []string, streams terraformStreams) error {
+	terraformCmd := exec.Command(terraformBinary, terraformArgs...)
+	terraformCmd.Dir = dir
+	terraformCmd.Env = append(os.Environ(), env...)
+	terraformCmd.Stdin = streams.stdin
+	terraformCmd.Stdout = streams.stdout
+	terraformCmd.Stderr = streams.stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := terraformCmd.Start(); err != nil {
+		return fmt.Errorf("error starting terraform: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				// a second interrupt makes Terraform exit immediately, skip the one the terminal already sent
+				if sig == os.Interrupt && streams.fromTerminal {
+					continue
+				}
+				_ = terraformCmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := terraformCmd.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+
+		// like the shells, a Terraform killed by a signal exits with 128 + the signal number
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			code = 128 + int(status.Signal())
+		}
+
+		return &terraformExitError{code: code}
+	}
+
+	return err
+}
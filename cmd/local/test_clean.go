@@ -0,0 +1,125 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/edgarsilva948/aftctl/pkg/gitignore"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Cleaning the generated files", func() {
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		dir = ginkgo.GinkgoT().TempDir()
+
+		for _, name := range []string{"backend.jinja", "backend.tf", "main.tf", "plan.tfplan", ".terraform/modules/modules.json"} {
+			path := filepath.Join(dir, name)
+			gomega.Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(gomega.Succeed())
+			gomega.Expect(os.WriteFile(path, []byte("x"), 0600)).To(gomega.Succeed())
+		}
+		gomega.Expect(os.WriteFile(filepath.Join(dir, gitignore.FileName), []byte("*.zip\n"), 0600)).To(gomega.Succeed())
+	})
+
+	ginkgo.Context("testing the generatedPatterns function", func() {
+		ginkgo.It("should ignore the rendered templates, the terraform directory and the plans", func() {
+			gomega.Expect(generatedPatterns(dir)).To(gomega.Equal(
+				[]string{"backend.tf", ".terraform*", "*.tfplan", "tfplan"}))
+		})
+	})
+
+	ginkgo.Context("testing the clean function", func() {
+		ginkgo.BeforeEach(func() {
+			_, err := gitignore.Merge(dir, generatedPatterns(dir))
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		})
+
+		ginkgo.When("it runs with dry run", func() {
+			ginkgo.It("should only list the generated files", func() {
+				result, err := clean(dir, true)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(result.Paths).To(gomega.Equal([]CleanedPath{
+					{Path: ".terraform", Status: wouldRemoveStatus},
+					{Path: "backend.tf", Status: wouldRemoveStatus},
+					{Path: "plan.tfplan", Status: wouldRemoveStatus},
+					{Path: gitignoreBlockPath, Status: wouldRemoveStatus},
+				}))
+				gomega.Expect(filepath.Join(dir, "backend.tf")).To(gomega.BeAnExistingFile())
+
+				found, err := gitignore.HasBlock(dir)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(found).To(gomega.BeTrue())
+			})
+		})
+
+		ginkgo.When("it runs", func() {
+			ginkgo.It("should remove the generated files and keep the others", func() {
+				result, err := clean(dir, false)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(result.Paths).To(gomega.HaveLen(4))
+
+				for _, name := range []string{".terraform", "backend.tf", "plan.tfplan"} {
+					gomega.Expect(filepath.Join(dir, name)).ToNot(gomega.BeAnExistingFile())
+				}
+				gomega.Expect(filepath.Join(dir, "main.tf")).To(gomega.BeAnExistingFile())
+				gomega.Expect(filepath.Join(dir, "backend.jinja")).To(gomega.BeAnExistingFile())
+
+				content, err := os.ReadFile(filepath.Join(dir, gitignore.FileName))
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(string(content)).To(gomega.Equal("*.zip\n"))
+
+				buffer := &bytes.Buffer{}
+				again, err := clean(dir, false)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(again.PrintTable(buffer)).To(gomega.Succeed())
+				gomega.Expect(buffer.String()).To(gomega.HavePrefix("No file generated by aftctl"))
+			})
+		})
+
+		ginkgo.When("a generated file is tracked by git", func() {
+			ginkgo.It("should keep it", func() {
+				if _, err := exec.LookPath("git"); err != nil {
+					ginkgo.Skip("git isn't installed")
+				}
+
+				gomega.Expect(exec.Command("git", "-C", dir, "init", "-q").Run()).To(gomega.Succeed())
+				gomega.Expect(exec.Command("git", "-C", dir, "add", "-f", "backend.tf").Run()).To(gomega.Succeed())
+
+				result, err := clean(dir, false)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(result.Paths).To(gomega.ContainElement(CleanedPath{Path: "backend.tf", Status: trackedStatus}))
+				gomega.Expect(filepath.Join(dir, "backend.tf")).To(gomega.BeAnExistingFile())
+			})
+		})
+
+		ginkgo.When("the .gitignore file is tracked by git", func() {
+			ginkgo.It("should keep it once the block is removed", func() {
+				if _, err := exec.LookPath("git"); err != nil {
+					ginkgo.Skip("git isn't installed")
+				}
+
+				path := filepath.Join(dir, gitignore.FileName)
+				gomega.Expect(os.WriteFile(path, nil, 0600)).To(gomega.Succeed())
+				gomega.Expect(exec.Command("git", "-C", dir, "init", "-q").Run()).To(gomega.Succeed())
+				gomega.Expect(exec.Command("git", "-C", dir, "add", gitignore.FileName).Run()).To(gomega.Succeed())
+				_, err := gitignore.Merge(dir, generatedPatterns(dir))
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				_, err = clean(dir, false)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				content, err := os.ReadFile(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(content).To(gomega.BeEmpty())
+			})
+		})
+	})
+})
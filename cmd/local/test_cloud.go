@@ -0,0 +1,95 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Running against Terraform Cloud", func() {
+	sandbox := repository{Type: accountCustomizationsRepository, Customization: "sandbox"}
+
+	ginkgo.Context("testing the workspace names", func() {
+		ginkgo.It("should name the workspaces like the AFT pipeline", func() {
+			gomega.Expect(sandbox.workspaceName("111111111111")).To(gomega.Equal("111111111111-aft-account-customizations-sandbox"))
+			gomega.Expect(repository{Type: globalCustomizationsRepository}.workspaceName("111111111111")).
+				To(gomega.Equal("111111111111-aft-global-customizations"))
+			gomega.Expect(repository{Type: accountRequestRepository}.workspaceName("111111111111")).
+				To(gomega.Equal("ct-aft-account-request"))
+
+			run := &localRun{repo: sandbox, params: map[string]string{tfDistribution: "tfe"}}
+			gomega.Expect(run.stateKey("111111111111")).To(gomega.Equal("111111111111-aft-account-customizations-sandbox"))
+
+			run.params[tfDistribution] = "oss"
+			gomega.Expect(run.stateKey("111111111111")).To(gomega.Equal("111111111111-aft-account-customizations/sandbox/terraform.tfstate"))
+		})
+	})
+
+	ginkgo.Context("testing the Terraform Cloud settings", func() {
+		ginkgo.When("the distribution is tfc", func() {
+			ginkgo.It("should render the workspace and give the token to Terraform", func() {
+				aftParams := map[string]string{tfOrgName: "acme", tfAPIEndpoint: "https://app.terraform.io/api/v2/"}
+				params := map[string]string{tfDistribution: "tfc"}
+				gomega.Expect(terraformCloudParameters(aftParams, params)).To(gomega.Succeed())
+
+				dir := ginkgo.GinkgoT().TempDir()
+				gomega.Expect(os.WriteFile(filepath.Join(dir, "backend.jinja"), []byte(
+					`{% if tf_distribution_type == "oss" %}key = "{{ key }}"{% else %}`+
+						`organization = "{{ terraform_org_name }}" name = "{{ terraform_workspace_name }}"{% endif %}`), 0600)).To(gomega.Succeed())
+
+				files, err := renderTemplates(dir, templateContext("111111111111", params, sandbox))
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(files[0].Content).To(gomega.Equal(`organization = "acme" name = "111111111111-aft-account-customizations-sandbox"`))
+
+				mockClient := &MockSSMClient{
+					GetParameterFunc: func(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+						gomega.Expect(aws.StringValue(input.Name)).To(gomega.Equal(tfToken))
+						gomega.Expect(aws.BoolValue(input.WithDecryption)).To(gomega.BeTrue())
+						return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String("xyz.atlasv1.abc")}}, nil
+					},
+				}
+
+				env, err := terraformCloudEnv(mockClient, params)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(env).To(gomega.Equal([]string{"TF_TOKEN_app_terraform_io=xyz.atlasv1.abc"}))
+			})
+		})
+
+		ginkgo.When("the distribution is oss", func() {
+			ginkgo.It("should not need the Terraform Cloud parameters", func() {
+				params := map[string]string{tfDistribution: "oss"}
+				gomega.Expect(terraformCloudParameters(map[string]string{}, params)).To(gomega.Succeed())
+
+				env, err := terraformCloudEnv(nil, params)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(env).To(gomega.BeEmpty())
+			})
+		})
+
+		ginkgo.When("the Terraform Cloud parameters are missing", func() {
+			ginkgo.It("should return an error", func() {
+				err := terraformCloudParameters(map[string]string{}, map[string]string{tfDistribution: "tfe"})
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(tfOrgName + ", " + tfAPIEndpoint)))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the token variable", func() {
+		ginkgo.It("should follow the Terraform naming of the hostname", func() {
+			hostname, err := apiHostname("tfe.my-company.example.com")
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(tokenEnvVar(hostname)).To(gomega.Equal("TF_TOKEN_tfe_my__company_example_com"))
+
+			_, err = apiHostname("https:///api/v2/")
+			gomega.Expect(err).To(gomega.HaveOccurred())
+		})
+	})
+})
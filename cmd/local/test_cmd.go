@@ -8,9 +8,13 @@ package local
 import (
 	"errors"
 	"os"
+	"strings"
+	"time"
 
 	awsAft "github.com/edgarsilva948/aftctl/pkg/aws"
 	profile "github.com/edgarsilva948/aftctl/pkg/aws/profiles"
+	"github.com/edgarsilva948/aftctl/pkg/cache"
+	"github.com/edgarsilva948/aftctl/pkg/config"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -20,18 +24,21 @@ import (
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	ginkgo "github.com/onsi/ginkgo/v2"
 	gomega "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
 )
 
 // MockSTSClient is a mock implementation of an STS client for testing.
 type MockSTSClient struct {
 	stsiface.STSAPI
-	AssumeRoleFunc func(*sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error)
+	AssumeRoleFunc        func(*sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error)
+	GetCallerIdentityFunc func(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
 }
 
 // MockSSMClient is a mock implementation of an SSM client for testing.
 type MockSSMClient struct {
 	ssmiface.SSMAPI
-	GetParameterFunc func(*ssm.GetParameterInput) (*ssm.GetParameterOutput, error)
+	GetParameterFunc             func(*ssm.GetParameterInput) (*ssm.GetParameterOutput, error)
+	GetParametersByPathPagesFunc func(*ssm.GetParametersByPathInput, func(*ssm.GetParametersByPathOutput, bool) bool) error
 }
 
 // GetParameter is a mock implementation of the GetParameter method.
@@ -39,11 +46,21 @@ func (m *MockSSMClient) GetParameter(input *ssm.GetParameterInput) (*ssm.GetPara
 	return m.GetParameterFunc(input)
 }
 
+// GetParametersByPathPages is a mock implementation of the GetParametersByPathPages method.
+func (m *MockSSMClient) GetParametersByPathPages(input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool) error {
+	return m.GetParametersByPathPagesFunc(input, fn)
+}
+
 // AssumeRole is a mock implementation of the AssumeRole method.
 func (m *MockSTSClient) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
 	return m.AssumeRoleFunc(input)
 }
 
+// GetCallerIdentity is a mock implementation of the GetCallerIdentity method.
+func (m *MockSTSClient) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return m.GetCallerIdentityFunc(input)
+}
+
 var _ = ginkgo.Describe("Interacting with the SSM API", func() {
 	var (
 		originalWd string
@@ -121,7 +138,7 @@ var _ = ginkgo.Describe("Interacting with the SSM API", func() {
 
 	})
 
-	ginkgo.Context("when setting up the profile", func() {
+	ginkgo.Context("when assuming the AFT Admin role", func() {
 		var mockSTSClient *MockSTSClient
 		var aftMgmtAccountIDParam string
 		var aftAdminRoleNameParam string
@@ -150,8 +167,13 @@ var _ = ginkgo.Describe("Interacting with the SSM API", func() {
 						}, nil
 					},
 				}
-				err := profile.SetupProfile(mockSTSClient, aftMgmtAccountIDParam, aftAdminRoleNameParam, "AWSAFT-Session")
+				credentials, err := profile.AssumeRole(mockSTSClient, profile.RoleARN(aftMgmtAccountIDParam, aftAdminRoleNameParam), profile.Options{SessionName: sessionNamePrefix})
 				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				gomega.Expect(credentials.Env()).To(gomega.Equal([]string{
+					"AWS_ACCESS_KEY_ID=some-access-key-id",
+					"AWS_SECRET_ACCESS_KEY=some-secret-access-key",
+					"AWS_SESSION_TOKEN=some-session-token",
+				}))
 			})
 		})
 
@@ -163,11 +185,231 @@ var _ = ginkgo.Describe("Interacting with the SSM API", func() {
 			})
 
 			ginkgo.It("should handle the error", func() {
-				err := profile.SetupProfile(mockSTSClient, aftMgmtAccountIDParam, aftAdminRoleNameParam, "AWSAFT-Session")
+				_, err := profile.AssumeRole(mockSTSClient, profile.RoleARN(aftMgmtAccountIDParam, aftAdminRoleNameParam), profile.Options{SessionName: sessionNamePrefix})
 				gomega.Expect(err).To(gomega.HaveOccurred())
 				gomega.Expect(err.Error()).To(gomega.Equal("some error"))
 			})
 		})
 	})
 
+	ginkgo.Context("testing the context of the local execution", func() {
+
+		var mockClient *MockSSMClient
+
+		ginkgo.BeforeEach(func() {
+			mockClient = &MockSSMClient{
+				GetParameterFunc: func(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+					values := map[string]string{
+						aftMgmtAccountID: "111111111111",
+						tfDistribution:   "oss",
+					}
+					return &ssm.GetParameterOutput{
+						Parameter: &ssm.Parameter{Value: aws.String(values[aws.StringValue(input.Name)])},
+					}, nil
+				},
+			}
+		})
+
+		ginkgo.When("the SSM parameters are loaded", func() {
+			ginkgo.It("should fetch them in one batch, once per ttl, the context values taking precedence", func() {
+				ginkgo.GinkgoT().Setenv(cache.EnvVar, ginkgo.GinkgoT().TempDir())
+
+				calls := 0
+				mockClient.GetParametersByPathPagesFunc = func(input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool) error {
+					calls++
+					gomega.Expect(aws.StringValue(input.Path)).To(gomega.Equal("/aft/"))
+					gomega.Expect(aws.BoolValue(input.Recursive)).To(gomega.BeTrue())
+
+					fn(&ssm.GetParametersByPathOutput{Parameters: []*ssm.Parameter{
+						{Name: aws.String(aftMgmtAccountID), Value: aws.String("111111111111"), Type: aws.String(ssm.ParameterTypeString)},
+						{Name: aws.String(tfDistribution), Value: aws.String("oss"), Type: aws.String(ssm.ParameterTypeString)},
+					}}, false)
+					fn(&ssm.GetParametersByPathOutput{Parameters: []*ssm.Parameter{
+						{Name: aws.String("/aft/config/terraform/token"), Value: aws.String("secret"), Type: aws.String(ssm.ParameterTypeSecureString)},
+					}}, true)
+					return nil
+				}
+
+				overrides := map[string]string{tfDistribution: "tfc"}
+				expected := map[string]string{aftMgmtAccountID: "111111111111", tfDistribution: "tfc"}
+
+				params, err := loadSSMParameters(mockClient, "ssm.json", time.Hour, overrides)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(params).To(gomega.Equal(expected))
+
+				params, err = loadSSMParameters(mockClient, "ssm.json", time.Hour, overrides)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(params).To(gomega.Equal(expected))
+				gomega.Expect(calls).To(gomega.Equal(1))
+
+				_, err = loadSSMParameters(mockClient, "ssm.json", cacheTTL(time.Hour, true), overrides)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(calls).To(gomega.Equal(2))
+			})
+		})
+
+		ginkgo.When("the caller account is known", func() {
+			ginkgo.It("should cache the parameters per account and region", func() {
+				mockClient := &MockSTSClient{
+					GetCallerIdentityFunc: func(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+						return &sts.GetCallerIdentityOutput{Account: aws.String("111111111111")}, nil
+					},
+				}
+
+				cacheName, ttl := ssmCache(mockClient, "us-east-1", time.Hour)
+				gomega.Expect(cacheName).To(gomega.Equal("ssm-111111111111-us-east-1.json"))
+				gomega.Expect(ttl).To(gomega.Equal(time.Hour))
+			})
+		})
+
+		ginkgo.When("the caller account is unknown", func() {
+			ginkgo.It("should disable the cache", func() {
+				mockClient := &MockSTSClient{
+					GetCallerIdentityFunc: func(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+						return nil, errors.New("expired token")
+					},
+				}
+
+				cacheName, ttl := ssmCache(mockClient, "us-east-1", time.Hour)
+				gomega.Expect(cacheName).To(gomega.BeEmpty())
+				gomega.Expect(ttl).To(gomega.BeZero())
+			})
+		})
+
+		ginkgo.When("SSM parameters are missing", func() {
+			ginkgo.It("should report them together", func() {
+				params, err := getSSMParameters(map[string]string{tfDistribution: "oss", tfS3BucketID: "bucket"}, []string{tfDistribution})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(params).To(gomega.Equal(map[string]string{tfDistribution: "oss"}))
+
+				_, err = getSSMParameters(map[string]string{tfDistribution: "oss"}, []string{aftMgmtAccountID, tfDistribution, tfKmsKeyID})
+				gomega.Expect(err).To(gomega.MatchError(gomega.HavePrefix(
+					"missing SSM parameters /aft/account/aft-management/account-id, /aft/config/oss-backend/kms-key-id,")))
+			})
+		})
+
+		ginkgo.When("the credentials reach the AFT management account of the context", func() {
+			ginkgo.It("should not return an error", func() {
+				err := checkContextAccount(mockClient, config.Context{AftManagementAccountID: "111111111111"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			})
+		})
+
+		ginkgo.When("the credentials reach another AFT management account", func() {
+			ginkgo.It("should return an error", func() {
+				err := checkContextAccount(mockClient, config.Context{AftManagementAccountID: "222222222222"})
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("the context expects 222222222222")))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the Terraform arguments", func() {
+		ginkgo.AfterEach(func() {
+			args.targetAccounts = nil
+			args.terraformArgs = nil
+			args.allowDestroy = false
+		})
+
+		ginkgo.When("the arguments are given after --", func() {
+			ginkgo.It("should keep them as they are", func() {
+				cmd := &cobra.Command{}
+				cmd.Flags().StringVarP(&args.terraformCommand, "terraform-command", "c", "", "")
+
+				terraformArgs, err := terraformArguments(cmd, []string{"plan", `-target=module.x["a b"]`})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(terraformArgs).To(gomega.Equal([]string{"plan", `-target=module.x["a b"]`}))
+			})
+		})
+
+		ginkgo.When("the deprecated terraform command is given", func() {
+			ginkgo.It("should split it", func() {
+				cmd := &cobra.Command{}
+				cmd.Flags().StringVarP(&args.terraformCommand, "terraform-command", "c", "", "")
+				gomega.Expect(cmd.Flags().Set("terraform-command", "plan -out=plan.tfplan")).To(gomega.Succeed())
+
+				terraformArgs, err := terraformArguments(cmd, nil)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(terraformArgs).To(gomega.Equal([]string{"plan", "-out=plan.tfplan"}))
+
+				_, err = terraformArguments(cmd, []string{"plan"})
+				gomega.Expect(err).To(gomega.HaveOccurred())
+			})
+		})
+
+		ginkgo.When("a destroying command is given", func() {
+			ginkgo.It("should be denied unless allowed", func() {
+				args.targetAccounts = []string{"111111111111"}
+
+				for _, terraformArgs := range [][]string{
+					{"destroy", "-auto-approve"},
+					{"-chdir=terraform", "destroy"},
+					{"apply", "-destroy"},
+					{"apply", "-auto-approve", "destroy.tfplan"},
+					{"apply", "-lock-timeout", "5m", "tfplan"},
+					{"state", "rm", "module.x"},
+					{"state", "-lock=false", "mv", "a", "b"},
+					{"import", "aws_s3_bucket.b", "bucket"},
+					{"taint", "aws_s3_bucket.b"},
+					{"force-unlock", "-force", "1234"},
+					{"workspace", "delete", "old"},
+				} {
+					args.terraformArgs = terraformArgs
+					gomega.Expect(validateInput()).To(gomega.MatchError(gomega.ContainSubstring("--allow-destroy")), strings.Join(terraformArgs, " "))
+				}
+
+				args.allowDestroy = true
+				gomega.Expect(validateInput()).To(gomega.Succeed())
+			})
+		})
+
+		ginkgo.DescribeTable("the -destroy option of apply",
+			func(option string, denied bool) {
+				args.targetAccounts = []string{"111111111111"}
+				args.terraformArgs = []string{"apply", option}
+
+				if denied {
+					gomega.Expect(validateInput()).To(gomega.MatchError(gomega.ContainSubstring("--allow-destroy")))
+				} else {
+					gomega.Expect(validateInput()).To(gomega.Succeed())
+				}
+			},
+			ginkgo.Entry("without a value", "-destroy", true),
+			ginkgo.Entry("with two dashes", "--destroy", true),
+			ginkgo.Entry("set to true", "-destroy=true", true),
+			ginkgo.Entry("set to TRUE", "-destroy=TRUE", true),
+			ginkgo.Entry("set to 1", "-destroy=1", true),
+			ginkgo.Entry("set to t", "-destroy=t", true),
+			ginkgo.Entry("set to T with two dashes", "--destroy=T", true),
+			ginkgo.Entry("set to an unreadable value", "-destroy=yes", true),
+			ginkgo.Entry("set to false", "-destroy=false", false),
+			ginkgo.Entry("set to 0", "--destroy=0", false),
+			ginkgo.Entry("set to F", "-destroy=F", false),
+			ginkgo.Entry("a variable named destroy", "-var=destroy=true", false),
+		)
+
+		ginkgo.When("any other command is given", func() {
+			ginkgo.It("should be allowed", func() {
+				args.targetAccounts = []string{"111111111111"}
+
+				for _, terraformArgs := range [][]string{
+					{"console"}, {"test"}, {"graph"}, {"plan", "-destroy"}, {"apply", "-var", "env=prod"},
+					{"state", "list"}, {"state", "show", "module.x"}, {"workspace", "select", "default"},
+				} {
+					args.terraformArgs = terraformArgs
+					gomega.Expect(validateInput()).To(gomega.Succeed(), terraformArgs[0])
+				}
+			})
+		})
+
+		ginkgo.When("plan exits with 2", func() {
+			ginkgo.It("should only be changes with -detailed-exitcode", func() {
+				args.terraformArgs = []string{"plan"}
+				gomega.Expect(isPlanWithChanges(&terraformExitError{code: 2})).To(gomega.BeFalse())
+
+				args.terraformArgs = []string{"plan", "-detailed-exitcode"}
+				gomega.Expect(isPlanWithChanges(&terraformExitError{code: 2})).To(gomega.BeTrue())
+				gomega.Expect(isPlanWithChanges(&terraformExitError{code: 1})).To(gomega.BeFalse())
+			})
+		})
+	})
 })
@@ -0,0 +1,220 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/service/sts"
+	awsAft "github.com/edgarsilva948/aftctl/pkg/aws"
+	profile "github.com/edgarsilva948/aftctl/pkg/aws/profiles"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Giving the credentials to Terraform", func() {
+
+	ginkgo.AfterEach(func() {
+		args.sessionName = ""
+		args.externalID = ""
+		args.sessionDuration = time.Hour
+		args.mfaSerial = ""
+		args.mfaToken = ""
+	})
+
+	ginkgo.Context("testing the adminSessionOptions function", func() {
+		var mockClient *MockSTSClient
+
+		ginkgo.BeforeEach(func() {
+			mockClient = &MockSTSClient{
+				GetCallerIdentityFunc: func(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+					return &sts.GetCallerIdentityOutput{
+						Arn: aws.String("arn:aws:sts::111111111111:assumed-role/AWSReservedSSO_Admin/jdoe@example.com"),
+					}, nil
+				},
+			}
+		})
+
+		ginkgo.When("no session name is given", func() {
+			ginkgo.It("should name the session after the caller", func() {
+				options, err := adminSessionOptions(mockClient, nil, nil)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(options.SessionName).To(gomega.Equal("AWSAFT-Session-jdoe@example.com"))
+				gomega.Expect(options.Duration).To(gomega.Equal(time.Hour))
+			})
+		})
+
+		ginkgo.When("an MFA serial is given without token", func() {
+			ginkgo.It("should prompt for the token", func() {
+				args.sessionName = "ci"
+				args.mfaSerial = "arn:aws:iam::111111111111:mfa/jdoe"
+
+				out := &bytes.Buffer{}
+				options, err := adminSessionOptions(mockClient, strings.NewReader("123456\n"), out)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(out.String()).To(gomega.Equal("MFA token for arn:aws:iam::111111111111:mfa/jdoe: "))
+				gomega.Expect(options.SessionName).To(gomega.Equal("ci"))
+				gomega.Expect(options.MFAToken).To(gomega.Equal("123456"))
+
+				_, err = adminSessionOptions(mockClient, strings.NewReader("12\n"), out)
+				gomega.Expect(err).To(gomega.MatchError("MFA token must be 6 digits"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the AssumeRole function", func() {
+		ginkgo.When("the session settings are given", func() {
+			ginkgo.It("should send them to STS", func() {
+				var input *sts.AssumeRoleInput
+				mockClient := &MockSTSClient{
+					AssumeRoleFunc: func(i *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+						input = i
+						return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{}}, nil
+					},
+				}
+
+				_, err := profile.AssumeRole(mockClient, "arn:aws:iam::111111111111:role/AWSAFTAdmin", profile.Options{
+					SessionName: "AWSAFT-Session-jdoe",
+					ExternalID:  "external",
+					Duration:    2 * time.Hour,
+					MFASerial:   "arn:aws:iam::111111111111:mfa/jdoe",
+					MFAToken:    "123456",
+				})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(aws.StringValue(input.RoleSessionName)).To(gomega.Equal("AWSAFT-Session-jdoe"))
+				gomega.Expect(aws.StringValue(input.ExternalId)).To(gomega.Equal("external"))
+				gomega.Expect(aws.Int64Value(input.DurationSeconds)).To(gomega.Equal(int64(7200)))
+				gomega.Expect(aws.StringValue(input.SerialNumber)).To(gomega.Equal("arn:aws:iam::111111111111:mfa/jdoe"))
+				gomega.Expect(aws.StringValue(input.TokenCode)).To(gomega.Equal("123456"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the checkExecutionRoles function", func() {
+		ginkgo.When("the AFT Admin role is denied in some accounts", func() {
+			ginkgo.It("should report them together", func() {
+				mockClient := &MockSTSClient{
+					AssumeRoleFunc: func(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+						if strings.Contains(aws.StringValue(input.RoleArn), "111111111111") {
+							return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{}}, nil
+						}
+						return nil, errors.New("AccessDenied")
+					},
+				}
+
+				err := checkExecutionRoles(mockClient, []string{"111111111111", "222222222222", "333333333333"}, "AWSAFTExecution", "session")
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("of 2 account(s)")))
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("arn:aws:iam::222222222222:role/AWSAFTExecution: AccessDenied")))
+				gomega.Expect(err.Error()).ToNot(gomega.ContainSubstring("111111111111"))
+
+				gomega.Expect(checkExecutionRoles(mockClient, []string{"111111111111"}, "AWSAFTExecution", "session")).To(gomega.Succeed())
+			})
+		})
+	})
+
+	ginkgo.Context("testing the credentialProcessEnv function", func() {
+		session := profile.Options{SessionName: "AWSAFT-Session-jdoe", Duration: time.Hour, ExternalID: "external"}
+
+		ginkgo.When("a profile is given", func() {
+			ginkgo.It("should write a config file running aftctl credentials process", func() {
+				ginkgo.GinkgoT().Setenv("AWS_CONFIG_FILE", "/home/jdoe/.aws/config")
+
+				env, remove, err := credentialProcessEnv("arn:aws:iam::111111111111:role/AWSAFTAdmin", session, awsAft.ClientOptions{Profile: "aft admin"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				configFile := strings.TrimPrefix(env[0], "AWS_CONFIG_FILE=")
+				content, err := os.ReadFile(configFile)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(string(content)).To(gomega.HavePrefix("[profile aftctl-local]\ncredential_process = "))
+				gomega.Expect(string(content)).To(gomega.ContainSubstring(
+					`credentials process --role-arn arn:aws:iam::111111111111:role/AWSAFTAdmin --session-name AWSAFT-Session-jdoe ` +
+						`--duration 1h0m0s --config-file /home/jdoe/.aws/config --external-id external --profile "aft admin"`))
+				gomega.Expect(env).To(gomega.ContainElements("AWS_PROFILE=aftctl-local", "AWS_ACCESS_KEY_ID="))
+
+				remove()
+				_, err = os.Stat(configFile)
+				gomega.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+			})
+		})
+
+		ginkgo.When("the credential_process runs with the returned environment", func() {
+			var output string
+
+			ginkgo.BeforeEach(func() {
+				dir := ginkgo.GinkgoT().TempDir()
+				output = filepath.Join(dir, "process")
+
+				// the fake aftctl records what the credential_process runs it with
+				executable := filepath.Join(dir, "aftctl")
+				script := "#!/bin/sh\nprintf '%s\\n' \"$@\" \"AWS_CONFIG_FILE=$AWS_CONFIG_FILE\" > " + output + "\n"
+				gomega.Expect(os.WriteFile(executable, []byte(script), 0o700)).To(gomega.Succeed())
+
+				aftctlExecutable = func() (string, error) { return executable, nil }
+				ginkgo.DeferCleanup(func() { aftctlExecutable = os.Executable })
+			})
+
+			// runProcess runs the credential_process of the written config file like the SDKs and returns its arguments
+			runProcess := func(env []string) []string {
+				content, err := os.ReadFile(strings.TrimPrefix(env[0], "AWS_CONFIG_FILE="))
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				line := strings.Split(string(content), "\n")[1]
+				command := exec.Command("sh", "-c", strings.TrimPrefix(line, "credential_process = "))
+				command.Env = append(os.Environ(), env...)
+				gomega.Expect(command.Run()).To(gomega.Succeed())
+
+				recorded, err := os.ReadFile(output)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				return strings.Split(strings.TrimSpace(string(recorded)), "\n")
+			}
+
+			ginkgo.It("should read the profile of the user from the AWS config file of the user", func() {
+				ginkgo.GinkgoT().Setenv("AWS_CONFIG_FILE", "/home/jdoe/.aws/config")
+				ginkgo.GinkgoT().Setenv("AWS_PROFILE", "aft")
+
+				env, remove, err := credentialProcessEnv("arn:aws:iam::111111111111:role/AWSAFTAdmin", session, awsAft.ClientOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				defer remove()
+
+				processArgs := runProcess(env)
+				gomega.Expect(processArgs).To(gomega.ContainElement("AWS_CONFIG_FILE=" + strings.TrimPrefix(env[0], "AWS_CONFIG_FILE=")))
+				gomega.Expect(strings.Join(processArgs, " ")).To(gomega.ContainSubstring("--config-file /home/jdoe/.aws/config"))
+				gomega.Expect(strings.Join(processArgs, " ")).To(gomega.ContainSubstring("--profile aft"))
+			})
+
+			ginkgo.It("should not run the aftctl-local profile again without a profile", func() {
+				ginkgo.GinkgoT().Setenv("AWS_CONFIG_FILE", "")
+				ginkgo.GinkgoT().Setenv("AWS_PROFILE", "")
+				ginkgo.GinkgoT().Setenv("AWS_DEFAULT_PROFILE", "")
+
+				env, remove, err := credentialProcessEnv("arn:aws:iam::111111111111:role/AWSAFTAdmin", session, awsAft.ClientOptions{})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				defer remove()
+
+				processArgs := strings.Join(runProcess(env), " ")
+				gomega.Expect(processArgs).To(gomega.ContainSubstring("--config-file " + defaults.SharedConfigFilename()))
+				gomega.Expect(processArgs).ToNot(gomega.ContainSubstring("--profile"))
+			})
+		})
+
+		ginkgo.When("the credentials come from the environment", func() {
+			ginkgo.It("should return an error", func() {
+				ginkgo.GinkgoT().Setenv("AWS_ACCESS_KEY_ID", "AKIAENV")
+
+				_, _, err := credentialProcessEnv("arn:aws:iam::111111111111:role/AWSAFTAdmin", session, awsAft.ClientOptions{})
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("requires the credentials of a profile")))
+			})
+		})
+	})
+})
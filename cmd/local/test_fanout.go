@@ -0,0 +1,211 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	awsAft "github.com/edgarsilva948/aftctl/pkg/aws"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Running Terraform against several accounts", func() {
+
+	ginkgo.AfterEach(func() {
+		args.targetAccounts = nil
+		args.accountsFile = ""
+		args.selectors = nil
+		args.all = false
+		args.terraformArgs = nil
+	})
+
+	ginkgo.Context("testing the target accounts", func() {
+		ginkgo.When("accounts are given with the flag and the file", func() {
+			ginkgo.It("should return them once, in order", func() {
+				args.accountsFile = filepath.Join(ginkgo.GinkgoT().TempDir(), "accounts")
+				content := "# sandboxes\n222222222222\n\n333333333333 # team b\n111111111111\n"
+				gomega.Expect(os.WriteFile(args.accountsFile, []byte(content), 0600)).To(gomega.Succeed())
+				args.targetAccounts = []string{"111111111111"}
+
+				accounts, err := targetAccounts(nil, nil)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(accounts).To(gomega.Equal([]string{"111111111111", "222222222222", "333333333333"}))
+			})
+		})
+
+		ginkgo.When("the file holds an invalid account", func() {
+			ginkgo.It("should return an error naming the line", func() {
+				path := filepath.Join(ginkgo.GinkgoT().TempDir(), "accounts")
+				gomega.Expect(os.WriteFile(path, []byte("111111111111\nsandbox\n"), 0600)).To(gomega.Succeed())
+
+				_, err := readAccountsFile(path)
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("line 2")))
+			})
+		})
+
+		ginkgo.When("the accounts are selected in conflicting ways", func() {
+			ginkgo.It("should be invalid", func() {
+				args.terraformArgs = []string{"plan"}
+				gomega.Expect(validateInput()).To(gomega.MatchError(gomega.ContainSubstring("--all")))
+
+				args.all = true
+				args.targetAccounts = []string{"111111111111"}
+				gomega.Expect(validateInput()).To(gomega.MatchError(gomega.ContainSubstring("can't be used together")))
+
+				args.targetAccounts = nil
+				gomega.Expect(validateInput()).To(gomega.Succeed())
+
+				args.all = false
+				args.selectors = []string{"owner=team"}
+				gomega.Expect(validateInput()).To(gomega.MatchError(gomega.ContainSubstring("invalid selector key")))
+			})
+		})
+
+		ginkgo.When("the accounts are listed for the selectors", func() {
+			ginkgo.It("should add the account customization of the AFT request metadata", func() {
+				active := aws.String(organizations.AccountStatusActive)
+				orgClient := &awsAft.MockOrganizationsClient{
+					Roots: []*organizations.Root{{Id: aws.String("r-1"), Name: aws.String("Root")}},
+					ParentAccounts: map[string][]*organizations.Account{
+						"r-1": {
+							{Id: aws.String("111111111111"), Status: active},
+							{Id: aws.String("222222222222"), Status: active},
+						},
+					},
+				}
+				ddbClient := &awsAft.MockDynamoDBClient{
+					ScanPagesFunc: func(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+						gomega.Expect(aws.StringValue(input.TableName)).To(gomega.Equal("aft-request-metadata"))
+						fn(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{
+							{"id": {S: aws.String("222222222222")}, "account_customizations_name": {S: aws.String("sandbox")}},
+						}}, true)
+						return nil
+					},
+				}
+
+				accounts, err := listAccounts(orgClient, ddbClient, map[string]string{
+					aftRequestMetadataTable: "aft-request-metadata",
+				})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(accounts).To(gomega.HaveLen(2))
+				gomega.Expect(accounts[0].CustomizationsName).To(gomega.BeEmpty())
+				gomega.Expect(accounts[1].CustomizationsName).To(gomega.Equal("sandbox"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the prefixWriter", func() {
+		ginkgo.When("lines are written in pieces", func() {
+			ginkgo.It("should prefix whole lines", func() {
+				buffer := &bytes.Buffer{}
+				w := newPrefixWriter(buffer, "111111111111", &sync.Mutex{})
+
+				_, err := w.Write([]byte("Plan: 1 to add"))
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(buffer.String()).To(gomega.BeEmpty())
+
+				_, err = w.Write([]byte(", 0 to change\nNo"))
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(w.Flush()).To(gomega.Succeed())
+
+				gomega.Expect(buffer.String()).To(gomega.Equal("[111111111111] Plan: 1 to add, 0 to change\n[111111111111] No\n"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the fanOut function", func() {
+		var run *localRun
+
+		ginkgo.BeforeEach(func() {
+			root := ginkgo.GinkgoT().TempDir()
+			dir := filepath.Join(root, "sandbox", "terraform")
+			gomega.Expect(os.MkdirAll(filepath.Join(root, ".git"), 0700)).To(gomega.Succeed())
+			gomega.Expect(os.MkdirAll(filepath.Join(dir, ".terraform"), 0700)).To(gomega.Succeed())
+			gomega.Expect(os.WriteFile(filepath.Join(dir, "main.tf"), nil, 0600)).To(gomega.Succeed())
+			gomega.Expect(os.WriteFile(filepath.Join(dir, "backend.jinja"), []byte(`key = "{{ key }}"`), 0600)).To(gomega.Succeed())
+
+			run = &localRun{
+				accounts: []string{"111111111111", "222222222222", "333333333333"},
+				repo:     repository{Type: accountCustomizationsRepository, Root: root, Customization: "sandbox"},
+				dir:      dir,
+				params:   map[string]string{},
+			}
+		})
+
+		ginkgo.When("the accounts are planned", func() {
+			ginkgo.It("should run each account in its own working copy and record its status", func() {
+				// the first account has changes and the last one fails
+				fakeTerraform(`
+test "$1" = init && exit 0
+test -d .terraform -o -d ../../.git && exit 3
+case "$(cat backend.tf)" in
+  *111111111111*) echo changes; exit 2 ;;
+  *333333333333*) echo failure >&2; exit 1 ;;
+esac
+printf "no changes"`)
+				args.terraformArgs = []string{"plan", "-detailed-exitcode"}
+
+				stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+				results := fanOut(run, 2, terraformStreams{stdout: stdout, stderr: stderr})
+
+				gomega.Expect(results).To(gomega.HaveLen(3))
+				gomega.Expect(results[0].Status).To(gomega.Equal(changesStatus))
+				gomega.Expect(results[0].StateKey).To(gomega.Equal("111111111111-aft-account-customizations/sandbox/terraform.tfstate"))
+				gomega.Expect(results[1].Status).To(gomega.Equal(noChangesStatus))
+				gomega.Expect(results[2].Status).To(gomega.Equal(errorStatus))
+				gomega.Expect(results[2].ExitCode).To(gomega.Equal(1))
+
+				gomega.Expect(stdout.String()).To(gomega.ContainSubstring("[111111111111] changes\n"))
+				gomega.Expect(stdout.String()).To(gomega.ContainSubstring("[222222222222] no changes\n"))
+				gomega.Expect(stderr.String()).To(gomega.Equal("[333333333333] failure\n"))
+
+				summary := &Summary{Results: results}
+				gomega.Expect(summary.exitCode(true)).To(gomega.Equal(1))
+
+				// the working copies are removed and the repository is left as it was
+				_, err := os.Stat(filepath.Join(run.dir, "backend.tf"))
+				gomega.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+			})
+		})
+
+		ginkgo.When("terraform init fails", func() {
+			ginkgo.It("should not run the command", func() {
+				fakeTerraform(`test "$1" = init && exit 1; echo ran`)
+				args.terraformArgs = []string{"apply"}
+
+				stdout := &bytes.Buffer{}
+				results := fanOut(run, 1, terraformStreams{stdout: stdout, stderr: &bytes.Buffer{}})
+
+				for _, result := range results {
+					gomega.Expect(result.Error).To(gomega.HavePrefix("terraform init failed"))
+				}
+				gomega.Expect(strings.Contains(stdout.String(), "ran")).To(gomega.BeFalse())
+			})
+		})
+	})
+
+	ginkgo.Context("testing the summary", func() {
+		ginkgo.When("plans have changes", func() {
+			ginkgo.It("should exit with 2 only when -detailed-exitcode was asked for", func() {
+				summary := &Summary{Results: []*Result{{Status: noChangesStatus}, {Status: changesStatus}}}
+				gomega.Expect(summary.exitCode(true)).To(gomega.Equal(2))
+				gomega.Expect(summary.exitCode(false)).To(gomega.Equal(0))
+
+				buffer := &bytes.Buffer{}
+				gomega.Expect(summary.PrintTable(buffer)).To(gomega.Succeed())
+				gomega.Expect(buffer.String()).To(gomega.ContainSubstring("2 accounts in 0s: 1 no changes, 1 changes, 0 succeeded, 0 error"))
+			})
+		})
+	})
+})
@@ -0,0 +1,140 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Running the api_helpers scripts", func() {
+	var (
+		run     *localRun
+		root    string
+		helpers string
+		stdout  *bytes.Buffer
+		streams terraformStreams
+	)
+
+	// apply runs the fake Terraform of the spec
+	apply := func() error {
+		return runTerraform(root, args.terraformArgs, nil, streams)
+	}
+
+	ginkgo.BeforeEach(func() {
+		root = ginkgo.GinkgoT().TempDir()
+		helpers = filepath.Join(root, "sandbox", apiHelpersDir)
+		gomega.Expect(os.MkdirAll(filepath.Join(helpers, "python"), 0700)).To(gomega.Succeed())
+		gomega.Expect(os.WriteFile(filepath.Join(helpers, preAPIHelpers),
+			[]byte(`echo "pre $VENDED_ACCOUNT_ID $CUSTOMIZATION $AWS_ACCESS_KEY_ID $(pwd)"`), 0600)).To(gomega.Succeed())
+		gomega.Expect(os.WriteFile(filepath.Join(helpers, postAPIHelpers),
+			[]byte(`echo "post $AWS_REGION $AFT_MGMT_ACCOUNT"`), 0600)).To(gomega.Succeed())
+
+		fakeTerraform(`echo "terraform $1"`)
+		args.terraformArgs = []string{"apply"}
+
+		run = &localRun{
+			repo:   repository{Type: accountCustomizationsRepository, Root: root, Customization: "sandbox"},
+			params: map[string]string{aftExecutionRoleName: "AWSAFTExecution", aftMgmtAccountID: "000000000000", ctMgmtRegion: "us-east-1"},
+			adminClient: &MockSTSClient{
+				AssumeRoleFunc: func(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+					gomega.Expect(aws.StringValue(input.RoleArn)).To(gomega.Equal("arn:aws:iam::111111111111:role/AWSAFTExecution"))
+					return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{AccessKeyId: aws.String("target-key")}}, nil
+				},
+			},
+			sessionName: "AWSAFT-Session-jdoe",
+		}
+
+		stdout = &bytes.Buffer{}
+		streams = terraformStreams{stdout: stdout, stderr: &bytes.Buffer{}}
+	})
+
+	ginkgo.AfterEach(func() {
+		args.terraformArgs = nil
+		args.skipHelpers = false
+	})
+
+	ginkgo.When("terraform apply runs", func() {
+		ginkgo.It("should run the scripts around it with the target account credentials", func() {
+			gomega.Expect(run.withAPIHelpers(root, "111111111111", streams, apply)).To(gomega.Succeed())
+
+			gomega.Expect(stdout.String()).To(gomega.Equal(
+				"pre 111111111111 sandbox target-key " + root + "\nterraform apply\npost us-east-1 000000000000\n"))
+		})
+	})
+
+	ginkgo.When("the python requirements are given", func() {
+		ginkgo.It("should install them in a virtual environment", func() {
+			gomega.Expect(os.WriteFile(filepath.Join(helpers, helperRequirements), []byte("boto3\n"), 0600)).To(gomega.Succeed())
+			gomega.Expect(os.WriteFile(filepath.Join(helpers, preAPIHelpers), []byte(`echo "pre $(command -v pip)"`), 0600)).To(gomega.Succeed())
+
+			// the fake python creates a pip printing its arguments
+			python := filepath.Join(ginkgo.GinkgoT().TempDir(), "python3")
+			gomega.Expect(os.WriteFile(python, []byte("#!/bin/sh\nmkdir -p $3/bin\n"+
+				"printf '#!/bin/sh\\necho pip $1 $2\\n' > $3/bin/pip\nchmod +x $3/bin/pip\n"), 0755)).To(gomega.Succeed())
+			previous := pythonBinary
+			pythonBinary = python
+			ginkgo.DeferCleanup(func() { pythonBinary = previous })
+
+			gomega.Expect(run.withAPIHelpers(root, "111111111111", streams, apply)).To(gomega.Succeed())
+
+			gomega.Expect(stdout.String()).To(gomega.MatchRegexp(
+				`^pip install -r\npre .*/aftctl-api-helpers-venv-\d+/bin/pip\nterraform apply\npost `))
+		})
+	})
+
+	ginkgo.When("another command or --skip-helpers is given", func() {
+		ginkgo.It("should only run Terraform", func() {
+			args.terraformArgs = []string{"plan"}
+			gomega.Expect(run.withAPIHelpers(root, "111111111111", streams, apply)).To(gomega.Succeed())
+
+			args.terraformArgs = []string{"apply"}
+			args.skipHelpers = true
+			gomega.Expect(run.withAPIHelpers(root, "111111111111", streams, apply)).To(gomega.Succeed())
+
+			gomega.Expect(stdout.String()).To(gomega.Equal("terraform plan\nterraform apply\n"))
+		})
+	})
+
+	ginkgo.When("the pre script fails", func() {
+		ginkgo.It("should not run Terraform", func() {
+			gomega.Expect(os.WriteFile(filepath.Join(helpers, preAPIHelpers), []byte("exit 3"), 0600)).To(gomega.Succeed())
+
+			err := run.withAPIHelpers(root, "111111111111", streams, apply)
+			gomega.Expect(err).To(gomega.MatchError("pre-api-helpers.sh failed: exit status 3"))
+			gomega.Expect(stdout.String()).To(gomega.BeEmpty())
+		})
+	})
+
+	ginkgo.When("terraform apply fails", func() {
+		ginkgo.It("should not run the post script", func() {
+			err := run.withAPIHelpers(root, "111111111111", streams, func() error {
+				return errors.New("apply failed")
+			})
+			gomega.Expect(err).To(gomega.MatchError("apply failed"))
+			gomega.Expect(stdout.String()).ToNot(gomega.ContainSubstring("post"))
+		})
+	})
+
+	ginkgo.When("the repository has no api_helpers", func() {
+		ginkgo.It("should only run Terraform", func() {
+			run.repo.Type = accountProvisioningCustomizationsRepository
+			gomega.Expect(run.repo.apiHelpersPath(root)).To(gomega.BeEmpty())
+
+			run.repo = repository{Type: globalCustomizationsRepository, Root: root}
+			gomega.Expect(run.repo.apiHelpersPath(root)).To(gomega.Equal(filepath.Join(root, apiHelpersDir)))
+
+			gomega.Expect(run.withAPIHelpers(root, "111111111111", streams, apply)).To(gomega.Succeed())
+			gomega.Expect(stdout.String()).To(gomega.Equal("terraform apply\n"))
+		})
+	})
+})
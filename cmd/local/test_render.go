@@ -0,0 +1,97 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/flosch/pongo2"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Rendering the jinja templates", func() {
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		dir = ginkgo.GinkgoT().TempDir()
+	})
+
+	writeTemplate := func(name string, content string) {
+		gomega.Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).To(gomega.Succeed())
+	}
+
+	ginkgo.Context("testing the renderTemplates function", func() {
+		ginkgo.When("the templates are valid", func() {
+			ginkgo.It("should render them for the target account", func() {
+				writeTemplate("backend.jinja", `{% if tf_distribution_type == "oss" %}key = "{{ key }}"{% else %}name = "{{ terraform_workspace_name }}"{% endif %}`)
+				writeTemplate("providers.jinja", `{% for alias in aliases %}{{ alias|upper }}@{{ region|default:"us-east-1" }} {% endfor %}`)
+
+				context := templateContext("111111111111", map[string]string{tfDistribution: "oss", tfBackendRegion: "eu-west-1"},
+					repository{Type: globalCustomizationsRepository})
+				context["aliases"] = []string{"a", "b"}
+
+				files, err := renderTemplates(dir, context)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(files).To(gomega.Equal([]renderedFile{
+					{Path: filepath.Join(dir, "backend.tf"), Content: `key = "111111111111-aft-global-customizations/terraform.tfstate"`},
+					{Path: filepath.Join(dir, "providers.tf"), Content: "A@eu-west-1 B@eu-west-1 "},
+				}))
+
+				buffer := &bytes.Buffer{}
+				gomega.Expect(printRenderedFiles(buffer, files)).To(gomega.Succeed())
+				gomega.Expect(buffer.String()).To(gomega.Equal(
+					"# backend.tf\nkey = \"111111111111-aft-global-customizations/terraform.tfstate\"\n# providers.tf\nA@eu-west-1 B@eu-west-1 \n"))
+			})
+		})
+
+		ginkgo.When("a template uses an undefined variable", func() {
+			ginkgo.It("should return an error naming it", func() {
+				writeTemplate("backend.jinja", `bucket = "{{ bucket }}"{% set x = "a" %}{{ x }}{% if owner or team.name %}{% endif %}`)
+
+				_, err := renderTemplates(dir, pongo2.Context{"bucket": "b"})
+				gomega.Expect(err).To(gomega.MatchError(gomega.HaveSuffix("backend.jinja uses undefined variables owner, team")))
+			})
+		})
+
+		ginkgo.When("a template declares its own variables", func() {
+			ginkgo.It("should only report the variables pongo2 reads from the context", func() {
+				writeTemplate("providers.jinja", `{% for alias in aliases %}{{ alias|upper }}{% endfor %}`+
+					`{% with name="n" %}{{ name }}{% endwith %}{{ "owner"|lower }}{{ region|default:fallback }}`)
+
+				_, err := renderTemplates(dir, pongo2.Context{"aliases": []string{"a"}})
+				gomega.Expect(err).To(gomega.MatchError(gomega.HaveSuffix("providers.jinja uses undefined variables fallback, region")))
+			})
+		})
+
+		ginkgo.When("a template has a syntax error", func() {
+			ginkgo.It("should return an error", func() {
+				writeTemplate("backend.jinja", `{% if key %}key = "{{ key }}"`)
+
+				_, err := renderTemplates(dir, pongo2.Context{"key": "k"})
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("error parsing")))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the readVarFile function", func() {
+		ginkgo.When("the file holds a map", func() {
+			ginkgo.It("should return the variables", func() {
+				path := filepath.Join(dir, "vars.yaml")
+				gomega.Expect(os.WriteFile(path, []byte("region: eu-west-1\nenabled: true\n"), 0600)).To(gomega.Succeed())
+
+				vars, err := readVarFile(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(vars).To(gomega.Equal(map[string]interface{}{"region": "eu-west-1", "enabled": true}))
+
+				gomega.Expect(os.WriteFile(path, []byte("- region\n"), 0600)).To(gomega.Succeed())
+				_, err = readVarFile(path)
+				gomega.Expect(err).To(gomega.HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,137 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"os"
+	"path/filepath"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// newRepository creates a git clone with the given origin and the folders, returning its root
+func newRepository(remote string, folders ...string) string {
+	root := ginkgo.GinkgoT().TempDir()
+
+	gomega.Expect(os.MkdirAll(filepath.Join(root, ".git"), 0755)).To(gomega.Succeed())
+	config := "[core]\n\tbare = false\n[remote \"origin\"]\n\turl = " + remote + "\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n"
+	gomega.Expect(os.WriteFile(filepath.Join(root, ".git", "config"), []byte(config), 0644)).To(gomega.Succeed())
+
+	for _, folder := range folders {
+		gomega.Expect(os.MkdirAll(filepath.Join(root, folder), 0755)).To(gomega.Succeed())
+	}
+
+	return root
+}
+
+// defaultNames doesn't configure any repository name
+func defaultNames() map[repositoryType]string {
+	return map[repositoryType]string{}
+}
+
+var _ = ginkgo.Describe("Detecting the AFT repository", func() {
+
+	ginkgo.Context("testing the detectRepository function", func() {
+		ginkgo.When("the remote is the global customizations repository", func() {
+			ginkgo.It("should use the global state key", func() {
+				root := newRepository("codecommit::us-east-1://aft-global-customizations", "terraform")
+
+				repo, err := detectRepository(filepath.Join(root, "terraform"), "", defaultNames)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(repo.Type).To(gomega.Equal(globalCustomizationsRepository))
+				gomega.Expect(repo.stateKey("111111111111")).To(gomega.Equal("111111111111-aft-global-customizations/terraform.tfstate"))
+			})
+		})
+
+		ginkgo.When("the remote is an account customizations repository with a custom name", func() {
+			ginkgo.It("should include the customization folder in the state key", func() {
+				root := newRepository("git@github.com:acme/platform-account-customizations.git", "sandbox/terraform")
+
+				repo, err := detectRepository(filepath.Join(root, "sandbox", "terraform"), "", func() map[repositoryType]string {
+					return map[repositoryType]string{accountCustomizationsRepository: "platform-account-customizations"}
+				})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(repo.Type).To(gomega.Equal(accountCustomizationsRepository))
+				gomega.Expect(repo.Customization).To(gomega.Equal("sandbox"))
+				gomega.Expect(repo.stateKey("111111111111")).To(gomega.Equal("111111111111-aft-account-customizations/sandbox/terraform.tfstate"))
+			})
+		})
+
+		ginkgo.When("the account customizations are run from the repository root", func() {
+			ginkgo.It("should require the customization flag", func() {
+				root := newRepository("https://git-codecommit.us-east-1.amazonaws.com/v1/repos/aft-account-customizations")
+
+				_, err := detectRepository(root, "", defaultNames)
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("--customization")))
+
+				repo, err := detectRepository(root, "production", defaultNames)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(repo.Customization).To(gomega.Equal("production"))
+			})
+		})
+
+		ginkgo.When("the marker file names the repository type", func() {
+			ginkgo.It("should not read the remote", func() {
+				root := newRepository("https://example.com/unrelated.git", "terraform")
+				gomega.Expect(os.WriteFile(filepath.Join(root, markerFile), []byte("aft-account-provisioning-customizations\n"), 0644)).To(gomega.Succeed())
+
+				repo, err := detectRepository(filepath.Join(root, "terraform"), "", func() map[repositoryType]string {
+					ginkgo.Fail("the repository names should not be resolved")
+					return nil
+				})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(repo.Type).To(gomega.Equal(accountProvisioningCustomizationsRepository))
+			})
+		})
+
+		ginkgo.When("the marker file holds an unknown type", func() {
+			ginkgo.It("should return an error", func() {
+				root := newRepository("codecommit://aft-global-customizations")
+				gomega.Expect(os.WriteFile(filepath.Join(root, markerFile), []byte("customizations"), 0644)).To(gomega.Succeed())
+
+				_, err := detectRepository(root, "", defaultNames)
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("accepted repository types")))
+			})
+		})
+
+		ginkgo.When("the remote doesn't match any AFT repository", func() {
+			ginkgo.It("should return an error", func() {
+				root := newRepository("https://example.com/unrelated.git")
+
+				_, err := detectRepository(root, "", defaultNames)
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("doesn't match an AFT repository")))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the remoteRepositoryName function", func() {
+		ginkgo.It("should read the name of every remote format", func() {
+			for url, name := range map[string]string{
+				"codecommit::us-east-1://aft-account-request":                                 "aft-account-request",
+				"codecommit::us-east-1://admin@aft-account-request":                           "aft-account-request",
+				"https://git-codecommit.us-east-1.amazonaws.com/v1/repos/aft-account-request": "aft-account-request",
+				"git@github.com:acme/aft-account-request.git":                                 "aft-account-request",
+				"https://github.com/acme/aft-account-request/":                                "aft-account-request",
+			} {
+				gomega.Expect(remoteRepositoryName(url)).To(gomega.Equal(name), url)
+			}
+		})
+	})
+
+	ginkgo.Context("testing the getRepositoryNames function", func() {
+		ginkgo.It("should keep the default name of the missing parameters", func() {
+			names := getRepositoryNames(map[string]string{
+				repositoryNameParameters[accountRequestRepository]:       "requests",
+				repositoryNameParameters[globalCustomizationsRepository]: "global",
+				tfDistribution: "oss",
+			})
+			gomega.Expect(names).To(gomega.Equal(map[repositoryType]string{
+				accountRequestRepository:       "requests",
+				globalCustomizationsRepository: "global",
+			}))
+		})
+	})
+})
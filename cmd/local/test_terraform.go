@@ -0,0 +1,96 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// fakeTerraform replaces the Terraform binary by the given shell script for the spec
+func fakeTerraform(script string) {
+	path := filepath.Join(ginkgo.GinkgoT().TempDir(), "terraform")
+	gomega.Expect(os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755)).To(gomega.Succeed())
+
+	previous := terraformBinary
+	terraformBinary = path
+	ginkgo.DeferCleanup(func() {
+		terraformBinary = previous
+	})
+}
+
+var _ = ginkgo.Describe("Running Terraform", func() {
+
+	ginkgo.Context("testing the runTerraform function", func() {
+		ginkgo.When("Terraform succeeds", func() {
+			ginkgo.It("should wire the standard streams and the environment", func() {
+				fakeTerraform(`read answer; echo "$1 $answer $AWS_ACCESS_KEY_ID"; echo warning >&2`)
+
+				stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+				err := runTerraform("", []string{"apply"}, []string{"AWS_ACCESS_KEY_ID=key"}, terraformStreams{
+					stdin:  strings.NewReader("yes\n"),
+					stdout: stdout,
+					stderr: stderr,
+				})
+
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(stdout.String()).To(gomega.Equal("apply yes key\n"))
+				gomega.Expect(stderr.String()).To(gomega.Equal("warning\n"))
+			})
+		})
+
+		ginkgo.When("Terraform fails", func() {
+			ginkgo.It("should return its exit code", func() {
+				fakeTerraform("exit 2")
+
+				err := runTerraform("", []string{"plan", "-detailed-exitcode"}, nil, terraformStreams{
+					stdout: &bytes.Buffer{},
+					stderr: &bytes.Buffer{},
+				})
+
+				gomega.Expect(err).To(gomega.Equal(&terraformExitError{code: 2}))
+				gomega.Expect(err).To(gomega.MatchError("terraform exited with code 2"))
+			})
+		})
+
+		ginkgo.When("Terraform can't be started", func() {
+			ginkgo.It("should return an error", func() {
+				previous := terraformBinary
+				terraformBinary = filepath.Join(ginkgo.GinkgoT().TempDir(), "missing")
+				defer func() { terraformBinary = previous }()
+
+				err := runTerraform("", nil, nil, terraformStreams{stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}})
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("error starting terraform")))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the teeStreams function", func() {
+		ginkgo.When("a log file is given", func() {
+			ginkgo.It("should also write the output to the file", func() {
+				fakeTerraform("echo out; echo err >&2")
+				logFile := filepath.Join(ginkgo.GinkgoT().TempDir(), "terraform.log")
+
+				stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+				streams, closeLog, err := teeStreams(terraformStreams{stdout: stdout, stderr: stderr}, logFile)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				gomega.Expect(runTerraform("", nil, nil, streams)).To(gomega.Succeed())
+				closeLog()
+
+				content, err := os.ReadFile(logFile)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(string(content)).To(gomega.ContainSubstring("out\n"))
+				gomega.Expect(string(content)).To(gomega.ContainSubstring("err\n"))
+				gomega.Expect(stdout.String()).To(gomega.Equal("out\n"))
+			})
+		})
+	})
+})
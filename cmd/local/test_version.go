@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"os"
+	"path/filepath"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// fakeBinary writes a shell script printing the version json under the name, returning its path
+func fakeBinary(name string, version string) string {
+	path := filepath.Join(ginkgo.GinkgoT().TempDir(), name)
+	script := `#!/bin/sh
+test "$1 $2" = "version -json" || exit 1
+echo '{"terraform_version": "` + version + `", "platform": "linux_amd64", "terraform_outdated": false}'`
+	gomega.Expect(os.WriteFile(path, []byte(script), 0755)).To(gomega.Succeed())
+
+	return path
+}
+
+var _ = ginkgo.Describe("Checking the Terraform binary", func() {
+	aftParams := map[string]string{tfVersion: "1.5.7", tfDistribution: "oss"}
+
+	ginkgo.Context("testing the checkTerraformVersion function", func() {
+		ginkgo.When("the binary runs the version of the AFT pipeline", func() {
+			ginkgo.It("should succeed", func() {
+				binary := fakeBinary("terraform", "1.5.7")
+				gomega.Expect(checkTerraformVersion(binary, aftParams, true)).To(gomega.Succeed())
+
+				// older AFT deployments don't record the version
+				gomega.Expect(checkTerraformVersion(fakeBinary("terraform", "1.6.0"), map[string]string{}, true)).To(gomega.Succeed())
+			})
+		})
+
+		ginkgo.When("the binary runs another version", func() {
+			ginkgo.It("should only fail with strict", func() {
+				binary := fakeBinary("terraform", "1.6.0")
+				gomega.Expect(checkTerraformVersion(binary, aftParams, false)).To(gomega.Succeed())
+
+				err := checkTerraformVersion(binary, aftParams, true)
+				gomega.Expect(err).To(gomega.MatchError(gomega.HavePrefix("Terraform 1.6.0 is installed but the AFT pipeline runs Terraform 1.5.7")))
+			})
+		})
+
+		ginkgo.When("the binary is OpenTofu", func() {
+			ginkgo.It("should report the distribution", func() {
+				binary := fakeBinary("tofu", "1.5.7")
+
+				installed, err := readTerraformVersion(binary)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(installed).To(gomega.Equal(installedTerraform{Version: "1.5.7", OpenTofu: true}))
+
+				err = checkTerraformVersion(binary, aftParams, true)
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("is OpenTofu but the AFT pipeline runs the oss distribution")))
+			})
+		})
+
+		ginkgo.When("the binary doesn't print a version", func() {
+			ginkgo.It("should return an error", func() {
+				fakeTerraform("echo Terraform v0.12.31")
+
+				_, err := readTerraformVersion(terraformBinary)
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("Terraform 0.13 or later is required")))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the resolveTerraformBinary function", func() {
+		ginkgo.When("terraform isn't in the PATH", func() {
+			ginkgo.It("should fall back to tofu", func() {
+				tofu := fakeBinary("tofu", "1.6.0")
+				ginkgo.GinkgoT().Setenv("PATH", filepath.Dir(tofu))
+
+				path, err := resolveTerraformBinary("")
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(path).To(gomega.Equal(tofu))
+
+				_, err = resolveTerraformBinary("terraform")
+				gomega.Expect(err).To(gomega.MatchError(gomega.HavePrefix("the Terraform binary terraform can't be found")))
+
+				ginkgo.GinkgoT().Setenv("PATH", ginkgo.GinkgoT().TempDir())
+				_, err = resolveTerraformBinary("")
+				gomega.Expect(err).To(gomega.MatchError(gomega.HavePrefix("neither terraform nor tofu is in the PATH")))
+			})
+		})
+	})
+})
@@ -0,0 +1,117 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+)
+
+// tfVersion is the Terraform version the AFT pipeline runs
+const tfVersion = "/aft/config/terraform/version"
+
+// terraformExecutables are looked up in the PATH in this order when --terraform-bin isn't given
+var terraformExecutables = []string{"terraform", "tofu"}
+
+// installedTerraform is the Terraform binary aftctl local runs
+type installedTerraform struct {
+	// Version is the version of terraform version -json, OpenTofu also names it terraform_version.
+	Version string `json:"terraform_version"`
+
+	// OpenTofu is set when the binary is OpenTofu rather than Terraform.
+	OpenTofu bool `json:"-"`
+}
+
+// product returns the name of the distribution of the binary
+func (t installedTerraform) product() string {
+	if t.OpenTofu {
+		return "OpenTofu"
+	}
+
+	return "Terraform"
+}
+
+// resolveTerraformBinary returns the path of the binary given with --terraform-bin, or else of terraform or tofu
+func resolveTerraformBinary(binary string) (string, error) {
+	if binary != "" {
+		path, err := exec.LookPath(binary)
+		if err != nil {
+			return "", fmt.Errorf("the Terraform binary %s can't be found: %v", binary, err)
+		}
+		return path, nil
+	}
+
+	for _, name := range terraformExecutables {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("neither %s is in the PATH, give the Terraform binary with --terraform-bin",
+		strings.Join(terraformExecutables, " nor "))
+}
+
+// readTerraformVersion runs terraform version -json, OpenTofu is told by its executable name as it prints the
+// same output
+func readTerraformVersion(binary string) (installedTerraform, error) {
+	content, err := exec.Command(binary, "version", "-json").Output()
+	if err != nil {
+		return installedTerraform{}, fmt.Errorf("error running %s version -json: %v", binary, err)
+	}
+
+	var installed installedTerraform
+	if err := json.Unmarshal(content, &installed); err != nil || installed.Version == "" {
+		return installedTerraform{}, fmt.Errorf("error reading the version of %s, Terraform 0.13 or later is required", binary)
+	}
+
+	installed.OpenTofu = strings.HasPrefix(filepath.Base(binary), "tofu")
+
+	return installed, nil
+}
+
+// checkTerraformVersion compares the binary with the Terraform version and distribution of the AFT pipeline, the
+// differences are logged as warnings or returned as an error with strict
+func checkTerraformVersion(binary string, aftParams map[string]string, strict bool) error {
+	installed, err := readTerraformVersion(binary)
+	if err != nil {
+		return err
+	}
+
+	var differences []string
+
+	expected := strings.TrimPrefix(aftParams[tfVersion], "v")
+	if expected == "" {
+		logging.Debugf("the AFT deployment doesn't record its Terraform version in %s", tfVersion)
+	} else if strings.TrimPrefix(installed.Version, "v") != expected {
+		differences = append(differences, fmt.Sprintf("%s %s is installed but the AFT pipeline runs Terraform %s",
+			installed.product(), installed.Version, expected))
+	}
+
+	if installed.OpenTofu {
+		differences = append(differences, fmt.Sprintf("%s is OpenTofu but the AFT pipeline runs the %s distribution of Terraform",
+			binary, aftParams[tfDistribution]))
+	}
+
+	if len(differences) == 0 {
+		logging.Debugf("%s %s matches the AFT pipeline", installed.product(), installed.Version)
+		return nil
+	}
+
+	if strict {
+		return errors.New(strings.Join(differences, ", ") + ", give the matching binary with --terraform-bin")
+	}
+
+	for _, difference := range differences {
+		logging.Warnf("%s", difference)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package validate provides the validate command linting the deployment manifest
+package validate
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/edgarsilva948/aftctl/pkg/manifest"
+	"github.com/edgarsilva948/aftctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var args struct {
+	file   string
+	schema bool
+}
+
+// Cmd is the exported command validating the deployment manifest.
+var Cmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a deployment manifest",
+	Long: "Validate the types, required fields, account IDs, regions and resource names of a deployment manifest, " +
+		"every problem is reported at once with its line. No AWS credentials are needed.\n" +
+		"With --schema the JSON Schema of the manifest is written instead, for the editors.",
+	Example: `  aftctl validate -f deployment.yaml
+
+  cat deployment.yaml | aftctl validate -f -
+
+  aftctl validate --schema > deployment.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: run,
+}
+
+func init() {
+	flags := Cmd.Flags()
+	flags.SortFlags = false
+
+	flags.StringVarP(
+		&args.file,
+		"file",
+		"f",
+		manifest.DefaultFileName,
+		"Path of the deployment manifest, - reads the standard input",
+	)
+
+	flags.BoolVar(
+		&args.schema,
+		"schema",
+		false,
+		"Write the JSON Schema of the deployment manifest instead of validating it",
+	)
+}
+
+func run(cmd *cobra.Command, _ []string) error {
+	if args.schema {
+		schema, err := manifest.Schema()
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+		return err
+	}
+
+	if err := output.CheckFormat(); err != nil {
+		return err
+	}
+
+	content, err := readManifest(cmd.InOrStdin(), args.file)
+	if err != nil {
+		return err
+	}
+
+	result := &Result{
+		File:     args.file,
+		Problems: manifest.Validate(content),
+	}
+	result.Valid = len(result.Problems) == 0
+
+	if err := output.Print(cmd.OutOrStdout(), result); err != nil {
+		return err
+	}
+
+	if !result.Valid {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s has %d problem(s)", args.file, len(result.Problems))
+	}
+
+	return nil
+}
+
+// readManifest reads the manifest file, or the standard input for -
+func readManifest(stdin io.Reader, path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the deployment manifest: %w", err)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,36 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package validate
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/edgarsilva948/aftctl/pkg/manifest"
+	"github.com/edgarsilva948/aftctl/pkg/output"
+)
+
+// Result is the outcome of the validate command.
+type Result struct {
+	File     string             `json:"file" yaml:"file"`
+	Valid    bool               `json:"valid" yaml:"valid"`
+	Problems []manifest.Problem `json:"problems" yaml:"problems"`
+}
+
+// PrintTable writes one row per problem.
+func (r *Result) PrintTable(w io.Writer) error {
+	if r.Valid {
+		_, err := fmt.Fprintf(w, "%s is valid\n", r.File)
+		return err
+	}
+
+	rows := make([][]string, 0, len(r.Problems))
+	for _, problem := range r.Problems {
+		rows = append(rows, []string{r.File + ":" + strconv.Itoa(problem.Line), problem.Field, problem.Message})
+	}
+
+	return output.Table(w, []string{"LINE", "FIELD", "PROBLEM"}, rows)
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/edgarsilva948/aftctl/pkg/info"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+	"github.com/edgarsilva948/aftctl/pkg/output"
 )
 
 // Cmd represents the Cobra command for the version functionality.
@@ -22,5 +24,7 @@ var Cmd = &cobra.Command{
 
 // Run executes the version command, printing the version of the tool.
 func Run(cmd *cobra.Command, argv []string) {
-	info.PrintVersion(os.Stdout)
+	if err := output.Print(os.Stdout, info.BuildCurrentVersion()); err != nil {
+		logging.Fatalf("%v", err)
+	}
 }
@@ -0,0 +1,147 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/organizations"
+)
+
+// Account is an active account of the organization.
+type Account struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+
+	// OUPath is the path of the organizational unit holding the account, e.g. Root/Workloads/Sandbox.
+	OUPath string            `json:"ouPath"`
+	Tags   map[string]string `json:"tags,omitempty"`
+
+	// CustomizationsName is the account_customizations_name of the account request, empty outside AFT.
+	CustomizationsName string `json:"customizationsName,omitempty"`
+}
+
+// ListOrganizationAccounts returns the active accounts of the organization with their OU path and tags,
+// walking the organizational units from the root.
+func ListOrganizationAccounts(client OrganizationsClient) ([]Account, error) {
+	if client == nil {
+		return nil, errors.New("client is nil")
+	}
+
+	roots, err := client.ListRoots(&organizations.ListRootsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []Account
+	for _, root := range roots.Roots {
+		found, err := listAccountsUnder(client, aws.StringValue(root.Id), aws.StringValue(root.Name))
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, found...)
+	}
+
+	for i := range accounts {
+		accounts[i].Tags = map[string]string{}
+
+		err := client.ListTagsForResourcePages(&organizations.ListTagsForResourceInput{
+			ResourceId: aws.String(accounts[i].ID),
+		}, func(page *organizations.ListTagsForResourceOutput, lastPage bool) bool {
+			for _, tag := range page.Tags {
+				accounts[i].Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+			return true
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return accounts, nil
+}
+
+// listAccountsUnder returns the active accounts of the parent and of its organizational units
+func listAccountsUnder(client OrganizationsClient, parentID string, path string) ([]Account, error) {
+	var accounts []Account
+
+	err := client.ListAccountsForParentPages(&organizations.ListAccountsForParentInput{
+		ParentId: aws.String(parentID),
+	}, func(page *organizations.ListAccountsForParentOutput, lastPage bool) bool {
+		for _, account := range page.Accounts {
+			if aws.StringValue(account.Status) != organizations.AccountStatusActive {
+				continue
+			}
+			accounts = append(accounts, Account{
+				ID:     aws.StringValue(account.Id),
+				Name:   aws.StringValue(account.Name),
+				Email:  aws.StringValue(account.Email),
+				OUPath: path,
+			})
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var units []*organizations.OrganizationalUnit
+
+	err = client.ListOrganizationalUnitsForParentPages(&organizations.ListOrganizationalUnitsForParentInput{
+		ParentId: aws.String(parentID),
+	}, func(page *organizations.ListOrganizationalUnitsForParentOutput, lastPage bool) bool {
+		units = append(units, page.OrganizationalUnits...)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, unit := range units {
+		found, err := listAccountsUnder(client, aws.StringValue(unit.Id), path+"/"+aws.StringValue(unit.Name))
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, found...)
+	}
+
+	return accounts, nil
+}
+
+// GetAccountCustomizationsNames returns the account_customizations_name of the accounts recorded in the AFT
+// request metadata table, by account ID.
+func GetAccountCustomizationsNames(client DynamoDBClient, table string) (map[string]string, error) {
+	if client == nil {
+		return nil, errors.New("client is nil")
+	}
+
+	names := map[string]string{}
+
+	err := client.ScanPages(&dynamodb.ScanInput{
+		TableName:            aws.String(table),
+		ProjectionExpression: aws.String("id, account_customizations_name"),
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			id, name := item["id"], item["account_customizations_name"]
+			if id == nil || name == nil {
+				continue
+			}
+			names[aws.StringValue(id.S)] = aws.StringValue(name.S)
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
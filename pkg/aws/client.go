@@ -6,10 +6,9 @@ Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 package aws
 
 import (
-	"fmt"
 	"os"
 
-	"github.com/caarlos0/log"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -21,10 +20,18 @@ import (
 	"github.com/aws/aws-sdk-go/service/codecommit/codecommitiface"
 	"github.com/aws/aws-sdk-go/service/codepipeline"
 	"github.com/aws/aws-sdk-go/service/codepipeline/codepipelineiface"
+	"github.com/aws/aws-sdk-go/service/codestarnotifications"
+	"github.com/aws/aws-sdk-go/service/codestarnotifications/codestarnotificationsiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/organizations/organizationsiface"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/aws/aws-sdk-go/service/sts"
@@ -33,7 +40,7 @@ import (
 
 // S3Client represents a client for Amazon S3.
 type S3Client interface {
-	ListBuckets(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error)
+	HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error)
 	CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error)
 	WaitUntilBucketExists(*s3.HeadBucketInput) error
 	PutPublicAccessBlock(*s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error)
@@ -53,6 +60,8 @@ type CodeCommitClient interface {
 type CodeBuildClient interface {
 	CreateProject(*codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error)
 	ListProjects(*codebuild.ListProjectsInput) (*codebuild.ListProjectsOutput, error)
+	BatchGetProjects(*codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error)
+	UpdateProject(*codebuild.UpdateProjectInput) (*codebuild.UpdateProjectOutput, error)
 }
 
 // IAMClient represents a client for Amazon Code Commit.
@@ -60,32 +69,67 @@ type IAMClient interface {
 	CreateRole(*iam.CreateRoleInput) (*iam.CreateRoleOutput, error)
 	PutRolePolicy(*iam.PutRolePolicyInput) (*iam.PutRolePolicyOutput, error)
 	GetRole(*iam.GetRoleInput) (*iam.GetRoleOutput, error)
+	AttachRolePolicy(*iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error)
+	UpdateRole(*iam.UpdateRoleInput) (*iam.UpdateRoleOutput, error)
+	PutRolePermissionsBoundary(*iam.PutRolePermissionsBoundaryInput) (*iam.PutRolePermissionsBoundaryOutput, error)
 }
 
 // CodePipelineClient represents a client for Amazon Code Pipeline.
 type CodePipelineClient interface {
 	CreatePipeline(*codepipeline.CreatePipelineInput) (*codepipeline.CreatePipelineOutput, error)
 	ListPipelines(input *codepipeline.ListPipelinesInput) (*codepipeline.ListPipelinesOutput, error)
+	GetPipeline(input *codepipeline.GetPipelineInput) (*codepipeline.GetPipelineOutput, error)
 }
 
 // CloudformationClient represents a client for Cloudformation.
 type CloudformationClient interface {
 	CreateStack(*cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error)
 	DescribeStacks(*cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
+	DescribeStackSet(*cloudformation.DescribeStackSetInput) (*cloudformation.DescribeStackSetOutput, error)
+}
+
+// SNSClient represents a client for SNS.
+type SNSClient interface {
+	CreateTopic(*sns.CreateTopicInput) (*sns.CreateTopicOutput, error)
+	SetTopicAttributes(*sns.SetTopicAttributesInput) (*sns.SetTopicAttributesOutput, error)
+	Subscribe(*sns.SubscribeInput) (*sns.SubscribeOutput, error)
+}
+
+// CodeStarNotificationsClient represents a client for CodeStar Notifications.
+type CodeStarNotificationsClient interface {
+	CreateNotificationRule(*codestarnotifications.CreateNotificationRuleInput) (*codestarnotifications.CreateNotificationRuleOutput, error)
 }
 
 // SSMClient represents a client for SSM.
 type SSMClient interface {
 	GetParameter(*ssm.GetParameterInput) (*ssm.GetParameterOutput, error)
+	GetParametersByPathPages(*ssm.GetParametersByPathInput, func(*ssm.GetParametersByPathOutput, bool) bool) error
 }
 
 // STSClient represents a client for STS.
 type STSClient interface {
 	AssumeRole(*sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error)
+	GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
+}
+
+// OrganizationsClient represents a client for Organizations.
+type OrganizationsClient interface {
+	DescribeOrganization(*organizations.DescribeOrganizationInput) (*organizations.DescribeOrganizationOutput, error)
+	ListAccountsPages(*organizations.ListAccountsInput, func(*organizations.ListAccountsOutput, bool) bool) error
+	ListRoots(*organizations.ListRootsInput) (*organizations.ListRootsOutput, error)
+	ListOrganizationalUnitsForParentPages(*organizations.ListOrganizationalUnitsForParentInput, func(*organizations.ListOrganizationalUnitsForParentOutput, bool) bool) error
+	ListAccountsForParentPages(*organizations.ListAccountsForParentInput, func(*organizations.ListAccountsForParentOutput, bool) bool) error
+	ListTagsForResourcePages(*organizations.ListTagsForResourceInput, func(*organizations.ListTagsForResourceOutput, bool) bool) error
+}
+
+// DynamoDBClient represents a client for DynamoDB.
+type DynamoDBClient interface {
+	ScanPages(*dynamodb.ScanInput, func(*dynamodb.ScanOutput, bool) bool) error
 }
 
 // Client struct implementing all the client interfaces
 type Client struct {
+	region               string
 	s3Client             s3iface.S3API
 	iamClient            iamiface.IAMAPI
 	codepipelineClient   codepipelineiface.CodePipelineAPI
@@ -94,61 +138,82 @@ type Client struct {
 	cloudformationClient cloudformationiface.CloudFormationAPI
 	ssmClient            ssmiface.SSMAPI
 	stsClient            stsiface.STSAPI
+	snsClient            snsiface.SNSAPI
+	notificationsClient  codestarnotificationsiface.CodeStarNotificationsAPI
+	organizationsClient  organizationsiface.OrganizationsAPI
+	dynamodbClient       dynamodbiface.DynamoDBAPI
+}
+
+// ClientOptions selects the credentials, region and endpoint of the AWS clients.
+type ClientOptions struct {
+	// Profile is the shared config profile, empty follows the default credentials chain.
+	Profile string
+
+	// Region overrides the region of the profile.
+	Region string
+
+	// EndpointURL sends every request to the given endpoint, e.g. a local AWS emulator.
+	EndpointURL string
 }
 
 // NewClient loads credentials following the chain credentials
-func NewClient(profile string) *Client {
+func NewClient(options ClientOptions) *Client {
 
 	opts := session.Options{
-		Profile:           profile,
+		Profile:           options.Profile,
 		SharedConfigState: session.SharedConfigEnable,
 	}
 
+	if options.Region != "" {
+		opts.Config.Region = aws.String(options.Region)
+	}
+
+	// Emulators serve every service from one host, so the buckets are addressed by path
+	if options.EndpointURL != "" {
+		opts.Config.Endpoint = aws.String(options.EndpointURL)
+		opts.Config.S3ForcePathStyle = aws.Bool(true)
+	}
+
 	sess, err := session.NewSessionWithOptions(opts)
 
 	// Check for session initialization error
 	if err != nil {
-		fmt.Println("error creating session:", err)
-		os.Exit(1)
+		logging.Fatalf("error creating session: %v", err)
 	}
 
 	// Check for a nil session
 	if sess == nil {
-		log.Fatal("session is nil")
-		os.Exit(1)
+		logging.Fatalf("session is nil")
 	}
 
 	// Check for a nil Config
 	if sess.Config == nil {
-		log.Fatal("invalid session configuration")
-		os.Exit(1)
+		logging.Fatalf("invalid session configuration")
 	}
 
 	// Check for nil Credentials
 	if sess.Config.Credentials == nil {
-		log.Fatal("invalid session credentials")
-		os.Exit(1)
+		logging.Fatalf("invalid session credentials")
 	}
 
 	// Check for credential errors
 	_, errCreds := sess.Config.Credentials.Get()
 	if errCreds != nil {
-		fmt.Println("credential error:", errCreds)
-		os.Exit(1)
+		logging.Fatalf("credential error: %v", errCreds)
 	}
 
 	// Check for an unset AWS region
 	if aws.StringValue(sess.Config.Region) == "" {
-		fmt.Println("region is not set.")
-		os.Exit(1)
+		logging.Fatalf("region is not set.")
 	}
 
 	// Check for an unset aws default profile and a profile set in the environment variable
-	if awsProfile := os.Getenv("AWS_PROFILE"); awsProfile != "" && profile == "" {
-		log.WithField("profile", awsProfile).Info("using AWS_PROFILE environment variable")
+	if awsProfile := os.Getenv("AWS_PROFILE"); awsProfile != "" && options.Profile == "" {
+		logging.Infow("using AWS_PROFILE environment variable", "profile", awsProfile)
 	}
 
 	return &Client{
+		region:               aws.StringValue(sess.Config.Region),
 		s3Client:             s3.New(sess),
 		iamClient:            iam.New(sess),
 		codepipelineClient:   codepipeline.New(sess),
@@ -157,9 +222,18 @@ func NewClient(profile string) *Client {
 		cloudformationClient: cloudformation.New(sess),
 		ssmClient:            ssm.New(sess),
 		stsClient:            sts.New(sess),
+		snsClient:            sns.New(sess),
+		notificationsClient:  codestarnotifications.New(sess),
+		organizationsClient:  organizations.New(sess),
+		dynamodbClient:       dynamodb.New(sess),
 	}
 }
 
+// GetRegion returns the region the clients were created for
+func (ac *Client) GetRegion() string {
+	return ac.region
+}
+
 // GetS3Client fetches the S3 Client and enables the cmd to use
 func (ac *Client) GetS3Client() s3iface.S3API {
 	return ac.s3Client
@@ -200,24 +274,22 @@ func (ac *Client) GetSTSClient() stsiface.STSAPI {
 	return ac.stsClient
 }
 
-// GetAWSCredentials returns the AWS credentials for the given profile.
-func GetAWSCredentials(profile string) (string, string, string, error) {
-
-	opts := session.Options{
-		Profile: profile,
-	}
-
-	sess, err := session.NewSessionWithOptions(opts)
+// GetSNSClient returns the client for AWS SNS service.
+func (ac *Client) GetSNSClient() snsiface.SNSAPI {
+	return ac.snsClient
+}
 
-	if err != nil {
-		return "", "", "", err
-	}
+// GetCodeStarNotificationsClient returns the client for AWS CodeStar Notifications service.
+func (ac *Client) GetCodeStarNotificationsClient() codestarnotificationsiface.CodeStarNotificationsAPI {
+	return ac.notificationsClient
+}
 
-	creds := sess.Config.Credentials
-	credValue, err := creds.Get()
-	if err != nil {
-		return "", "", "", err
-	}
+// GetOrganizationsClient returns the client for AWS Organizations service.
+func (ac *Client) GetOrganizationsClient() organizationsiface.OrganizationsAPI {
+	return ac.organizationsClient
+}
 
-	return credValue.AccessKeyID, credValue.SecretAccessKey, credValue.SessionToken, nil
+// GetDynamoDBClient returns the client for AWS DynamoDB service.
+func (ac *Client) GetDynamoDBClient() dynamodbiface.DynamoDBAPI {
+	return ac.dynamodbClient
 }
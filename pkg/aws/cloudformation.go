@@ -7,7 +7,6 @@ package aws
 import (
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -24,7 +23,7 @@ func EnsureCloudformationExists(client CloudformationClient, stackName string, r
 	_, err := checkIfCloudformationClientIsProvided(client)
 
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -69,13 +68,13 @@ func checkIfCloudformationClientIsProvided(client CloudformationClient) (bool, e
 // func to verify if the given stack name is provided
 func checkIfStackNameIsProvided(stackName string) (bool, error) {
 	if stackName == "" {
-		fmt.Printf("Error: %v\n", "stack name is not provided")
+		logging.Errorf("stack name is not provided")
 		return false, fmt.Errorf("stack name is not provided")
 	}
 
 	isStackNameValid, err := checkStackNameCompliance(stackName)
 	if !isStackNameValid {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -152,7 +151,7 @@ Resources:
 
 	_, err := client.CreateStack(input)
 	if err != nil {
-		log.Fatalf("Error creating CloudFormation stack: %v", err)
+		logging.Fatalf("Error creating CloudFormation stack: %v", err)
 	}
 
 	message := fmt.Sprintf("Cloudformation stack %s successfully created", stackName)
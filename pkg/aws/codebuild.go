@@ -7,27 +7,31 @@ package aws
 import (
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/codebuild"
 	"github.com/edgarsilva948/aftctl/pkg/aws/tags"
 	"github.com/edgarsilva948/aftctl/pkg/logging"
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
 const buildIcon = "🛠️ "
 
-// EnsureCodeBuildProjectExists creates a new codebuild project with the given name, or returns success if it already exists.
-func EnsureCodeBuildProjectExists(client CodeBuildClient, aftManagementAccountID string, codeBuildDockerImage string, projectName string, repoName string, repoBranch string, codeBuildRoleName string) (bool, error) {
+// EnsureCodeBuildProjectExists creates a new codebuild project with the given name, or updates it to the given
+// configuration if it already exists.
+func EnsureCodeBuildProjectExists(client CodeBuildClient, aftManagementAccountID string, projectName string, repoName string, repoBranch string, codeBuildRoleName string, codeBuildRolePath string, projectConfig CodeBuildProjectConfig) (bool, error) {
 
 	_, err := checkIfCodeBuildClientIsProvided(client)
 
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
+		return false, err
+	}
+
+	err = projectConfig.Validate()
+
+	if err != nil {
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -37,14 +41,19 @@ func EnsureCodeBuildProjectExists(client CodeBuildClient, aftManagementAccountID
 		return false, err
 	}
 
-	projectExists, _ := projectExists(client, projectName)
+	projectExists, err := projectExists(client, projectName)
+
+	if err != nil {
+		logging.Errorf("%v", err)
+		return false, err
+	}
 
 	if !projectExists {
 
 		message := fmt.Sprintf("CodeBuild project %s doesn't exists... creating", projectName)
 		logging.CustomLog(buildIcon, "yellow", message)
 
-		_, err := createCodeBuildProject(client, aftManagementAccountID, codeBuildDockerImage, projectName, repoName, repoBranch, codeBuildRoleName)
+		_, err := createCodeBuildProject(client, aftManagementAccountID, projectName, repoName, repoBranch, codeBuildRoleName, codeBuildRolePath, projectConfig)
 
 		if err != nil {
 			return false, err
@@ -53,33 +62,31 @@ func EnsureCodeBuildProjectExists(client CodeBuildClient, aftManagementAccountID
 		return true, nil
 	}
 
-	config := zap.NewDevelopmentConfig()
-	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	config.DisableCaller = true
-	config.EncoderConfig.EncodeTime = zapcore.TimeEncoder(func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-		enc.AppendString(t.Format("02/01/2006 15:04:01"))
-	})
-
-	logger, _ := config.Build()
-
-	defer logger.Sync()
-
 	message := fmt.Sprintf("CodeBuild Project %s already exists", projectName)
 	logging.CustomLog(buildIcon, "blue", message)
 
+	_, err = updateCodeBuildProject(client, aftManagementAccountID, projectName, repoName, repoBranch, codeBuildRoleName, codeBuildRolePath, projectConfig)
+
+	if err != nil {
+		return false, err
+	}
+
 	return true, nil
 }
 
+// func to verify if the given project name already exists
 func checkIfProjectExists(client CodeBuildClient, projectName string) (bool, error) {
-	input := &codebuild.ListProjectsInput{}
+	input := &codebuild.BatchGetProjectsInput{
+		Names: []*string{aws.String(projectName)},
+	}
 
-	result, err := client.ListProjects(input)
+	result, err := client.BatchGetProjects(input)
 	if err != nil {
 		return false, err
 	}
 
 	for _, existingProject := range result.Projects {
-		if *existingProject == projectName {
+		if aws.StringValue(existingProject.Name) == projectName {
 			return true, nil
 		}
 	}
@@ -88,9 +95,60 @@ func checkIfProjectExists(client CodeBuildClient, projectName string) (bool, err
 }
 
 // func to create the AFT codebuild project if it doesn't exist'
-func createCodeBuildProject(client CodeBuildClient, aftManagementAccountID string, codeBuildDockerImage string, projectName string, repoName string, repoBranch string, codeBuildRoleName string) (bool, error) {
+func createCodeBuildProject(client CodeBuildClient, aftManagementAccountID string, projectName string, repoName string, repoBranch string, codeBuildRoleName string, codeBuildRolePath string, config CodeBuildProjectConfig) (bool, error) {
+
+	codeBuildRoleArn := RoleArn(aftManagementAccountID, codeBuildRolePath, codeBuildRoleName)
+
+	input := newCodeBuildProjectInput(projectName, repoName, repoBranch, codeBuildRoleArn, config)
+
+	_, err := client.CreateProject(input)
 
-	codeBuildRoleArn := "arn:aws:iam::" + aftManagementAccountID + ":role/" + codeBuildRoleName
+	if err != nil {
+		logging.Errorf("unable to create project %q, %v", projectName, err)
+		return false, err
+	}
+
+	message := fmt.Sprintf("CodeBuild Project %s successfully created", projectName)
+	logging.CustomLog(buildIcon, "green", message)
+
+	return true, nil
+}
+
+// func to update the existing AFT codebuild project to the given configuration, its tags are kept
+func updateCodeBuildProject(client CodeBuildClient, aftManagementAccountID string, projectName string, repoName string, repoBranch string, codeBuildRoleName string, codeBuildRolePath string, config CodeBuildProjectConfig) (bool, error) {
+
+	codeBuildRoleArn := RoleArn(aftManagementAccountID, codeBuildRolePath, codeBuildRoleName)
+
+	project := newCodeBuildProjectInput(projectName, repoName, repoBranch, codeBuildRoleArn, config)
+
+	input := &codebuild.UpdateProjectInput{
+		Name:                   project.Name,
+		Artifacts:              project.Artifacts,
+		Source:                 project.Source,
+		Environment:            project.Environment,
+		LogsConfig:             project.LogsConfig,
+		VpcConfig:              project.VpcConfig,
+		Cache:                  project.Cache,
+		ServiceRole:            project.ServiceRole,
+		TimeoutInMinutes:       project.TimeoutInMinutes,
+		QueuedTimeoutInMinutes: project.QueuedTimeoutInMinutes,
+	}
+
+	_, err := client.UpdateProject(input)
+
+	if err != nil {
+		logging.Errorf("unable to update project %q, %v", projectName, err)
+		return false, err
+	}
+
+	message := fmt.Sprintf("CodeBuild Project %s updated", projectName)
+	logging.CustomLog(buildIcon, "green", message)
+
+	return true, nil
+}
+
+// newCodeBuildProjectInput builds the AFT codebuild project from its configuration
+func newCodeBuildProjectInput(projectName string, repoName string, repoBranch string, codeBuildRoleArn string, config CodeBuildProjectConfig) *codebuild.CreateProjectInput {
 
 	input := &codebuild.CreateProjectInput{
 		Tags: []*codebuild.Tag{
@@ -107,34 +165,27 @@ func createCodeBuildProject(client CodeBuildClient, aftManagementAccountID strin
 			Type: aws.String("CODEPIPELINE"),
 		},
 		Environment: &codebuild.ProjectEnvironment{
-			ComputeType:    aws.String("BUILD_GENERAL1_SMALL"),
-			Type:           aws.String("LINUX_CONTAINER"),
-			Image:          aws.String(codeBuildDockerImage),
-			PrivilegedMode: aws.Bool(true),
-			EnvironmentVariables: []*codebuild.EnvironmentVariable{
-				{
-					Name:  aws.String("REPOSITORY_NAME"),
-					Value: aws.String(repoName),
-				},
-				{
-					Name:  aws.String("REPOSITORY_BRANCH"),
-					Value: aws.String(repoBranch),
-				},
-			},
+			ComputeType:          aws.String(config.ComputeType),
+			Type:                 aws.String(config.EnvironmentType),
+			Image:                aws.String(config.Image),
+			PrivilegedMode:       aws.Bool(config.PrivilegedMode),
+			EnvironmentVariables: config.environmentVariables(repoName, repoBranch),
 		},
+		LogsConfig:  config.logsConfig(),
+		VpcConfig:   config.vpcConfig(),
+		Cache:       config.cacheConfig(),
 		ServiceRole: aws.String(codeBuildRoleArn),
 	}
 
-	_, err := client.CreateProject(input)
-
-	if err != nil {
-		log.Fatalf("Error creating project: %v", err)
+	if config.BuildTimeout != 0 {
+		input.TimeoutInMinutes = aws.Int64(config.BuildTimeout)
 	}
 
-	message := fmt.Sprintf("CodeBuild Project %s successfully created", projectName)
-	logging.CustomLog(buildIcon, "green", message)
+	if config.QueuedTimeout != 0 {
+		input.QueuedTimeoutInMinutes = aws.Int64(config.QueuedTimeout)
+	}
 
-	return true, nil
+	return input
 }
 
 // func to verify if the given client is valid
@@ -149,13 +200,13 @@ func checkIfCodeBuildClientIsProvided(client CodeBuildClient) (bool, error) {
 // func to verify if the given project is provided
 func checkIfProjectNameIsProvided(projectName string) (bool, error) {
 	if projectName == "" {
-		fmt.Printf("Error: %v\n", "project name is not provided")
+		logging.Errorf("project name is not provided")
 		return false, fmt.Errorf("project name is not provided")
 	}
 
 	isProjectNameValid, err := checkProjectNameCompliance(projectName)
 	if !isProjectNameValid {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -0,0 +1,316 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codebuild"
+)
+
+// CodeBuildProjectConfig holds the configurable settings of the AFT deployment CodeBuild project.
+type CodeBuildProjectConfig struct {
+	// Image is the docker image used by the build environment.
+	Image string
+
+	// ComputeType is the CodeBuild compute type, e.g. BUILD_GENERAL1_SMALL.
+	ComputeType string
+
+	// EnvironmentType is LINUX_CONTAINER or ARM_CONTAINER.
+	EnvironmentType string
+
+	// PrivilegedMode enables running the docker daemon inside the build.
+	PrivilegedMode bool
+
+	// BuildTimeout is the build timeout in minutes, zero keeps the AWS default.
+	BuildTimeout int64
+
+	// QueuedTimeout is the queue timeout in minutes, zero keeps the AWS default.
+	QueuedTimeout int64
+
+	// VpcID, Subnets and SecurityGroupIDs place the build in a VPC, e.g. to reach private module registries.
+	VpcID            string
+	Subnets          []string
+	SecurityGroupIDs []string
+
+	// LogGroupName and LogStreamName override the CloudWatch Logs destination.
+	LogGroupName  string
+	LogStreamName string
+
+	// S3LogsLocation enables S3 logs in the given bucket/prefix.
+	S3LogsLocation string
+
+	// ParameterStoreVariables maps environment variable names to SSM parameter names.
+	ParameterStoreVariables map[string]string
+
+	// SecretsManagerVariables maps environment variable names to Secrets Manager secret ids.
+	SecretsManagerVariables map[string]string
+
+	// PlainTextVariables maps environment variable names to plain text values.
+	PlainTextVariables map[string]string
+
+	// PluginCacheDir enables the local custom cache holding the Terraform plugins, in the directory the buildspec caches.
+	PluginCacheDir string
+}
+
+// computeTypes lists the compute types available to each environment type
+var computeTypes = map[string][]string{
+	codebuild.EnvironmentTypeLinuxContainer: {
+		codebuild.ComputeTypeBuildGeneral1Small,
+		codebuild.ComputeTypeBuildGeneral1Medium,
+		codebuild.ComputeTypeBuildGeneral1Large,
+		codebuild.ComputeTypeBuildGeneral12xlarge,
+	},
+	codebuild.EnvironmentTypeArmContainer: {
+		codebuild.ComputeTypeBuildGeneral1Small,
+		codebuild.ComputeTypeBuildGeneral1Large,
+	},
+}
+
+// Validate checks the project configuration before anything is created.
+func (c CodeBuildProjectConfig) Validate() error {
+	if c.Image == "" {
+		return errors.New("codebuild image is not provided")
+	}
+
+	allowedComputeTypes, ok := computeTypes[c.EnvironmentType]
+	if !ok {
+		return fmt.Errorf("codebuild environment type must be %s or %s", codebuild.EnvironmentTypeLinuxContainer, codebuild.EnvironmentTypeArmContainer)
+	}
+
+	if !containsString(allowedComputeTypes, c.ComputeType) {
+		return fmt.Errorf("codebuild compute type %s is not available for %s, accepted types are %s", c.ComputeType, c.EnvironmentType, allowedComputeTypes)
+	}
+
+	// build timeouts must be between 5 and 480 minutes (8 hours)
+	if c.BuildTimeout != 0 && (c.BuildTimeout < 5 || c.BuildTimeout > 480) {
+		return errors.New("codebuild build timeout must be between 5 and 480 minutes")
+	}
+
+	if c.QueuedTimeout != 0 && (c.QueuedTimeout < 5 || c.QueuedTimeout > 480) {
+		return errors.New("codebuild queued timeout must be between 5 and 480 minutes")
+	}
+
+	if err := c.validateVpcConfig(); err != nil {
+		return err
+	}
+
+	if c.LogStreamName != "" && c.LogGroupName == "" {
+		return errors.New("codebuild log stream requires a log group")
+	}
+
+	if c.S3LogsLocation != "" {
+		if _, err := checkBucketNameCompliance(strings.SplitN(c.S3LogsLocation, "/", 2)[0]); err != nil {
+			return fmt.Errorf("invalid codebuild s3 logs location: %w", err)
+		}
+	}
+
+	return c.validateEnvironmentVariables()
+}
+
+// func to verify the vpc settings are complete and well formed
+func (c CodeBuildProjectConfig) validateVpcConfig() error {
+	if c.VpcID == "" && len(c.Subnets) == 0 && len(c.SecurityGroupIDs) == 0 {
+		return nil
+	}
+
+	if c.VpcID == "" || len(c.Subnets) == 0 || len(c.SecurityGroupIDs) == 0 {
+		return errors.New("codebuild vpc config requires a vpc id, at least one subnet and at least one security group")
+	}
+
+	if !regexp.MustCompile(`^vpc-[0-9a-f]+$`).MatchString(c.VpcID) {
+		return fmt.Errorf("invalid vpc id %q", c.VpcID)
+	}
+
+	for _, subnet := range c.Subnets {
+		if !regexp.MustCompile(`^subnet-[0-9a-f]+$`).MatchString(subnet) {
+			return fmt.Errorf("invalid subnet id %q", subnet)
+		}
+	}
+
+	for _, securityGroup := range c.SecurityGroupIDs {
+		if !regexp.MustCompile(`^sg-[0-9a-f]+$`).MatchString(securityGroup) {
+			return fmt.Errorf("invalid security group id %q", securityGroup)
+		}
+	}
+
+	return nil
+}
+
+// func to verify the environment variable names are valid and not repeated
+func (c CodeBuildProjectConfig) validateEnvironmentVariables() error {
+	re := regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	seen := map[string]bool{
+		"REPOSITORY_NAME":     true,
+		"REPOSITORY_BRANCH":   true,
+		"TF_PLUGIN_CACHE_DIR": true,
+	}
+
+	for _, variables := range []map[string]string{c.PlainTextVariables, c.ParameterStoreVariables, c.SecretsManagerVariables} {
+		for name, value := range variables {
+			if !re.MatchString(name) {
+				return fmt.Errorf("invalid codebuild environment variable name %q", name)
+			}
+
+			if seen[name] {
+				return fmt.Errorf("codebuild environment variable %s is defined more than once", name)
+			}
+
+			if value == "" {
+				return fmt.Errorf("codebuild environment variable %s has no value", name)
+			}
+
+			seen[name] = true
+		}
+	}
+
+	for name, secretID := range c.SecretsManagerVariables {
+		if strings.HasPrefix(secretID, "arn:") && !regexp.MustCompile(`^arn:aws[a-z-]*:secretsmanager:[a-z0-9-]+:\d{12}:secret:.+`).MatchString(secretID) {
+			return fmt.Errorf("codebuild environment variable %s has an invalid secret arn %q", name, secretID)
+		}
+	}
+
+	return nil
+}
+
+// environmentVariables builds the project environment variables, reserved ones first
+func (c CodeBuildProjectConfig) environmentVariables(repoName string, repoBranch string) []*codebuild.EnvironmentVariable {
+	variables := []*codebuild.EnvironmentVariable{
+		{
+			Name:  aws.String("REPOSITORY_NAME"),
+			Value: aws.String(repoName),
+		},
+		{
+			Name:  aws.String("REPOSITORY_BRANCH"),
+			Value: aws.String(repoBranch),
+		},
+	}
+
+	if c.PluginCacheDir != "" {
+		variables = append(variables, &codebuild.EnvironmentVariable{
+			Name:  aws.String("TF_PLUGIN_CACHE_DIR"),
+			Value: aws.String(c.PluginCacheDir),
+		})
+	}
+
+	variables = append(variables, typedVariables(c.PlainTextVariables, codebuild.EnvironmentVariableTypePlaintext)...)
+	variables = append(variables, typedVariables(c.ParameterStoreVariables, codebuild.EnvironmentVariableTypeParameterStore)...)
+	variables = append(variables, typedVariables(c.SecretsManagerVariables, codebuild.EnvironmentVariableTypeSecretsManager)...)
+
+	return variables
+}
+
+// SecretIDs returns the Secrets Manager secrets read by the environment variables, once each
+func (c CodeBuildProjectConfig) SecretIDs() []string {
+	var secretIDs []string
+	seen := map[string]bool{}
+
+	for _, name := range sortedKeys(c.SecretsManagerVariables) {
+		secretID := c.SecretsManagerVariables[name]
+		if !seen[secretID] {
+			seen[secretID] = true
+			secretIDs = append(secretIDs, secretID)
+		}
+	}
+
+	return secretIDs
+}
+
+// typedVariables converts a name to value map into codebuild variables, sorted by name
+func typedVariables(variables map[string]string, variableType string) []*codebuild.EnvironmentVariable {
+	var result []*codebuild.EnvironmentVariable
+
+	for _, name := range sortedKeys(variables) {
+		result = append(result, &codebuild.EnvironmentVariable{
+			Name:  aws.String(name),
+			Value: aws.String(variables[name]),
+			Type:  aws.String(variableType),
+		})
+	}
+
+	return result
+}
+
+// logsConfig builds the CloudWatch and S3 logs settings
+func (c CodeBuildProjectConfig) logsConfig() *codebuild.LogsConfig {
+	logs := &codebuild.LogsConfig{
+		CloudWatchLogs: &codebuild.CloudWatchLogsConfig{
+			Status: aws.String(codebuild.LogsConfigStatusTypeEnabled),
+		},
+		S3Logs: &codebuild.S3LogsConfig{
+			Status: aws.String(codebuild.LogsConfigStatusTypeDisabled),
+		},
+	}
+
+	if c.LogGroupName != "" {
+		logs.CloudWatchLogs.GroupName = aws.String(c.LogGroupName)
+	}
+
+	if c.LogStreamName != "" {
+		logs.CloudWatchLogs.StreamName = aws.String(c.LogStreamName)
+	}
+
+	if c.S3LogsLocation != "" {
+		logs.S3Logs.Status = aws.String(codebuild.LogsConfigStatusTypeEnabled)
+		logs.S3Logs.Location = aws.String(c.S3LogsLocation)
+	}
+
+	return logs
+}
+
+// vpcConfig builds the VPC settings, or nil when the build runs outside a VPC
+func (c CodeBuildProjectConfig) vpcConfig() *codebuild.VpcConfig {
+	if c.VpcID == "" {
+		return nil
+	}
+
+	return &codebuild.VpcConfig{
+		VpcId:            aws.String(c.VpcID),
+		Subnets:          aws.StringSlice(c.Subnets),
+		SecurityGroupIds: aws.StringSlice(c.SecurityGroupIDs),
+	}
+}
+
+// cacheConfig builds the local file-system cache used for the Terraform plugins
+func (c CodeBuildProjectConfig) cacheConfig() *codebuild.ProjectCache {
+	if c.PluginCacheDir == "" {
+		return &codebuild.ProjectCache{
+			Type: aws.String(codebuild.CacheTypeNoCache),
+		}
+	}
+
+	return &codebuild.ProjectCache{
+		Type:  aws.String(codebuild.CacheTypeLocal),
+		Modes: aws.StringSlice([]string{codebuild.CacheModeLocalCustomCache}),
+	}
+}
+
+// containsString reports whether value is in values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedKeys returns the keys of the map in lexical order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -7,23 +7,23 @@ package aws
 import (
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/codecommit"
 	"github.com/edgarsilva948/aftctl/pkg/aws/tags"
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
 )
 
+const codeCommitIcon = "📦"
+
 // EnsureCodeCommitRepoExists creates a new codecommit repository with the given name, or returns success if it already exists.
 func EnsureCodeCommitRepoExists(client CodeCommitClient, repoName string, description string) (bool, error) {
 
 	_, err := checkIfCodeCommitClientIsProvided(client)
 
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -36,7 +36,8 @@ func EnsureCodeCommitRepoExists(client CodeCommitClient, repoName string, descri
 	repoExists, _ := repoExists(client, repoName)
 
 	if !repoExists {
-		fmt.Printf("CodeCommit repository %s doesn't exists... creating\n", repoName)
+		message := fmt.Sprintf("CodeCommit repository %s doesn't exists... creating", repoName)
+		logging.CustomLog(codeCommitIcon, "yellow", message)
 
 		_, err := createRepo(client, repoName, description)
 
@@ -47,39 +48,22 @@ func EnsureCodeCommitRepoExists(client CodeCommitClient, repoName string, descri
 		return true, nil
 	}
 
-	config := zap.NewDevelopmentConfig()
-	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	config.DisableCaller = true
-
-	logger, _ := config.Build()
-
-	defer logger.Sync()
-
 	message := fmt.Sprintf("CodeCommit Repository %s already exists", repoName)
-
-	customCodeCommitInfoLog(logger, message)
+	logging.CustomLog(codeCommitIcon, "magenta", message)
 
 	return true, nil
 }
 
-func customCodeCommitInfoLog(logger *zap.Logger, msg string) {
-
-	codeEmoji := "📦"
-	coloredMsg := "\033[35m" + codeEmoji + " " + msg + "\033[0m"
-
-	logger.Info(coloredMsg)
-}
-
 // func to verify if the given repository is provided
 func checkIfRepoNameIsProvided(repoName string) (bool, error) {
 	if repoName == "" {
-		fmt.Printf("Error: %v\n", "repository name is not provided")
+		logging.Errorf("repository name is not provided")
 		return false, fmt.Errorf("repository name is not provided")
 	}
 
 	isRepoNameValid, err := checkRepoNameCompliance(repoName)
 	if !isRepoNameValid {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -149,10 +133,11 @@ func createRepo(client CodeCommitClient, repoName string, description string) (b
 	})
 
 	if err != nil {
-		log.Printf("unable to create repository %q, %v", repoName, err)
+		logging.Errorf("unable to create repository %q, %v", repoName, err)
 		return false, err
 	}
 
-	fmt.Printf("CodeCommit repository %s successfully created", repoName)
+	message := fmt.Sprintf("CodeCommit repository %s successfully created", repoName)
+	logging.CustomLog(codeCommitIcon, "green", message)
 	return true, nil
 }
@@ -7,10 +7,10 @@ package aws
 import (
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/codepipeline"
 	"github.com/edgarsilva948/aftctl/pkg/aws/tags"
 	"github.com/edgarsilva948/aftctl/pkg/logging"
@@ -19,12 +19,12 @@ import (
 const pipelineIcon = "👷"
 
 // EnsureCodePipelineExists creates a new codepipeline pipeline with the given name, or returns success if it already exists.
-func EnsureCodePipelineExists(client CodePipelineClient, aftManagementAccountID string, codePipelineRoleName string, pipelineName string, codeSuiteBucketName string, repoName string, branchName string, codeBuildProjectName string) (bool, error) {
+func EnsureCodePipelineExists(client CodePipelineClient, aftManagementAccountID string, codePipelineRoleName string, codePipelineRolePath string, pipelineName string, codeSuiteBucketName string, repoName string, branchName string, codeBuildProjectName string) (bool, error) {
 
 	_, err := checkIfCodePipelineClientIsProvided(client)
 
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -34,14 +34,19 @@ func EnsureCodePipelineExists(client CodePipelineClient, aftManagementAccountID
 		return false, err
 	}
 
-	pipelineExists, _ := pipelineExists(client, pipelineName)
+	pipelineExists, err := pipelineExists(client, pipelineName)
+
+	if err != nil {
+		logging.Errorf("%v", err)
+		return false, err
+	}
 
 	if !pipelineExists {
 
 		message := fmt.Sprintf("CodePipeline pipeline %s doesn't exists... creating", pipelineName)
 		logging.CustomLog(pipelineIcon, "yellow", message)
 
-		_, err := createCodePipelinePipeline(client, aftManagementAccountID, codePipelineRoleName, pipelineName, codeSuiteBucketName, repoName, branchName, codeBuildProjectName)
+		_, err := createCodePipelinePipeline(client, aftManagementAccountID, codePipelineRoleName, codePipelineRolePath, pipelineName, codeSuiteBucketName, repoName, branchName, codeBuildProjectName)
 
 		if err != nil {
 			return false, err
@@ -57,9 +62,25 @@ func EnsureCodePipelineExists(client CodePipelineClient, aftManagementAccountID
 }
 
 // func to create the AFT CodePipeline pipe if it doesn't exist'
-func createCodePipelinePipeline(client CodePipelineClient, aftManagementAccountID string, codePipelineRoleName string, pipelineName string, codeSuiteBucketName string, repoName string, branchName string, codeBuildProjectName string) (bool, error) {
+func createCodePipelinePipeline(client CodePipelineClient, aftManagementAccountID string, codePipelineRoleName string, codePipelineRolePath string, pipelineName string, codeSuiteBucketName string, repoName string, branchName string, codeBuildProjectName string) (bool, error) {
+
+	codePipelineRoleArn := RoleArn(aftManagementAccountID, codePipelineRolePath, codePipelineRoleName)
+
+	input := newCodePipelineInput(pipelineName, codePipelineRoleArn, codeSuiteBucketName, repoName, branchName, codeBuildProjectName)
+
+	_, err := client.CreatePipeline(input)
+	if err != nil {
+		logging.Fatalf("Error creating project: %v", err)
+	}
+
+	message := fmt.Sprintf("CodePipeline Pipeline %s successfully created", pipelineName)
+	logging.CustomLog(pipelineIcon, "green", message)
+
+	return true, nil
+}
 
-	codePipelineRoleArn := "arn:aws:iam::" + aftManagementAccountID + ":role/" + codePipelineRoleName
+// newCodePipelineInput builds the AFT pipeline: a CodeCommit source stage followed by the CodeBuild stage
+func newCodePipelineInput(pipelineName string, codePipelineRoleArn string, codeSuiteBucketName string, repoName string, branchName string, codeBuildProjectName string) *codepipeline.CreatePipelineInput {
 
 	input := &codepipeline.CreatePipelineInput{
 		Tags: []*codepipeline.Tag{
@@ -126,15 +147,7 @@ func createCodePipelinePipeline(client CodePipelineClient, aftManagementAccountI
 		},
 	}
 
-	_, err := client.CreatePipeline(input)
-	if err != nil {
-		log.Fatalf("Error creating project: %v", err)
-	}
-
-	message := fmt.Sprintf("CodePipeline Pipeline %s successfully created", pipelineName)
-	logging.CustomLog(pipelineIcon, "green", message)
-
-	return true, nil
+	return input
 }
 
 // func to verify if the given client is valid
@@ -149,13 +162,13 @@ func checkIfCodePipelineClientIsProvided(client CodePipelineClient) (bool, error
 // func to verify if the given pipeline is provided
 func checkIfPipelineNameIsProvided(pipelineName string) (bool, error) {
 	if pipelineName == "" {
-		fmt.Printf("Error: %v\n", "pipeline name is not provided")
+		logging.Errorf("pipeline name is not provided")
 		return false, fmt.Errorf("pipeline name is not provided")
 	}
 
 	isPipelineNameValid, err := checkPipelineNameCompliance(pipelineName)
 	if !isPipelineNameValid {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -192,20 +205,20 @@ func pipelineExists(client CodePipelineClient, pipelineName string) (bool, error
 	return isPipelineExistent, nil
 }
 
+// func to verify if the given pipeline name already exists
 func checkIfPipelineExists(client CodePipelineClient, pipelineName string) (bool, error) {
 
-	input := &codepipeline.ListPipelinesInput{}
-
-	result, err := client.ListPipelines(input)
-	if err != nil {
-		return false, err
+	input := &codepipeline.GetPipelineInput{
+		Name: aws.String(pipelineName),
 	}
 
-	for _, existingPipeline := range result.Pipelines {
-		if *existingPipeline.Name == pipelineName {
-			return true, nil
+	_, err := client.GetPipeline(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == codepipeline.ErrCodePipelineNotFoundException {
+			return false, nil
 		}
+		return false, err
 	}
 
-	return false, nil
+	return true, nil
 }
@@ -0,0 +1,115 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/codebuild"
+	"github.com/aws/aws-sdk-go/service/codepipeline"
+	"github.com/aws/aws-sdk-go/service/codestarnotifications"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Deployment describes every resource aftctl creates in the AFT management account to deploy AFT.
+// The Ensure functions and the CloudFormation and Terraform exports are both built from it,
+// so the two paths always create the same resources.
+type Deployment struct {
+	Region                 string
+	AftManagementAccountID string
+
+	PipelineRole  DeploymentRole
+	CodeBuildRole DeploymentRole
+
+	StateBucketName    string
+	ArtifactBucketName string
+
+	Repository DeploymentRepository
+	Project    DeploymentProject
+
+	PipelineName string
+
+	// Notifications is nil when the pipeline events aren't sent to SNS.
+	Notifications *DeploymentNotifications
+}
+
+// DeploymentRole is an IAM role of the deployment with its inline and managed policies.
+type DeploymentRole struct {
+	Name              string
+	TrustService      string
+	PolicyName        string
+	Policy            PolicyDocument
+	ManagedPolicyArns []string
+	Options           RoleOptions
+}
+
+// DeploymentRepository is the CodeCommit repository holding the AFT deployment code.
+type DeploymentRepository struct {
+	Name        string
+	Description string
+	Branch      string
+}
+
+// DeploymentProject is the CodeBuild project running the AFT deployment.
+type DeploymentProject struct {
+	Name   string
+	Config CodeBuildProjectConfig
+}
+
+// DeploymentNotifications is the SNS topic receiving the pipeline execution events.
+type DeploymentNotifications struct {
+	TopicName string
+	Emails    []string
+}
+
+// RoleArn returns the ARN of the given deployment role.
+func (d Deployment) RoleArn(role DeploymentRole) string {
+	return RoleArn(d.AftManagementAccountID, role.Options.Path, role.Name)
+}
+
+// RoleInput returns the role as created by EnsureIamRoleExists.
+func (d Deployment) RoleInput(role DeploymentRole) *iam.CreateRoleInput {
+	return newCreateRoleInput(role.Name, role.TrustService, role.Options)
+}
+
+// PublicAccessBlock returns the public access settings applied to the deployment buckets.
+func (d Deployment) PublicAccessBlock() *s3.PublicAccessBlockConfiguration {
+	return newPublicAccessBlockConfiguration()
+}
+
+// BucketPolicy returns the policy EnsureS3BucketExists applies to the given deployment bucket.
+func (d Deployment) BucketPolicy(bucketName string) PolicyDocument {
+	return newBucketPolicy(bucketName, d.AftManagementAccountID, d.RoleArn(d.CodeBuildRole))
+}
+
+// ProjectInput returns the CodeBuild project as created by EnsureCodeBuildProjectExists.
+func (d Deployment) ProjectInput() *codebuild.CreateProjectInput {
+	return newCodeBuildProjectInput(d.Project.Name, d.Repository.Name, d.Repository.Branch, d.RoleArn(d.CodeBuildRole), d.Project.Config)
+}
+
+// PipelineInput returns the pipeline as created by EnsureCodePipelineExists.
+func (d Deployment) PipelineInput() *codepipeline.CreatePipelineInput {
+	return newCodePipelineInput(d.PipelineName, d.RoleArn(d.PipelineRole), d.ArtifactBucketName, d.Repository.Name, d.Repository.Branch, d.Project.Name)
+}
+
+// TopicArn returns the ARN of the notifications topic.
+func (d Deployment) TopicArn() string {
+	if d.Notifications == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("arn:aws:sns:%s:%s:%s", d.Region, d.AftManagementAccountID, d.Notifications.TopicName)
+}
+
+// TopicPolicy returns the policy EnsurePipelineNotificationsExist applies to the notifications topic.
+func (d Deployment) TopicPolicy() PolicyDocument {
+	return newTopicPolicy(d.TopicArn())
+}
+
+// NotificationRuleInput returns the notification rule as created by EnsurePipelineNotificationsExist.
+func (d Deployment) NotificationRuleInput() *codestarnotifications.CreateNotificationRuleInput {
+	return newNotificationRuleInput(notificationRuleName(d.PipelineName), PipelineArn(d.Region, d.AftManagementAccountID, d.PipelineName), d.TopicArn())
+}
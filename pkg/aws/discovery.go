@@ -0,0 +1,142 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// controlTowerBaselineStackSet is created by Control Tower in its home region
+const controlTowerBaselineStackSet = "AWSControlTowerBP-BASELINE-CONFIG"
+
+// GetCallerAccountID returns the account of the current credentials.
+func GetCallerAccountID(client STSClient) (string, error) {
+	if client == nil {
+		return "", errors.New("client is nil")
+	}
+
+	output, err := client.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(output.Account), nil
+}
+
+// GetCallerARN returns the ARN of the identity of the current credentials.
+func GetCallerARN(client STSClient) (string, error) {
+	if client == nil {
+		return "", errors.New("client is nil")
+	}
+
+	output, err := client.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(output.Arn), nil
+}
+
+// GetManagementAccountID returns the management account of the organization.
+func GetManagementAccountID(client OrganizationsClient) (string, error) {
+	if client == nil {
+		return "", errors.New("client is nil")
+	}
+
+	output, err := client.DescribeOrganization(&organizations.DescribeOrganizationInput{})
+	if err != nil {
+		return "", err
+	}
+
+	if output.Organization == nil {
+		return "", errors.New("organization is not described")
+	}
+
+	return aws.StringValue(output.Organization.MasterAccountId), nil
+}
+
+// FindAccountIDByName returns the ID of the organization account with the given name, ignoring the case.
+func FindAccountIDByName(client OrganizationsClient, name string) (string, error) {
+	if client == nil {
+		return "", errors.New("client is nil")
+	}
+
+	var accountID string
+
+	err := client.ListAccountsPages(&organizations.ListAccountsInput{}, func(page *organizations.ListAccountsOutput, lastPage bool) bool {
+		for _, account := range page.Accounts {
+			if strings.EqualFold(aws.StringValue(account.Name), name) {
+				accountID = aws.StringValue(account.Id)
+				return false
+			}
+		}
+		return true
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if accountID == "" {
+		return "", fmt.Errorf("account %q not found in the organization", name)
+	}
+
+	return accountID, nil
+}
+
+// ListActiveAccountIDs returns the IDs of the active accounts of the organization.
+func ListActiveAccountIDs(client OrganizationsClient) ([]string, error) {
+	if client == nil {
+		return nil, errors.New("client is nil")
+	}
+
+	var accountIDs []string
+
+	err := client.ListAccountsPages(&organizations.ListAccountsInput{}, func(page *organizations.ListAccountsOutput, lastPage bool) bool {
+		for _, account := range page.Accounts {
+			if aws.StringValue(account.Status) == organizations.AccountStatusActive {
+				accountIDs = append(accountIDs, aws.StringValue(account.Id))
+			}
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return accountIDs, nil
+}
+
+// IsControlTowerHomeRegion reports whether the client region is the Control Tower home region,
+// found by the baseline stack set Control Tower creates there.
+func IsControlTowerHomeRegion(client CloudformationClient) (bool, error) {
+	if client == nil {
+		return false, errors.New("client is nil")
+	}
+
+	_, err := client.DescribeStackSet(&cloudformation.DescribeStackSetInput{
+		StackSetName: aws.String(controlTowerBaselineStackSet),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == cloudformation.ErrCodeStackSetNotFoundException {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
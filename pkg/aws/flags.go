@@ -0,0 +1,42 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var flagOptions ClientOptions
+
+// AddFlags adds the profile, region and endpoint URL flags to the given set of command line flags.
+func AddFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+
+	flags.StringVar(
+		&flagOptions.Profile,
+		"profile",
+		"",
+		"AWS profile to use instead of the default credentials chain",
+	)
+
+	flags.StringVar(
+		&flagOptions.Region,
+		"region",
+		"",
+		"AWS region to use instead of the region of the profile",
+	)
+
+	flags.StringVar(
+		&flagOptions.EndpointURL,
+		"endpoint-url",
+		"",
+		"Send the AWS requests to this URL instead of the AWS endpoints, e.g. http://localhost:4566 for a local emulator",
+	)
+}
+
+// FlagOptions returns the client options given on the command line.
+func FlagOptions() ClientOptions {
+	return flagOptions
+}
@@ -7,7 +7,6 @@ package aws
 import (
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -19,13 +18,41 @@ import (
 
 const secIcon = "🔒"
 
+// defaultRolePath is the IAM path used when no role path is configured.
+const defaultRolePath = "/"
+
+// RoleOptions holds the settings applied to the IAM roles created by aftctl.
+type RoleOptions struct {
+	// Path is the IAM path the role lives under, e.g. /platform/.
+	Path string
+
+	// PermissionsBoundary is the ARN of the managed policy used as the role permissions boundary.
+	PermissionsBoundary string
+
+	// MaxSessionDuration is the maximum session duration in seconds, zero keeps the AWS default.
+	MaxSessionDuration int64
+
+	// Description is the role description.
+	Description string
+}
+
+// RoleArn builds the ARN of a role, including its path.
+func RoleArn(accountID string, rolePath string, roleName string) string {
+	if rolePath == "" {
+		rolePath = defaultRolePath
+	}
+
+	return "arn:aws:iam::" + accountID + ":role" + rolePath + roleName
+}
+
 // EnsureIamRoleExists creates a new IAM Role with the given name, or returns success if it already exists.
-func EnsureIamRoleExists(client IAMClient, roleName string, trustRelationShipService string, policyName string, region string, aftAccount string, repoName string, bucketName string, terraformStateBucketName string) (bool, error) {
+// The managed policies are attached in both cases, since attaching is idempotent.
+func EnsureIamRoleExists(client IAMClient, roleName string, trustRelationShipService string, policyName string, policy PolicyDocument, managedPolicyArns []string, options RoleOptions) (bool, error) {
 
 	_, err := checkIfIamClientIsProvided(client)
 
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -35,9 +62,28 @@ func EnsureIamRoleExists(client IAMClient, roleName string, trustRelationShipSer
 		return false, err
 	}
 
-	roleExists, _ := checkIfRoleExists(client, roleName)
+	for _, policyArn := range managedPolicyArns {
+		if _, err := checkManagedPolicyArnCompliance(policyArn); err != nil {
+			logging.Errorf("%v", err)
+			return false, err
+		}
+	}
+
+	_, err = checkRoleOptionsCompliance(options)
+
+	if err != nil {
+		logging.Errorf("%v", err)
+		return false, err
+	}
+
+	role, err := getRole(client, roleName)
 
-	if !roleExists {
+	if err != nil {
+		logging.Errorf("unable to look up role %q, %v", roleName, err)
+		return false, err
+	}
+
+	if role == nil {
 		message := fmt.Sprintf("IAM Role %s doesn't exists... creating", roleName)
 
 		logging.CustomLog(secIcon, "yellow", message)
@@ -47,23 +93,37 @@ func EnsureIamRoleExists(client IAMClient, roleName string, trustRelationShipSer
 			roleName,
 			trustRelationShipService,
 			policyName,
-			region,
-			aftAccount,
-			repoName,
-			bucketName,
-			terraformStateBucketName,
+			policy,
+			options,
 		)
 
 		if err != nil {
 			return false, err
 		}
+	} else {
+		message := fmt.Sprintf("IAM Role %s already exists", roleName)
 
-		return true, nil
-	}
+		logging.CustomLog(secIcon, "blue", message)
+
+		_, err := updateRole(client, role, options)
+		if err != nil {
+			return false, err
+		}
 
-	message := fmt.Sprintf("IAM Role %s already exists", roleName)
+		// the inline policy is replaced so existing roles get the current policy of their purpose
+		_, err = putRolePolicy(client, roleName, policyName, policy)
+		if err != nil {
+			return false, err
+		}
 
-	logging.CustomLog(secIcon, "blue", message)
+		message = fmt.Sprintf("Inline policy %s of IAM Role %s updated", policyName, roleName)
+		logging.CustomLog(secIcon, "green", message)
+	}
+
+	_, err = attachManagedPolicies(client, roleName, managedPolicyArns)
+	if err != nil {
+		return false, err
+	}
 
 	return true, nil
 }
@@ -71,13 +131,13 @@ func EnsureIamRoleExists(client IAMClient, roleName string, trustRelationShipSer
 // func to verify if the given iam role is provided
 func checkIfRoleNameIsProvided(roleName string) (bool, error) {
 	if roleName == "" {
-		fmt.Printf("Error: %v\n", "role name is not provided")
+		logging.Errorf("role name is not provided")
 		return false, fmt.Errorf("role name is not provided")
 	}
 
 	isRoleNameValid, err := checkRoleNameCompliance(roleName)
 	if !isRoleNameValid {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -102,25 +162,132 @@ func checkRoleNameCompliance(roleName string) (bool, error) {
 	return true, nil
 }
 
+// func to verify if the given role options are compliant
+func checkRoleOptionsCompliance(options RoleOptions) (bool, error) {
+
+	// role paths must begin and end with a slash and contain printable ASCII characters only
+	if options.Path != "" {
+		pattern := `^/([\x21-\x7E]+/)?$`
+		re := regexp.MustCompile(pattern)
+		if len(options.Path) > 512 || !re.MatchString(options.Path) {
+			return false, errors.New("role path must begin and end with / and be at most 512 characters long")
+		}
+	}
+
+	if options.PermissionsBoundary != "" {
+		if _, err := checkManagedPolicyArnCompliance(options.PermissionsBoundary); err != nil {
+			return false, fmt.Errorf("invalid permissions boundary: %w", err)
+		}
+	}
+
+	// max session duration must be between 1 and 12 hours
+	if options.MaxSessionDuration != 0 && (options.MaxSessionDuration < 3600 || options.MaxSessionDuration > 43200) {
+		return false, errors.New("role max session duration must be between 3600 and 43200 seconds")
+	}
+
+	if len(options.Description) > 1000 {
+		return false, errors.New("role description must be at most 1000 characters long")
+	}
+
+	return true, nil
+}
+
 // func to verify if the given role name already exists
 func checkIfRoleExists(client IAMClient, roleName string) (bool, error) {
 
+	role, err := getRole(client, roleName)
+	if err != nil {
+		return false, err
+	}
+
+	return role != nil, nil
+}
+
+// func to get the given role, nil if it doesn't exist
+func getRole(client IAMClient, roleName string) (*iam.Role, error) {
+
 	input := &iam.GetRoleInput{
 		RoleName: aws.String(roleName),
 	}
 
-	_, err := client.GetRole(input)
+	output, err := client.GetRole(input)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
 			case iam.ErrCodeNoSuchEntityException:
-				return false, nil
+				return nil, nil
 			default:
-				return false, aerr
+				return nil, aerr
 			}
 		}
+		return nil, err
+	}
+
+	if output == nil || output.Role == nil {
+		return &iam.Role{RoleName: aws.String(roleName)}, nil
+	}
+
+	return output.Role, nil
+}
+
+// func to apply the given options to an existing role, the path of a role can't be changed
+func updateRole(client IAMClient, role *iam.Role, options RoleOptions) (bool, error) {
+
+	roleName := aws.StringValue(role.RoleName)
+
+	rolePath := options.Path
+	if rolePath == "" {
+		rolePath = defaultRolePath
+	}
+
+	if role.Path != nil && *role.Path != rolePath {
+		err := fmt.Errorf("IAM Role %s exists under the path %s instead of %s, the path of a role can't be changed, delete the role or set its path", roleName, *role.Path, rolePath)
+		logging.Errorf("%v", err)
+		return false, err
+	}
+
+	if options.PermissionsBoundary != "" && (role.PermissionsBoundary == nil || aws.StringValue(role.PermissionsBoundary.PermissionsBoundaryArn) != options.PermissionsBoundary) {
+		_, err := client.PutRolePermissionsBoundary(&iam.PutRolePermissionsBoundaryInput{
+			PermissionsBoundary: aws.String(options.PermissionsBoundary),
+			RoleName:            aws.String(roleName),
+		})
+		if err != nil {
+			logging.Errorf("unable to put permissions boundary %q on role %q, %v", options.PermissionsBoundary, roleName, err)
+			return false, err
+		}
+
+		message := fmt.Sprintf("Permissions boundary of IAM Role %s updated", roleName)
+		logging.CustomLog(secIcon, "green", message)
+	}
+
+	updateRoleInput := &iam.UpdateRoleInput{
+		RoleName: aws.String(roleName),
+	}
+	changed := false
+
+	if options.MaxSessionDuration != 0 && aws.Int64Value(role.MaxSessionDuration) != options.MaxSessionDuration {
+		updateRoleInput.MaxSessionDuration = aws.Int64(options.MaxSessionDuration)
+		changed = true
+	}
+
+	if options.Description != "" && aws.StringValue(role.Description) != options.Description {
+		updateRoleInput.Description = aws.String(options.Description)
+		changed = true
+	}
+
+	if !changed {
+		return true, nil
+	}
+
+	_, err := client.UpdateRole(updateRoleInput)
+	if err != nil {
+		logging.Errorf("unable to update role %q, %v", roleName, err)
 		return false, err
 	}
+
+	message := fmt.Sprintf("IAM Role %s updated", roleName)
+	logging.CustomLog(secIcon, "green", message)
+
 	return true, nil
 }
 
@@ -134,94 +301,56 @@ func checkIfIamClientIsProvided(client IAMClient) (bool, error) {
 }
 
 // func to create given role if it doesn't exist'
-func createRole(client IAMClient, roleName string, trustRelationShipService string, policyName string, region string, aftAccount string, repoName string, bucketName string, terraformStateBucketName string) (bool, error) {
+func createRole(client IAMClient, roleName string, trustRelationShipService string, policyName string, policy PolicyDocument, options RoleOptions) (bool, error) {
 
-	// Assume Role Policy Document
-	const iamAssumeRolePolicyDocument = `{
-		"Version": "2012-10-17",
-		"Statement": [
-			{
-				"Effect": "Allow",
-				"Principal": {
-					"Service": "%s"
-				},
-				"Action": "sts:AssumeRole"
-			}
-		]
-	}`
-
-	// Role Policy
-	const rolePolicyDocument = `{
-		"Version":"2012-10-17",
-		"Statement":[
-		   {
-			  "Resource":"*",
-			  "Effect":"Allow",
-			  "Action":[
-				 "codebuild:StartBuild",
-				 "codebuild:BatchGetBuilds"
-			  ]
-		   },
-		   {
-			"Resource":"*",
-			"Effect":"Allow",
-			"Action":[
-			   "logs:CreateLogGroup",
-			   "logs:CreateLogStream",
-			   "logs:PutLogEvents"
-			]
- 		   },
-		   {
-			  "Resource":"arn:aws:codecommit:%s:%s:%s",
-			  "Effect":"Allow",
-			  "Action":[
-				 "codecommit:GetBranch",
-				 "codecommit:GetCommit",
-				 "codecommit:UploadArchive",
-				 "codecommit:GetUploadArchiveStatus",
-				 "codecommit:CancelUploadArchive"
-			  ]
-		   },
-		   {
-			"Effect": "Allow",
-			"Resource": "arn:aws:s3:::%s/*",
-			"Action": [
-				"s3:PutObject",
-				"s3:GetObject",
-				"s3:GetObjectVersion",
-				"s3:GetBucketVersioning"
-			]
-			},
-			{
-				"Effect": "Allow",
-				"Resource": "arn:aws:s3:::%s/*",
-				"Action": [
-					"s3:PutObject",
-					"s3:GetObject",
-					"s3:GetObjectVersion",
-					"s3:GetBucketVersioning"
-				]
-			},
-		   {
-			  "Resource":"*",
-			  "Effect":"Allow",
-			  "Action":[
-				 "ec2:CreateNetworkInterface",
-				 "ec2:DescribeDhcpOptions",
-				 "ec2:DescribeNetworkInterfaces",
-				 "ec2:DeleteNetworkInterface",
-				 "ec2:DescribeSubnets",
-				 "ec2:DescribeSecurityGroups",
-				 "ec2:DescribeVpcs",
-				 "ec2:CreateNetworkInterfacePermission"
-			  ]
-		   }
-		]
-	 }`
+	createRoleInput := newCreateRoleInput(roleName, trustRelationShipService, options)
+
+	_, err := client.CreateRole(createRoleInput)
+	if err != nil {
+		logging.Errorf("unable to create role %q, %v", roleName, err)
+		return false, err
+	}
+
+	_, err = putRolePolicy(client, roleName, policyName, policy)
+	if err != nil {
+		return false, err
+	}
+
+	message := fmt.Sprintf("IAM Role %s successfully created", roleName)
+	logging.CustomLog(secIcon, "green", message)
+
+	return true, nil
+}
+
+// func to put the given inline policy on the role, replacing the policy of the same name
+func putRolePolicy(client IAMClient, roleName string, policyName string, policy PolicyDocument) (bool, error) {
+
+	putPolicyInput := &iam.PutRolePolicyInput{
+		PolicyDocument: aws.String(policy.String()),
+		PolicyName:     aws.String(policyName),
+		RoleName:       aws.String(roleName),
+	}
+
+	_, err := client.PutRolePolicy(putPolicyInput)
+	if err != nil {
+		logging.Errorf("unable to put policy %q on role %q, %v", policyName, roleName, err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// newCreateRoleInput builds the role, its trust policy and options
+func newCreateRoleInput(roleName string, trustRelationShipService string, options RoleOptions) *iam.CreateRoleInput {
+
+	rolePath := options.Path
+	if rolePath == "" {
+		rolePath = defaultRolePath
+	}
 
 	createRoleInput := &iam.CreateRoleInput{
-		AssumeRolePolicyDocument: aws.String(fmt.Sprintf(iamAssumeRolePolicyDocument, trustRelationShipService)),
-		Path:                     aws.String("/"),
+		AssumeRolePolicyDocument: aws.String(NewAssumeRolePolicy(trustRelationShipService).String()),
+		Path:                     aws.String(rolePath),
 		RoleName:                 aws.String(roleName),
 		Tags: []*iam.Tag{
 			{
@@ -231,25 +360,38 @@ func createRole(client IAMClient, roleName string, trustRelationShipService stri
 		},
 	}
 
-	_, err := client.CreateRole(createRoleInput)
-	if err != nil {
-		log.Printf("unable to create role %q, %v", roleName, err)
-		return false, err
+	if options.PermissionsBoundary != "" {
+		createRoleInput.PermissionsBoundary = aws.String(options.PermissionsBoundary)
 	}
 
-	putPolicyInput := &iam.PutRolePolicyInput{
-		PolicyDocument: aws.String(fmt.Sprintf(rolePolicyDocument, region, aftAccount, repoName, bucketName, terraformStateBucketName)),
-		PolicyName:     aws.String(policyName),
-		RoleName:       aws.String(roleName),
+	if options.MaxSessionDuration != 0 {
+		createRoleInput.MaxSessionDuration = aws.Int64(options.MaxSessionDuration)
 	}
 
-	_, err = client.PutRolePolicy(putPolicyInput)
-	if err != nil {
-		return false, err
+	if options.Description != "" {
+		createRoleInput.Description = aws.String(options.Description)
 	}
 
-	message := fmt.Sprintf("IAM Role %s successfully created", roleName)
-	logging.CustomLog(secIcon, "green", message)
+	return createRoleInput
+}
+
+// func to attach the given managed policies to the role
+func attachManagedPolicies(client IAMClient, roleName string, managedPolicyArns []string) (bool, error) {
+
+	for _, policyArn := range managedPolicyArns {
+		_, err := client.AttachRolePolicy(&iam.AttachRolePolicyInput{
+			PolicyArn: aws.String(policyArn),
+			RoleName:  aws.String(roleName),
+		})
+
+		if err != nil {
+			logging.Errorf("unable to attach policy %q to role %q, %v", policyArn, roleName, err)
+			return false, err
+		}
+
+		message := fmt.Sprintf("Managed policy %s attached to IAM Role %s", policyArn, roleName)
+		logging.CustomLog(secIcon, "green", message)
+	}
 
 	return true, nil
 }
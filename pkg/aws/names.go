@@ -0,0 +1,38 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+// The naming rules checked before creating the deployment resources, exported to lint a
+// deployment manifest without reaching AWS.
+
+// CheckBucketName checks the naming rules of an S3 bucket.
+func CheckBucketName(bucketName string) (bool, error) {
+	return checkBucketNameCompliance(bucketName)
+}
+
+// CheckRoleName checks the naming rules of an IAM role.
+func CheckRoleName(roleName string) (bool, error) {
+	return checkRoleNameCompliance(roleName)
+}
+
+// CheckRepositoryName checks the naming rules of a CodeCommit repository.
+func CheckRepositoryName(repoName string) (bool, error) {
+	return checkRepoNameCompliance(repoName)
+}
+
+// CheckProjectName checks the naming rules of a CodeBuild project.
+func CheckProjectName(projectName string) (bool, error) {
+	return checkProjectNameCompliance(projectName)
+}
+
+// CheckPipelineName checks the naming rules of a CodePipeline pipeline.
+func CheckPipelineName(pipelineName string) (bool, error) {
+	return checkPipelineNameCompliance(pipelineName)
+}
+
+// CheckStackName checks the naming rules of a CloudFormation stack.
+func CheckStackName(stackName string) (bool, error) {
+	return checkStackNameCompliance(stackName)
+}
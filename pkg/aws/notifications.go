@@ -0,0 +1,251 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/codestarnotifications"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/edgarsilva948/aftctl/pkg/aws/tags"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+)
+
+const notificationsIcon = "📣"
+
+// notificationsServicePrincipal is the service publishing the pipeline events to the topic
+const notificationsServicePrincipal = "codestar-notifications.amazonaws.com"
+
+// PipelineNotificationEvents are the pipeline execution events sent to the notifications topic.
+var PipelineNotificationEvents = []string{
+	"codepipeline-pipeline-pipeline-execution-started",
+	"codepipeline-pipeline-pipeline-execution-succeeded",
+	"codepipeline-pipeline-pipeline-execution-failed",
+}
+
+// EnsurePipelineNotificationsExist creates the SNS topic, its email subscriptions and the notification rule of the given pipeline, or returns success if they already exist.
+func EnsurePipelineNotificationsExist(snsClient SNSClient, notificationsClient CodeStarNotificationsClient, region string, aftManagementAccountID string, pipelineName string, topicName string, emails []string) (bool, error) {
+
+	_, err := checkIfNotificationsClientsAreProvided(snsClient, notificationsClient)
+
+	if err != nil {
+		logging.Errorf("%v", err)
+		return false, err
+	}
+
+	_, err = checkTopicNameCompliance(topicName)
+
+	if err != nil {
+		logging.Errorf("%v", err)
+		return false, err
+	}
+
+	for _, email := range emails {
+		_, err = checkEmailCompliance(email)
+
+		if err != nil {
+			logging.Errorf("%v", err)
+			return false, err
+		}
+	}
+
+	// CreateTopic is idempotent and returns the arn of the existing topic
+	topicArn, err := createTopic(snsClient, topicName)
+
+	if err != nil {
+		logging.Errorf("%v", err)
+		return false, err
+	}
+
+	for _, email := range emails {
+		_, err = subscribeEmail(snsClient, topicArn, email)
+
+		if err != nil {
+			logging.Errorf("%v", err)
+			return false, err
+		}
+	}
+
+	pipelineArn := PipelineArn(region, aftManagementAccountID, pipelineName)
+
+	created, err := createNotificationRule(notificationsClient, notificationRuleName(pipelineName), pipelineArn, topicArn)
+
+	if err != nil {
+		logging.Errorf("%v", err)
+		return false, err
+	}
+
+	if !created {
+		message := fmt.Sprintf("Notification rule for pipeline %s already exists", pipelineName)
+		logging.CustomLog(notificationsIcon, "blue", message)
+
+		return true, nil
+	}
+
+	message := fmt.Sprintf("Notification rule for pipeline %s created, events are sent to %s", pipelineName, topicArn)
+	logging.CustomLog(notificationsIcon, "green", message)
+
+	return true, nil
+}
+
+// func to create the notifications topic and allow codestar notifications to publish to it
+func createTopic(client SNSClient, topicName string) (string, error) {
+
+	output, err := client.CreateTopic(&sns.CreateTopicInput{
+		Name: aws.String(topicName),
+		Tags: []*sns.Tag{
+			{
+				Key:   aws.String(tags.Aftctl),
+				Value: aws.String(tags.True),
+			},
+		},
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to create SNS topic %s: %w", topicName, err)
+	}
+
+	topicArn := aws.StringValue(output.TopicArn)
+
+	_, err = client.SetTopicAttributes(&sns.SetTopicAttributesInput{
+		TopicArn:       aws.String(topicArn),
+		AttributeName:  aws.String("Policy"),
+		AttributeValue: aws.String(newTopicPolicy(topicArn).String()),
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to set the policy of SNS topic %s: %w", topicName, err)
+	}
+
+	return topicArn, nil
+}
+
+// func to subscribe an email address to the topic, SNS returns the existing subscription when it's repeated
+func subscribeEmail(client SNSClient, topicArn string, email string) (bool, error) {
+
+	_, err := client.Subscribe(&sns.SubscribeInput{
+		TopicArn: aws.String(topicArn),
+		Protocol: aws.String("email"),
+		Endpoint: aws.String(email),
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("failed to subscribe %s to %s: %w", email, topicArn, err)
+	}
+
+	message := fmt.Sprintf("%s subscribed to the pipeline notifications, confirmation pending", email)
+	logging.CustomLog(notificationsIcon, "yellow", message)
+
+	return true, nil
+}
+
+// func to create the notification rule, returns false when it already exists
+func createNotificationRule(client CodeStarNotificationsClient, ruleName string, pipelineArn string, topicArn string) (bool, error) {
+
+	_, err := client.CreateNotificationRule(newNotificationRuleInput(ruleName, pipelineArn, topicArn))
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == codestarnotifications.ErrCodeResourceAlreadyExistsException {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to create notification rule %s: %w", ruleName, err)
+	}
+
+	return true, nil
+}
+
+// PipelineArn builds the ARN of a CodePipeline pipeline.
+func PipelineArn(region string, accountID string, pipelineName string) string {
+	return fmt.Sprintf("arn:aws:codepipeline:%s:%s:%s", region, accountID, pipelineName)
+}
+
+// newTopicPolicy allows codestar notifications to publish to the topic
+func newTopicPolicy(topicArn string) PolicyDocument {
+	return PolicyDocument{
+		Version: policyVersion,
+		Statement: []PolicyStatement{
+			{
+				Sid:       "CodeStarNotificationsPublish",
+				Effect:    "Allow",
+				Principal: map[string]string{"Service": notificationsServicePrincipal},
+				Action:    []string{"sns:Publish"},
+				Resource:  []string{topicArn},
+			},
+		},
+	}
+}
+
+// newNotificationRuleInput builds the rule sending the pipeline execution events to the topic
+func newNotificationRuleInput(ruleName string, pipelineArn string, topicArn string) *codestarnotifications.CreateNotificationRuleInput {
+	return &codestarnotifications.CreateNotificationRuleInput{
+		Name:         aws.String(ruleName),
+		Resource:     aws.String(pipelineArn),
+		DetailType:   aws.String(codestarnotifications.DetailTypeBasic),
+		EventTypeIds: aws.StringSlice(PipelineNotificationEvents),
+		Status:       aws.String(codestarnotifications.NotificationRuleStatusEnabled),
+		Targets: []*codestarnotifications.Target{
+			{
+				TargetType:    aws.String("SNS"),
+				TargetAddress: aws.String(topicArn),
+			},
+		},
+		Tags: map[string]*string{
+			tags.Aftctl: aws.String(tags.True),
+		},
+	}
+}
+
+// notificationRuleName derives the rule name from the pipeline name, rule names are limited to 64 characters
+func notificationRuleName(pipelineName string) string {
+	name := pipelineName + "-notifications"
+
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// func to verify if both notifications clients are provided
+func checkIfNotificationsClientsAreProvided(snsClient SNSClient, notificationsClient CodeStarNotificationsClient) (bool, error) {
+	if snsClient == nil {
+		return false, errors.New("SNS client is not provided")
+	}
+
+	if notificationsClient == nil {
+		return false, errors.New("CodeStar Notifications client is not provided")
+	}
+
+	return true, nil
+}
+
+// func to verify if the topic name is valid
+func checkTopicNameCompliance(topicName string) (bool, error) {
+	if topicName == "" {
+		return false, errors.New("SNS topic name is not provided")
+	}
+
+	re := regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+	if !re.MatchString(topicName) {
+		return false, fmt.Errorf("invalid SNS topic name %q", topicName)
+	}
+
+	return true, nil
+}
+
+// func to verify if the email address looks valid
+func checkEmailCompliance(email string) (bool, error) {
+	re := regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	if !re.MatchString(email) {
+		return false, fmt.Errorf("invalid email address %q", email)
+	}
+
+	return true, nil
+}
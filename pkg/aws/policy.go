@@ -0,0 +1,523 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const policyVersion = "2012-10-17"
+
+// ctExecutionRoleName is the role AFT assumes in the Control Tower core accounts
+const ctExecutionRoleName = "AWSControlTowerExecution"
+
+// RolePurpose identifies what a deployment role is used for, which drives the permissions it receives.
+type RolePurpose string
+
+const (
+	// PipelineRolePurpose is the CodePipeline service role: source checkout and build triggering only.
+	PipelineRolePurpose RolePurpose = "pipeline"
+
+	// PlanRolePurpose is a CodeBuild service role that can only read what the AFT module manages.
+	PlanRolePurpose RolePurpose = "plan"
+
+	// ApplyRolePurpose is a CodeBuild service role that can create and update the AFT module resources.
+	ApplyRolePurpose RolePurpose = "apply"
+)
+
+// PolicyDocument is an IAM policy document.
+type PolicyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []PolicyStatement `json:"Statement"`
+}
+
+// PolicyStatement is a single statement of an IAM policy document.
+type PolicyStatement struct {
+	Sid       string            `json:"Sid,omitempty"`
+	Effect    string            `json:"Effect"`
+	Principal map[string]string `json:"Principal,omitempty"`
+	Action    []string          `json:"Action"`
+	Resource  []string          `json:"Resource,omitempty"`
+	Condition PolicyCondition   `json:"Condition,omitempty"`
+}
+
+// PolicyCondition maps condition operators to the keys and values they test.
+type PolicyCondition map[string]map[string][]string
+
+// PolicyScope holds the names of the deployment resources a role policy is restricted to.
+type PolicyScope struct {
+	Region                 string
+	AftManagementAccountID string
+	RepoName               string
+	ProjectName            string
+	ArtifactBucketName     string
+	StateBucketName        string
+
+	// ControlTowerAccountIDs are the CT management, log archive and audit accounts the AFT module deploys into.
+	ControlTowerAccountIDs []string
+
+	// BuildLogGroupName and BuildLogsS3Location are the custom log destinations of the CodeBuild project.
+	BuildLogGroupName   string
+	BuildLogsS3Location string
+
+	// BuildInVpc grants the network interface permissions CodeBuild needs to run in a VPC.
+	BuildInVpc bool
+
+	// BuildSecretIDs are the Secrets Manager secret ids, names or ARNs, of the build environment variables.
+	BuildSecretIDs []string
+}
+
+// String renders the policy document as JSON.
+func (p PolicyDocument) String() string {
+	// the document only holds strings and string maps, so marshaling can't fail
+	document, _ := json.Marshal(p)
+
+	return string(document)
+}
+
+// NewAssumeRolePolicy returns the trust policy allowing the given service to assume a role.
+func NewAssumeRolePolicy(service string) PolicyDocument {
+	return PolicyDocument{
+		Version: policyVersion,
+		Statement: []PolicyStatement{
+			{
+				Effect:    "Allow",
+				Principal: map[string]string{"Service": service},
+				Action:    []string{"sts:AssumeRole"},
+			},
+		},
+	}
+}
+
+// NewRolePolicy builds the inline policy for a deployment role with the given purpose.
+func NewRolePolicy(purpose RolePurpose, scope PolicyScope) (PolicyDocument, error) {
+	var statements []PolicyStatement
+
+	switch purpose {
+	case PipelineRolePurpose:
+		statements = pipelineStatements(scope)
+	case PlanRolePurpose:
+		statements = readStatements(scope)
+	case ApplyRolePurpose:
+		statements = append(readStatements(scope), applyStatements(scope)...)
+	default:
+		return PolicyDocument{}, fmt.Errorf("unknown role purpose %q", purpose)
+	}
+
+	return PolicyDocument{
+		Version:   policyVersion,
+		Statement: statements,
+	}, nil
+}
+
+// pipelineStatements grants CodePipeline access to the source repository, artifact bucket and build project
+func pipelineStatements(scope PolicyScope) []PolicyStatement {
+	return []PolicyStatement{
+		{
+			Sid:    "SourceRepository",
+			Effect: "Allow",
+			Action: []string{
+				"codecommit:GetBranch",
+				"codecommit:GetCommit",
+				"codecommit:UploadArchive",
+				"codecommit:GetUploadArchiveStatus",
+				"codecommit:CancelUploadArchive",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:codecommit:%s:%s:%s", scope.Region, scope.AftManagementAccountID, scope.RepoName)},
+		},
+		{
+			Sid:    "BuildProject",
+			Effect: "Allow",
+			Action: []string{
+				"codebuild:StartBuild",
+				"codebuild:BatchGetBuilds",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:codebuild:%s:%s:project/%s", scope.Region, scope.AftManagementAccountID, scope.ProjectName)},
+		},
+		artifactBucketStatement(scope),
+	}
+}
+
+// readStatements grants CodeBuild read access to everything the AFT module manages
+func readStatements(scope PolicyScope) []PolicyStatement {
+	logGroups := []string{fmt.Sprintf("arn:aws:logs:%s:%s:log-group:/aws/codebuild/%s*", scope.Region, scope.AftManagementAccountID, scope.ProjectName)}
+
+	if scope.BuildLogGroupName != "" {
+		logGroups = append(logGroups, fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s*", scope.Region, scope.AftManagementAccountID, scope.BuildLogGroupName))
+	}
+
+	statements := []PolicyStatement{
+		{
+			Sid:    "BuildLogs",
+			Effect: "Allow",
+			Action: []string{
+				"logs:CreateLogGroup",
+				"logs:CreateLogStream",
+				"logs:PutLogEvents",
+			},
+			Resource: logGroups,
+		},
+		artifactBucketStatement(scope),
+		{
+			Sid:    "StateBucketRead",
+			Effect: "Allow",
+			Action: []string{
+				"s3:ListBucket",
+				"s3:GetBucketVersioning",
+				"s3:GetObject",
+				"s3:GetObjectVersion",
+			},
+			Resource: []string{
+				fmt.Sprintf("arn:aws:s3:::%s", scope.StateBucketName),
+				fmt.Sprintf("arn:aws:s3:::%s/*", scope.StateBucketName),
+			},
+		},
+		{
+			Sid:    "AftResourcesRead",
+			Effect: "Allow",
+			Action: []string{
+				"backup:Describe*",
+				"backup:Get*",
+				"backup:List*",
+				"codebuild:BatchGet*",
+				"codebuild:List*",
+				"codecommit:Get*",
+				"codecommit:List*",
+				"codepipeline:Get*",
+				"codepipeline:List*",
+				"codestar-connections:Get*",
+				"codestar-connections:List*",
+				"dynamodb:Describe*",
+				"dynamodb:List*",
+				"ec2:Describe*",
+				"events:Describe*",
+				"events:List*",
+				"iam:Get*",
+				"iam:List*",
+				"kms:Describe*",
+				"kms:Get*",
+				"kms:List*",
+				"lambda:Get*",
+				"lambda:List*",
+				"logs:Describe*",
+				"logs:List*",
+				"organizations:Describe*",
+				"organizations:List*",
+				"s3:Get*",
+				"s3:List*",
+				"sns:Get*",
+				"sns:List*",
+				"sqs:Get*",
+				"sqs:List*",
+				"ssm:Describe*",
+				"ssm:Get*",
+				"ssm:List*",
+				"states:Describe*",
+				"states:List*",
+				"sts:GetCallerIdentity",
+			},
+			Resource: []string{"*"},
+		},
+	}
+
+	if scope.BuildLogsS3Location != "" {
+		statements = append(statements, PolicyStatement{
+			Sid:    "BuildLogsBucket",
+			Effect: "Allow",
+			Action: []string{
+				"s3:PutObject",
+				"s3:GetBucketAcl",
+				"s3:GetBucketLocation",
+			},
+			Resource: []string{
+				fmt.Sprintf("arn:aws:s3:::%s", strings.SplitN(scope.BuildLogsS3Location, "/", 2)[0]),
+				fmt.Sprintf("arn:aws:s3:::%s/*", scope.BuildLogsS3Location),
+			},
+		})
+	}
+
+	if scope.BuildInVpc {
+		statements = append(statements, PolicyStatement{
+			Sid:    "BuildVpc",
+			Effect: "Allow",
+			Action: []string{
+				"ec2:CreateNetworkInterface",
+				"ec2:CreateNetworkInterfacePermission",
+				"ec2:DeleteNetworkInterface",
+				"ec2:DescribeDhcpOptions",
+				"ec2:DescribeNetworkInterfaces",
+				"ec2:DescribeSecurityGroups",
+				"ec2:DescribeSubnets",
+				"ec2:DescribeVpcs",
+			},
+			Resource: []string{"*"},
+		})
+	}
+
+	if len(scope.BuildSecretIDs) > 0 {
+		statements = append(statements, buildSecretsStatements(scope)...)
+	}
+
+	if crossAccount, ok := controlTowerAssumeRoleStatement(scope); ok {
+		statements = append(statements, crossAccount)
+	}
+
+	return statements
+}
+
+// applyStatements grants CodeBuild write access to the AFT module resources in the AFT management account
+func applyStatements(scope PolicyScope) []PolicyStatement {
+	account := scope.AftManagementAccountID
+
+	return []PolicyStatement{
+		{
+			Sid:    "StateBucketWrite",
+			Effect: "Allow",
+			Action: []string{
+				"s3:PutObject",
+				"s3:DeleteObject",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:s3:::%s/*", scope.StateBucketName)},
+		},
+		{
+			// services whose ARNs hold the names of the AFT module resources
+			Sid:    "AftNamedResourcesWrite",
+			Effect: "Allow",
+			Action: []string{
+				"dynamodb:*",
+				"lambda:*",
+				"sns:*",
+				"sqs:*",
+				"ssm:*",
+				"states:*",
+			},
+			Resource: []string{
+				fmt.Sprintf("arn:aws:dynamodb:*:%s:table/aft-*", account),
+				fmt.Sprintf("arn:aws:lambda:*:%s:function:aft-*", account),
+				fmt.Sprintf("arn:aws:lambda:*:%s:layer:aft-*", account),
+				fmt.Sprintf("arn:aws:sns:*:%s:aft-*", account),
+				fmt.Sprintf("arn:aws:sqs:*:%s:aft-*", account),
+				fmt.Sprintf("arn:aws:ssm:*:%s:parameter/aft/*", account),
+				fmt.Sprintf("arn:aws:states:*:%s:stateMachine:aft-*", account),
+				fmt.Sprintf("arn:aws:states:*:%s:execution:aft-*", account),
+			},
+		},
+		{
+			// services whose resources are named by the user or addressed by id, e.g. the repositories, VPC and keys
+			Sid:    "AftRegionalResourcesWrite",
+			Effect: "Allow",
+			Action: []string{
+				"backup:*",
+				"codebuild:*",
+				"codecommit:*",
+				"codepipeline:*",
+				"codestar-connections:*",
+				"ec2:*",
+				"events:*",
+				"kms:*",
+				"logs:*",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:*:*:%s:*", account)},
+		},
+		{
+			Sid:    "AftRolesWrite",
+			Effect: "Allow",
+			Action: []string{
+				"iam:CreateRole",
+				"iam:DeleteRole",
+				"iam:UpdateRole",
+				"iam:UpdateRoleDescription",
+				"iam:UpdateAssumeRolePolicy",
+				"iam:TagRole",
+				"iam:UntagRole",
+				"iam:PutRolePolicy",
+				"iam:DeleteRolePolicy",
+				"iam:AttachRolePolicy",
+				"iam:DetachRolePolicy",
+				"iam:CreatePolicy",
+				"iam:DeletePolicy",
+				"iam:CreatePolicyVersion",
+				"iam:DeletePolicyVersion",
+				"iam:SetDefaultPolicyVersion",
+				"iam:TagPolicy",
+				"iam:UntagPolicy",
+			},
+			Resource: append(aftIamResources(account, "role"), aftIamResources(account, "policy")...),
+		},
+		{
+			Sid:      "AftRolesPass",
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: aftIamResources(account, "role"),
+			Condition: PolicyCondition{
+				"StringEquals": {"iam:PassedToService": aftServicePrincipals},
+			},
+		},
+		{
+			Sid:    "AftBucketsWrite",
+			Effect: "Allow",
+			Action: []string{
+				"s3:*",
+			},
+			Resource: []string{
+				"arn:aws:s3:::aft-*",
+				"arn:aws:s3:::aws-aft-*",
+			},
+		},
+		{
+			// resource creation calls that can't be scoped to an ARN
+			Sid:    "AftUnscopedWrite",
+			Effect: "Allow",
+			Action: []string{
+				"ec2:CreateVpc",
+				"ec2:CreateSecurityGroup",
+				"ec2:CreateTags",
+				"kms:CreateKey",
+				"kms:CreateAlias",
+				"backup:CreateBackupVault",
+				"states:CreateStateMachine",
+				"codestar-connections:CreateConnection",
+			},
+			Resource: []string{"*"},
+		},
+	}
+}
+
+// aftServicePrincipals are the services the AFT module passes its roles to
+var aftServicePrincipals = []string{
+	"backup.amazonaws.com",
+	"codebuild.amazonaws.com",
+	"codepipeline.amazonaws.com",
+	"events.amazonaws.com",
+	"lambda.amazonaws.com",
+	"states.amazonaws.com",
+}
+
+// aftIamResources returns the ARNs of the IAM entities of the given kind, role or policy, named after the AFT
+// prefixes at the root path or under any other path
+func aftIamResources(account string, kind string) []string {
+	var resources []string
+
+	for _, prefix := range []string{"aft-", "AWSAFT"} {
+		resources = append(resources,
+			fmt.Sprintf("arn:aws:iam::%s:%s/%s*", account, kind, prefix),
+			fmt.Sprintf("arn:aws:iam::%s:%s/*/%s*", account, kind, prefix),
+		)
+	}
+
+	return resources
+}
+
+// artifactBucketStatement grants access to the CodePipeline artifact bucket
+func artifactBucketStatement(scope PolicyScope) PolicyStatement {
+	return PolicyStatement{
+		Sid:    "ArtifactBucket",
+		Effect: "Allow",
+		Action: []string{
+			"s3:PutObject",
+			"s3:GetObject",
+			"s3:GetObjectVersion",
+			"s3:GetBucketVersioning",
+		},
+		Resource: []string{
+			fmt.Sprintf("arn:aws:s3:::%s", scope.ArtifactBucketName),
+			fmt.Sprintf("arn:aws:s3:::%s/*", scope.ArtifactBucketName),
+		},
+	}
+}
+
+// buildSecretsStatements lets CodeBuild read the secrets of the environment variables, and decrypt them when they are
+// encrypted with a customer managed key
+func buildSecretsStatements(scope PolicyScope) []PolicyStatement {
+	var secrets, services []string
+
+	for _, secretID := range scope.BuildSecretIDs {
+		arn := secretArn(scope, secretID)
+		if slices.Contains(secrets, arn) {
+			continue
+		}
+		secrets = append(secrets, arn)
+
+		// the key is used through the Secrets Manager endpoint of the region of the secret
+		service := fmt.Sprintf("secretsmanager.%s.amazonaws.com", strings.Split(arn, ":")[3])
+		if !slices.Contains(services, service) {
+			services = append(services, service)
+		}
+	}
+
+	return []PolicyStatement{
+		{
+			Sid:      "BuildSecrets",
+			Effect:   "Allow",
+			Action:   []string{"secretsmanager:GetSecretValue"},
+			Resource: secrets,
+		},
+		{
+			Sid:      "BuildSecretsDecrypt",
+			Effect:   "Allow",
+			Action:   []string{"kms:Decrypt"},
+			Resource: []string{"*"},
+			Condition: PolicyCondition{
+				"StringEquals": {"kms:ViaService": services},
+			},
+		},
+	}
+}
+
+// secretArn returns the ARN matching the secret of a CodeBuild environment variable, given as an ARN or a name
+// followed by the optional json key, version stage and version id. Secrets Manager appends six random characters
+// to the name of the secret in its ARN.
+func secretArn(scope PolicyScope, secretID string) string {
+	if strings.HasPrefix(secretID, "arn:") {
+		// arn:aws:secretsmanager:region:account:secret:name-suffix, then the json key and version
+		parts := strings.SplitN(secretID, ":", 8)
+		if len(parts) > 7 {
+			parts = parts[:7]
+		}
+		return strings.Join(parts, ":")
+	}
+
+	name := strings.SplitN(secretID, ":", 2)[0]
+
+	return fmt.Sprintf("arn:aws:secretsmanager:%s:%s:secret:%s-??????", scope.Region, scope.AftManagementAccountID, name)
+}
+
+// controlTowerAssumeRoleStatement lets the AFT module providers reach the Control Tower core accounts
+func controlTowerAssumeRoleStatement(scope PolicyScope) (PolicyStatement, bool) {
+	var roles []string
+
+	for _, accountID := range scope.ControlTowerAccountIDs {
+		if accountID == "" {
+			continue
+		}
+		roles = append(roles, fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, ctExecutionRoleName))
+	}
+
+	if len(roles) == 0 {
+		return PolicyStatement{}, false
+	}
+
+	return PolicyStatement{
+		Sid:      "ControlTowerAccounts",
+		Effect:   "Allow",
+		Action:   []string{"sts:AssumeRole"},
+		Resource: roles,
+	}, true
+}
+
+// func to verify if the given managed policy arn is valid
+func checkManagedPolicyArnCompliance(policyArn string) (bool, error) {
+	pattern := `^arn:aws[a-z-]*:iam::(aws|\d{12}):policy/[\w+=,.@/-]+$`
+	re := regexp.MustCompile(pattern)
+	if !re.MatchString(policyArn) {
+		return false, fmt.Errorf("invalid managed policy arn %q", policyArn)
+	}
+
+	return true, nil
+}
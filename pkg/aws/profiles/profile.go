@@ -2,77 +2,241 @@
 Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 */
 
-// Package profile implements a new profile for AWS CLI
+// Package profile assumes the AFT roles and cleans up the AWS CLI profiles written by older aftctl versions
 package profile
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
 	awsClient "github.com/edgarsilva948/aftctl/pkg/aws"
 	"gopkg.in/ini.v1"
 )
 
-// SetupProfile creates a new profile for AWS CLI (~/.aws/credentials)
-func SetupProfile(client awsClient.STSClient, accountID string, roleName string, roleSession string) error {
+// Credentials are the temporary credentials of an assumed role, only kept in memory
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// Env returns the environment variables giving the credentials to a child process
+func (c Credentials) Env() []string {
+	return []string{
+		"AWS_ACCESS_KEY_ID=" + c.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + c.SecretAccessKey,
+		"AWS_SESSION_TOKEN=" + c.SessionToken,
+	}
+}
+
+// ProcessOutput returns the credentials in the format expected from a credential_process
+func (c Credentials) ProcessOutput() ([]byte, error) {
+	return json.Marshal(struct {
+		Version         int
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		SessionToken    string
+		Expiration      string `json:",omitempty"`
+	}{
+		Version:         1,
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		Expiration:      formatExpiration(c.Expiration),
+	})
+}
+
+func formatExpiration(expiration time.Time) string {
+	if expiration.IsZero() {
+		return ""
+	}
+
+	return expiration.UTC().Format(time.RFC3339)
+}
+
+// RoleARN returns the ARN of the role in the account
+func RoleARN(accountID string, roleName string) string {
+	return fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, roleName)
+}
+
+// Options are the settings of the role session
+type Options struct {
+	// SessionName identifies the session in CloudTrail, e.g. with the user's identity.
+	SessionName string
+
+	// ExternalID is required by the trust policy of some roles.
+	ExternalID string
+
+	// Duration of the session, the default of STS when zero.
+	Duration time.Duration
+
+	// MFASerial and MFAToken are the MFA device and its current code, required by the trust policy of some roles.
+	MFASerial string
+	MFAToken  string
+}
+
+// AssumeRole assumes the role and returns its credentials without writing them anywhere
+func AssumeRole(client awsClient.STSClient, roleARN string, options Options) (Credentials, error) {
 
 	if client == nil {
-		return errors.New("client is nil")
+		return Credentials{}, errors.New("client is nil")
 	}
 
 	input := &sts.AssumeRoleInput{
-		RoleArn:         aws.String(fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, roleName)),
-		RoleSessionName: aws.String(roleSession),
+		RoleArn:         aws.String(roleARN),
+		RoleSessionName: aws.String(options.SessionName),
+	}
+
+	if options.ExternalID != "" {
+		input.ExternalId = aws.String(options.ExternalID)
+	}
+	if options.Duration > 0 {
+		input.DurationSeconds = aws.Int64(int64(options.Duration.Seconds()))
+	}
+	if options.MFASerial != "" {
+		input.SerialNumber = aws.String(options.MFASerial)
+		input.TokenCode = aws.String(options.MFAToken)
 	}
 
 	result, err := client.AssumeRole(input)
 	if err != nil {
-		return err
+		return Credentials{}, err
 	}
 
 	if result == nil || result.Credentials == nil {
-		return errors.New("result or result.Credentials is nil")
+		return Credentials{}, errors.New("result or result.Credentials is nil")
 	}
 
-	// Construct profile name
-	profileName := fmt.Sprintf("%s-%s", accountID, roleName)
+	return Credentials{
+		AccessKeyID:     aws.StringValue(result.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(result.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(result.Credentials.SessionToken),
+		Expiration:      aws.TimeValue(result.Credentials.Expiration),
+	}, nil
+}
 
-	// Get the AWS config file path
-	awsConfigFile := fmt.Sprintf("%s/.aws/credentials", os.Getenv("HOME"))
+// NewSTSClient returns an STS client using the credentials, e.g. to assume the next role of a chain
+func NewSTSClient(credentials Credentials, region string, endpointURL string) (awsClient.STSClient, error) {
+	config := aws.NewConfig().
+		WithRegion(region).
+		WithCredentials(awscredentials.NewStaticCredentials(credentials.AccessKeyID, credentials.SecretAccessKey, credentials.SessionToken))
 
-	// Check if the file exists, if not, create it
-	if _, err := os.Stat(awsConfigFile); os.IsNotExist(err) {
-		// Create the directory if not exists
-		if err := os.MkdirAll(fmt.Sprintf("%s/.aws", os.Getenv("HOME")), 0755); err != nil {
-			return err
-		}
+	if endpointURL != "" {
+		config = config.WithEndpoint(endpointURL)
+	}
 
-		// Create the file
-		_, err := os.Create(awsConfigFile)
-		if err != nil {
-			return err
-		}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating session: %w", err)
 	}
 
-	// Load the AWS config file
-	cfg, err := ini.Load(awsConfigFile)
+	return sts.New(sess), nil
+}
+
+// sessionNameChars are the characters accepted in a role session name
+var sessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// SessionName returns the session name made of the prefix and of the name at the end of the caller ARN,
+// e.g. AWSAFT-Session-jdoe@example.com for arn:aws:sts::111111111111:assumed-role/Admin/jdoe@example.com
+func SessionName(prefix string, callerARN string) string {
+	identity := callerARN[strings.LastIndex(callerARN, "/")+1:]
+	if strings.HasPrefix(identity, "arn:") {
+		// the root user has no name
+		identity = "root"
+	}
+
+	name := prefix + "-" + sessionNameChars.ReplaceAllString(identity, "-")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// DefaultAdminRoleName is the default name of the AFT administrator role older aftctl versions wrote profiles for
+const DefaultAdminRoleName = "AWSAFTAdmin"
+
+// writtenProfileKeys are the keys, sorted, of the profiles older aftctl versions wrote
+var writtenProfileKeys = []string{"aws_access_key_id", "aws_secret_access_key", "aws_session_token"}
+
+// CredentialsFile returns the AWS credentials file, $AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials
+func CredentialsFile() (string, error) {
+	if path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
 	if err != nil {
+		return "", fmt.Errorf("error getting the home directory: %w", err)
+	}
+
+	return filepath.Join(home, ".aws", "credentials"), nil
+}
+
+// FindWrittenProfiles returns the profiles of the credentials file written by older aftctl versions, named
+// <account>-<role> after the AFT administrator role and holding exactly the keys of the assumed credentials
+func FindWrittenProfiles(path string, roleName string) ([]string, error) {
+	cfg, err := loadCredentialsFile(path)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+
+	pattern := regexp.MustCompile(`^\d{12}-` + regexp.QuoteMeta(roleName) + `$`)
+
+	var profiles []string
+	for _, section := range cfg.Sections() {
+		keys := section.KeyStrings()
+		sort.Strings(keys)
+
+		if pattern.MatchString(section.Name()) && slices.Equal(keys, writtenProfileKeys) {
+			profiles = append(profiles, section.Name())
+		}
+	}
+
+	return profiles, nil
+}
+
+// RemoveProfiles removes the profiles from the credentials file and makes it readable by the user only
+func RemoveProfiles(path string, profiles []string) error {
+	cfg, err := loadCredentialsFile(path)
+	if err != nil || cfg == nil {
 		return err
 	}
 
-	// Create a new section (AWS profile) or update an existing one
-	sec, err := cfg.NewSection(profileName)
-	if err != nil {
+	for _, profile := range profiles {
+		cfg.DeleteSection(profile)
+	}
+
+	if err := cfg.SaveTo(path); err != nil {
 		return err
 	}
 
-	sec.Key("aws_access_key_id").SetValue(*result.Credentials.AccessKeyId)
-	sec.Key("aws_secret_access_key").SetValue(*result.Credentials.SecretAccessKey)
-	sec.Key("aws_session_token").SetValue(*result.Credentials.SessionToken)
+	return os.Chmod(path, 0600)
+}
+
+// loadCredentialsFile reads the credentials file, a missing file has no profile
+func loadCredentialsFile(path string) (*ini.File, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	cfg, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
 
-	// Save the updated AWS config file
-	return cfg.SaveTo(awsConfigFile)
+	return cfg, nil
 }
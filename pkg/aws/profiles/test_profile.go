@@ -0,0 +1,120 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Managing the AFT credentials", func() {
+
+	ginkgo.Context("testing the ProcessOutput function", func() {
+		ginkgo.When("the credentials expire", func() {
+			ginkgo.It("should print the credential_process format", func() {
+				credentials := Credentials{
+					AccessKeyID:     "key",
+					SecretAccessKey: "secret",
+					SessionToken:    "token",
+					Expiration:      time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC),
+				}
+
+				content, err := credentials.ProcessOutput()
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(content).To(gomega.MatchJSON(`{"Version": 1, "AccessKeyId": "key", "SecretAccessKey": "secret",
+					"SessionToken": "token", "Expiration": "2023-09-01T12:00:00Z"}`))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the SessionName function", func() {
+		ginkgo.When("the caller is an assumed role", func() {
+			ginkgo.It("should name the session after the caller's session", func() {
+				gomega.Expect(SessionName("AWSAFT-Session", "arn:aws:sts::111111111111:assumed-role/AWSReservedSSO_Admin/jdoe@example.com")).
+					To(gomega.Equal("AWSAFT-Session-jdoe@example.com"))
+				gomega.Expect(SessionName("AWSAFT-Session", "arn:aws:iam::111111111111:root")).To(gomega.Equal("AWSAFT-Session-root"))
+			})
+		})
+
+		ginkgo.When("the name is too long or holds invalid characters", func() {
+			ginkgo.It("should replace them and truncate the name to 64 characters", func() {
+				name := SessionName("AWSAFT-Session", "arn:aws:iam::111111111111:user/"+strings.Repeat("a", 60)+" b:c")
+				gomega.Expect(name).To(gomega.HaveLen(64))
+				gomega.Expect(name).To(gomega.MatchRegexp(`^[\w+=,.@-]+$`))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the cleanup of the profiles written by aftctl", func() {
+		var path string
+
+		ginkgo.BeforeEach(func() {
+			path = filepath.Join(ginkgo.GinkgoT().TempDir(), "credentials")
+			content := `[default]
+aws_access_key_id = AKIADEFAULT
+aws_secret_access_key = secret
+
+[111111111111-AWSAFTAdmin]
+aws_access_key_id = ASIAASSUMED
+aws_secret_access_key = secret
+aws_session_token = token
+
+[222222222222-team]
+aws_access_key_id = AKIATEAM
+aws_secret_access_key = secret
+aws_session_token = token
+
+[333333333333-AWSAFTAdmin]
+aws_access_key_id = ASIAVAULT
+aws_secret_access_key = secret
+aws_session_token = token
+aws_credential_expiration = 2023-09-13T21:30:08Z
+`
+			gomega.Expect(os.WriteFile(path, []byte(content), 0644)).To(gomega.Succeed())
+		})
+
+		ginkgo.When("the credentials file has profiles written by aftctl", func() {
+			ginkgo.It("should only remove them", func() {
+				profiles, err := FindWrittenProfiles(path, DefaultAdminRoleName)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(profiles).To(gomega.Equal([]string{"111111111111-AWSAFTAdmin"}))
+
+				gomega.Expect(RemoveProfiles(path, profiles)).To(gomega.Succeed())
+
+				content, err := os.ReadFile(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(string(content)).ToNot(gomega.ContainSubstring("ASIAASSUMED"))
+				gomega.Expect(string(content)).To(gomega.ContainSubstring("AKIADEFAULT"))
+				gomega.Expect(string(content)).To(gomega.ContainSubstring("AKIATEAM"))
+				gomega.Expect(string(content)).To(gomega.ContainSubstring("ASIAVAULT"))
+
+				info, err := os.Stat(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(info.Mode().Perm()).To(gomega.Equal(os.FileMode(0600)))
+			})
+		})
+
+		ginkgo.When("the AFT administrator role has another name", func() {
+			ginkgo.It("should only find the profiles of that role", func() {
+				profiles, err := FindWrittenProfiles(path, "team")
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(profiles).To(gomega.Equal([]string{"222222222222-team"}))
+			})
+		})
+
+		ginkgo.When("the credentials file doesn't exist", func() {
+			ginkgo.It("should find no profile", func() {
+				profiles, err := FindWrittenProfiles(filepath.Join(ginkgo.GinkgoT().TempDir(), "missing"), DefaultAdminRoleName)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(profiles).To(gomega.BeEmpty())
+			})
+		})
+	})
+})
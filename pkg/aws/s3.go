@@ -8,7 +8,6 @@ package aws
 import (
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"regexp"
 	"strings"
@@ -18,18 +17,23 @@ import (
 	"github.com/edgarsilva948/aftctl/pkg/logging"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
 const bucketIcon = "🪣 "
 
+// ErrBucketOwnedByAnotherAccount is returned when the bucket name is already taken by a bucket
+// that doesn't belong to the AFT management account.
+var ErrBucketOwnedByAnotherAccount = errors.New("bucket already exists and is owned by another account")
+
 // EnsureS3BucketExists creates a new S3 bucket with the given name, or returns success if it already exists.
-func EnsureS3BucketExists(client S3Client, bucketName string, aftManagementAccountID string, kmsKeyID string, codeBuildRole string) (bool, error) {
+func EnsureS3BucketExists(client S3Client, bucketName string, aftManagementAccountID string, kmsKeyID string, codeBuildRoleArn string) (bool, error) {
 
 	_, err := checkIfS3ClientIsProvided(client)
 
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -39,14 +43,19 @@ func EnsureS3BucketExists(client S3Client, bucketName string, aftManagementAccou
 		return false, err
 	}
 
-	bucketExists, _ := bucketExists(client, bucketName)
+	bucketExists, err := bucketExists(client, bucketName, aftManagementAccountID)
+
+	if err != nil {
+		logging.Errorf("%v", err)
+		return false, err
+	}
 
 	if !bucketExists {
 
 		message := fmt.Sprintf("S3 bucket %s doesn't exists... creating", bucketName)
 		logging.CustomLog(bucketIcon, "yellow", message)
 
-		_, err := createBucket(client, bucketName, aftManagementAccountID, kmsKeyID, codeBuildRole)
+		_, err := createBucket(client, bucketName, aftManagementAccountID, kmsKeyID, codeBuildRoleArn)
 
 		if err != nil {
 			return false, err
@@ -62,9 +71,9 @@ func EnsureS3BucketExists(client S3Client, bucketName string, aftManagementAccou
 }
 
 // BucketExists checks if a given S3 bucket exists.
-func bucketExists(client S3Client, bucketName string) (bool, error) {
+func bucketExists(client S3Client, bucketName string, expectedOwner string) (bool, error) {
 
-	isBucketExistent, err := checkIfBucketExists(client, bucketName)
+	isBucketExistent, err := checkIfBucketExists(client, bucketName, expectedOwner)
 	if err != nil {
 		return false, err
 	}
@@ -81,34 +90,60 @@ func checkIfS3ClientIsProvided(client S3Client) (bool, error) {
 	return true, nil
 }
 
-// func to verify if the given bucket name already exists
-func checkIfBucketExists(client S3Client, bucketName string) (bool, error) {
-	input := &s3.ListBucketsInput{}
+// func to verify if the given bucket name already exists and belongs to the expected owner
+func checkIfBucketExists(client S3Client, bucketName string, expectedOwner string) (bool, error) {
+	input := &s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	}
 
-	output, err := client.ListBuckets(input)
-	if err != nil {
-		return false, fmt.Errorf("failed to list S3 buckets: %w", err)
+	if expectedOwner != "" {
+		input.ExpectedBucketOwner = aws.String(expectedOwner)
+	}
+
+	_, err := client.HeadBucket(input)
+	if err == nil {
+		return true, nil
 	}
 
-	for _, bucket := range output.Buckets {
-		if *bucket.Name == bucketName {
-			return true, nil
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		switch reqErr.StatusCode() {
+		case 404:
+			return false, nil
+		case 403:
+			// S3 answers 403 both when the owner doesn't match ExpectedBucketOwner and when the caller lacks
+			// s3:ListBucket, only a bucket readable without the owner check belongs to another account
+			if expectedOwner != "" && isReadableBucket(client, bucketName) {
+				return false, fmt.Errorf("%w: %s", ErrBucketOwnedByAnotherAccount, bucketName)
+			}
 		}
 	}
 
-	return false, nil
+	if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == s3.ErrCodeNoSuchBucket || aerr.Code() == "NotFound") {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("failed to look up S3 bucket %s: %w", bucketName, err)
+}
+
+// func to verify if the caller can read the bucket whoever owns it
+func isReadableBucket(client S3Client, bucketName string) bool {
+	_, err := client.HeadBucket(&s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	})
+
+	return err == nil
 }
 
 // func to verify if the given bucket is provided
 func checkIfBucketNameIsProvided(bucketName string) (bool, error) {
 	if bucketName == "" {
-		fmt.Printf("Error: %v\n", "bucket name is not provided")
+		logging.Errorf("bucket name is not provided")
 		return false, fmt.Errorf("bucket name is not provided")
 	}
 
 	isBucketNameValid, err := checkBucketNameCompliance(bucketName)
 	if !isBucketNameValid {
-		fmt.Printf("Error: %v\n", err)
+		logging.Errorf("%v", err)
 		return false, err
 	}
 
@@ -159,14 +194,14 @@ func checkBucketNameCompliance(bucketName string) (bool, error) {
 }
 
 // func to create given bucket if it doesn't exist'
-func createBucket(client S3Client, bucketName string, aftManagementAccountID string, kmsKeyID string, codeBuildRole string) (bool, error) {
+func createBucket(client S3Client, bucketName string, aftManagementAccountID string, kmsKeyID string, codeBuildRoleArn string) (bool, error) {
 
 	_, err := client.CreateBucket(&s3.CreateBucketInput{
 		Bucket: aws.String(bucketName),
 	})
 
 	if err != nil {
-		log.Printf("unable to create bucket %q, %v", bucketName, err)
+		logging.Errorf("unable to create bucket %q, %v", bucketName, err)
 		return false, err
 	}
 
@@ -179,63 +214,19 @@ func createBucket(client S3Client, bucketName string, aftManagementAccountID str
 	})
 
 	if err != nil {
-		log.Printf("error occurred while waiting for bucket to be created, %v: %v", bucketName, err)
+		logging.Errorf("error occurred while waiting for bucket to be created, %v: %v", bucketName, err)
 		return false, err
 	}
 
 	_, err = client.PutPublicAccessBlock(&s3.PutPublicAccessBlockInput{
-		Bucket: aws.String(bucketName),
-		PublicAccessBlockConfiguration: &s3.PublicAccessBlockConfiguration{
-			BlockPublicAcls:       aws.Bool(true),
-			IgnorePublicAcls:      aws.Bool(true),
-			BlockPublicPolicy:     aws.Bool(true),
-			RestrictPublicBuckets: aws.Bool(true),
-		},
+		Bucket:                         aws.String(bucketName),
+		PublicAccessBlockConfiguration: newPublicAccessBlockConfiguration(),
 	})
 
 	if err != nil {
 		return false, err
 	}
 
-	// WriteAndListPolicyTemplateForAccount is the default bucket policy to be used in new buckets
-	const WriteAndListPolicyTemplateForAccount = `{
-		"Version": "2012-10-17",
-		"Statement": [
-			{
-				"Sid": "AllowAccountWriteAndList",
-				"Effect": "Allow",
-				"Principal": {
-					"AWS": "arn:aws:iam::%s:root"
-				},
-				"Action": [
-					"s3:PutObject",
-					"s3:PutObjectAcl",
-					"s3:ListBucket"
-				],
-				"Resource": [
-					"arn:aws:s3:::%s/*",
-					"arn:aws:s3:::%s"
-				]
-			},
-			{
-				"Sid": "AllowCodeBuild",
-				"Effect": "Allow",
-				"Principal": {
-					"AWS": "arn:aws:iam::%s:role/%s"
-				},
-				"Action": [
-					"s3:PutObject",
-					"s3:PutObjectAcl",
-					"s3:ListBucket"
-				],
-				"Resource": [
-					"arn:aws:s3:::%s/*",
-					"arn:aws:s3:::%s"
-				]
-			}			
-		]
-	}`
-
 	// retries to put the bucket policy due API consistency
 	const maxRetries = 5
 	const initialDelay = 10
@@ -249,7 +240,7 @@ func createBucket(client S3Client, bucketName string, aftManagementAccountID str
 
 		_, err = client.PutBucketPolicy(&s3.PutBucketPolicyInput{
 			Bucket: aws.String(bucketName),
-			Policy: aws.String(fmt.Sprintf(WriteAndListPolicyTemplateForAccount, aftManagementAccountID, bucketName, bucketName, aftManagementAccountID, codeBuildRole, bucketName, bucketName)),
+			Policy: aws.String(newBucketPolicy(bucketName, aftManagementAccountID, codeBuildRoleArn).String()),
 		})
 
 		if err == nil {
@@ -264,7 +255,7 @@ func createBucket(client S3Client, bucketName string, aftManagementAccountID str
 	}
 
 	if lastErr != nil {
-		fmt.Printf("Error: %v\n", lastErr)
+		logging.Errorf("%v", lastErr)
 		return false, lastErr
 	}
 
@@ -288,3 +279,47 @@ func createBucket(client S3Client, bucketName string, aftManagementAccountID str
 
 	return true, nil
 }
+
+// newPublicAccessBlockConfiguration blocks every kind of public access to the bucket
+func newPublicAccessBlockConfiguration() *s3.PublicAccessBlockConfiguration {
+	return &s3.PublicAccessBlockConfiguration{
+		BlockPublicAcls:       aws.Bool(true),
+		IgnorePublicAcls:      aws.Bool(true),
+		BlockPublicPolicy:     aws.Bool(true),
+		RestrictPublicBuckets: aws.Bool(true),
+	}
+}
+
+// newBucketPolicy is the default bucket policy of new buckets, letting the account and the CodeBuild role write and list
+func newBucketPolicy(bucketName string, aftManagementAccountID string, codeBuildRoleArn string) PolicyDocument {
+	actions := []string{
+		"s3:PutObject",
+		"s3:PutObjectAcl",
+		"s3:ListBucket",
+	}
+
+	resources := []string{
+		fmt.Sprintf("arn:aws:s3:::%s/*", bucketName),
+		fmt.Sprintf("arn:aws:s3:::%s", bucketName),
+	}
+
+	return PolicyDocument{
+		Version: policyVersion,
+		Statement: []PolicyStatement{
+			{
+				Sid:       "AllowAccountWriteAndList",
+				Effect:    "Allow",
+				Principal: map[string]string{"AWS": fmt.Sprintf("arn:aws:iam::%s:root", aftManagementAccountID)},
+				Action:    actions,
+				Resource:  resources,
+			},
+			{
+				Sid:       "AllowCodeBuild",
+				Effect:    "Allow",
+				Principal: map[string]string{"AWS": codeBuildRoleArn},
+				Action:    actions,
+				Resource:  resources,
+			},
+		},
+	}
+}
@@ -25,3 +25,30 @@ func GetSSMParameter(client SSMClient, paramName string) (string, error) {
 
 	return *result.Parameter.Value, nil
 }
+
+// GetSSMParametersByPath returns the values of the parameters under the path and its sub paths by name, in as few
+// calls as the pages allow. The SecureString parameters are left out, their values are read with GetSSMParameter
+// when needed so that they're never cached.
+func GetSSMParametersByPath(client SSMClient, path string) (map[string]string, error) {
+	params := map[string]string{}
+
+	input := &ssm.GetParametersByPathInput{
+		Path:      aws.String(path),
+		Recursive: aws.Bool(true),
+	}
+
+	err := client.GetParametersByPathPages(input, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, parameter := range page.Parameters {
+			if aws.StringValue(parameter.Type) == ssm.ParameterTypeSecureString {
+				continue
+			}
+			params[aws.StringValue(parameter.Name)] = aws.StringValue(parameter.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// ListRoots is a mock implementation of the ListRoots method returning the Roots.
+func (m *MockOrganizationsClient) ListRoots(input *organizations.ListRootsInput) (*organizations.ListRootsOutput, error) {
+	return &organizations.ListRootsOutput{Roots: m.Roots}, nil
+}
+
+// ListOrganizationalUnitsForParentPages is a mock implementation of the ListOrganizationalUnitsForParentPages method
+// returning the Units of the parent.
+func (m *MockOrganizationsClient) ListOrganizationalUnitsForParentPages(input *organizations.ListOrganizationalUnitsForParentInput, fn func(*organizations.ListOrganizationalUnitsForParentOutput, bool) bool) error {
+	fn(&organizations.ListOrganizationalUnitsForParentOutput{OrganizationalUnits: m.Units[aws.StringValue(input.ParentId)]}, true)
+	return nil
+}
+
+// ListAccountsForParentPages is a mock implementation of the ListAccountsForParentPages method returning the
+// ParentAccounts of the parent.
+func (m *MockOrganizationsClient) ListAccountsForParentPages(input *organizations.ListAccountsForParentInput, fn func(*organizations.ListAccountsForParentOutput, bool) bool) error {
+	fn(&organizations.ListAccountsForParentOutput{Accounts: m.ParentAccounts[aws.StringValue(input.ParentId)]}, true)
+	return nil
+}
+
+// ListTagsForResourcePages is a mock implementation of the ListTagsForResourcePages method returning the Tags of the
+// account.
+func (m *MockOrganizationsClient) ListTagsForResourcePages(input *organizations.ListTagsForResourceInput, fn func(*organizations.ListTagsForResourceOutput, bool) bool) error {
+	fn(&organizations.ListTagsForResourceOutput{Tags: m.Tags[aws.StringValue(input.ResourceId)]}, true)
+	return nil
+}
+
+// MockDynamoDBClient is a mock implementation of a DynamoDB client for testing.
+type MockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	ScanPagesFunc func(*dynamodb.ScanInput, func(*dynamodb.ScanOutput, bool) bool) error
+}
+
+// ScanPages is a mock implementation of the ScanPages method.
+func (m *MockDynamoDBClient) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	return m.ScanPagesFunc(input, fn)
+}
+
+var _ = ginkgo.Describe("Listing the accounts", func() {
+
+	ginkgo.Context("testing the ListOrganizationAccounts function", func() {
+		ginkgo.When("accounts are in nested organizational units", func() {
+			ginkgo.It("should return the active accounts with their OU path and tags", func() {
+				active := aws.String(organizations.AccountStatusActive)
+				mockClient := &MockOrganizationsClient{
+					Roots: []*organizations.Root{{Id: aws.String("r-1"), Name: aws.String("Root")}},
+					Units: map[string][]*organizations.OrganizationalUnit{
+						"r-1":  {{Id: aws.String("ou-1"), Name: aws.String("Workloads")}},
+						"ou-1": {{Id: aws.String("ou-2"), Name: aws.String("Sandbox")}},
+					},
+					ParentAccounts: map[string][]*organizations.Account{
+						"r-1": {{Id: aws.String("111111111111"), Name: aws.String("Management"), Email: aws.String("root@example.com"), Status: active}},
+						"ou-2": {
+							{Id: aws.String("222222222222"), Name: aws.String("sandbox-a"), Email: aws.String("a@example.com"), Status: active},
+							{Id: aws.String("333333333333"), Name: aws.String("sandbox-b"), Status: aws.String(organizations.AccountStatusSuspended)},
+						},
+					},
+					Tags: map[string][]*organizations.Tag{
+						"222222222222": {{Key: aws.String("env"), Value: aws.String("dev")}},
+					},
+				}
+
+				accounts, err := ListOrganizationAccounts(mockClient)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(accounts).To(gomega.Equal([]Account{
+					{ID: "111111111111", Name: "Management", Email: "root@example.com", OUPath: "Root", Tags: map[string]string{}},
+					{ID: "222222222222", Name: "sandbox-a", Email: "a@example.com", OUPath: "Root/Workloads/Sandbox", Tags: map[string]string{"env": "dev"}},
+				}))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the GetAccountCustomizationsNames function", func() {
+		ginkgo.When("the table is scanned", func() {
+			ginkgo.It("should return the customization of the accounts by ID", func() {
+				mockClient := &MockDynamoDBClient{
+					ScanPagesFunc: func(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+						gomega.Expect(aws.StringValue(input.TableName)).To(gomega.Equal("aft-request-metadata"))
+						fn(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{
+							{"id": {S: aws.String("222222222222")}, "account_customizations_name": {S: aws.String("sandbox")}},
+							{"id": {S: aws.String("444444444444")}},
+						}}, true)
+						return nil
+					},
+				}
+
+				names, err := GetAccountCustomizationsNames(mockClient, "aft-request-metadata")
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(names).To(gomega.Equal(map[string]string{"222222222222": "sandbox"}))
+			})
+		})
+
+		ginkgo.When("the table can't be scanned", func() {
+			ginkgo.It("should return the error", func() {
+				mockClient := &MockDynamoDBClient{
+					ScanPagesFunc: func(*dynamodb.ScanInput, func(*dynamodb.ScanOutput, bool) bool) error {
+						return errors.New("access denied")
+					},
+				}
+
+				_, err := GetAccountCustomizationsNames(mockClient, "aft-request-metadata")
+				gomega.Expect(err).To(gomega.MatchError("access denied"))
+			})
+		})
+	})
+})
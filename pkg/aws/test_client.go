@@ -6,11 +6,15 @@ Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 package aws
 
 import (
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
 	"github.com/aws/aws-sdk-go/service/codebuild/codebuildiface"
 	"github.com/aws/aws-sdk-go/service/codecommit/codecommitiface"
 	"github.com/aws/aws-sdk-go/service/codepipeline/codepipelineiface"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	ginkgo "github.com/onsi/ginkgo/v2"
 	gomega "github.com/onsi/gomega"
@@ -118,4 +122,33 @@ var _ = ginkgo.Describe("Interacting with AWS API", func() {
 			})
 		})
 	})
+
+	// Context for creating the AWS Clients
+	ginkgo.Context("Creating AWS Clients", func() {
+
+		ginkgo.BeforeEach(func() {
+			ginkgo.GinkgoT().Setenv("AWS_ACCESS_KEY_ID", "test")
+			ginkgo.GinkgoT().Setenv("AWS_SECRET_ACCESS_KEY", "test")
+			ginkgo.GinkgoT().Setenv("AWS_REGION", "us-east-1")
+			ginkgo.GinkgoT().Setenv("AWS_PROFILE", "")
+			ginkgo.GinkgoT().Setenv("AWS_CONFIG_FILE", filepath.Join(ginkgo.GinkgoT().TempDir(), "config"))
+			ginkgo.GinkgoT().Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(ginkgo.GinkgoT().TempDir(), "credentials"))
+		})
+
+		ginkgo.When("NewClient is called with a region", func() {
+			ginkgo.It("should use it instead of the environment region", func() {
+				client := NewClient(ClientOptions{Region: "sa-east-1"})
+				gomega.Expect(client.GetRegion()).To(gomega.Equal("sa-east-1"))
+			})
+		})
+
+		ginkgo.When("NewClient is called with an endpoint URL", func() {
+			ginkgo.It("should send the requests to the endpoint", func() {
+				client := NewClient(ClientOptions{EndpointURL: "http://localhost:4566"})
+				gomega.Expect(client.GetRegion()).To(gomega.Equal("us-east-1"))
+				gomega.Expect(client.GetS3Client().(*s3.S3).Endpoint).To(gomega.Equal("http://localhost:4566"))
+				gomega.Expect(aws.BoolValue(client.GetS3Client().(*s3.S3).Config.S3ForcePathStyle)).To(gomega.BeTrue())
+			})
+		})
+	})
 })
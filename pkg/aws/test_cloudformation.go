@@ -20,6 +20,13 @@ type MockCloudformationClient struct {
 
 	CreateStackFunc    func(*cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error)
 	DescribeStacksFunc func(*cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
+
+	DescribeStackSetFunc func(*cloudformation.DescribeStackSetInput) (*cloudformation.DescribeStackSetOutput, error)
+}
+
+// DescribeStackSet is a mock implementation of the DescribeStackSet method.
+func (m *MockCloudformationClient) DescribeStackSet(input *cloudformation.DescribeStackSetInput) (*cloudformation.DescribeStackSetOutput, error) {
+	return m.DescribeStackSetFunc(input)
 }
 
 // DescribeStacks is a mock implementation of the DescribeStacks method.
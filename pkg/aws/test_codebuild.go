@@ -6,6 +6,8 @@ Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 package aws
 
 import (
+	"errors"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/codebuild"
 	"github.com/aws/aws-sdk-go/service/codebuild/codebuildiface"
@@ -17,8 +19,10 @@ import (
 type MockCodeBuildClient struct {
 	codebuildiface.CodeBuildAPI
 
-	CreateProjectFunc func(*codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error)
-	ListProjectsFunc  func(*codebuild.ListProjectsInput) (*codebuild.ListProjectsOutput, error)
+	CreateProjectFunc    func(*codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error)
+	ListProjectsFunc     func(*codebuild.ListProjectsInput) (*codebuild.ListProjectsOutput, error)
+	BatchGetProjectsFunc func(*codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error)
+	UpdateProjectFunc    func(*codebuild.UpdateProjectInput) (*codebuild.UpdateProjectOutput, error)
 }
 
 // ListProjects is a mock implementation of the ListProjects method.
@@ -26,11 +30,34 @@ func (m *MockCodeBuildClient) ListProjects(input *codebuild.ListProjectsInput) (
 	return m.ListProjectsFunc(input)
 }
 
+// BatchGetProjects is a mock implementation of the BatchGetProjects method.
+func (m *MockCodeBuildClient) BatchGetProjects(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+	return m.BatchGetProjectsFunc(input)
+}
+
 // CreateProject is a mock implementation of the CreateProject method.
 func (m *MockCodeBuildClient) CreateProject(input *codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error) {
 	return m.CreateProjectFunc(input)
 }
 
+// UpdateProject is a mock implementation of the UpdateProject method.
+func (m *MockCodeBuildClient) UpdateProject(input *codebuild.UpdateProjectInput) (*codebuild.UpdateProjectOutput, error) {
+	if m.UpdateProjectFunc != nil {
+		return m.UpdateProjectFunc(input)
+	}
+	return &codebuild.UpdateProjectOutput{}, nil
+}
+
+// newTestCodeBuildProjectConfig returns a valid project configuration for testing.
+func newTestCodeBuildProjectConfig() CodeBuildProjectConfig {
+	return CodeBuildProjectConfig{
+		Image:           "test-docker-image",
+		ComputeType:     codebuild.ComputeTypeBuildGeneral1Small,
+		EnvironmentType: codebuild.EnvironmentTypeLinuxContainer,
+		PrivilegedMode:  true,
+	}
+}
+
 var _ = ginkgo.Describe("Interacting with the CodeBuild API", func() {
 
 	ginkgo.Context("testing the EnsureCodeBuildProjectExists function", func() {
@@ -42,15 +69,104 @@ var _ = ginkgo.Describe("Interacting with the CodeBuild API", func() {
 					CreateProjectFunc: func(input *codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error) {
 						return &codebuild.CreateProjectOutput{}, nil
 					},
-					ListProjectsFunc: func(input *codebuild.ListProjectsInput) (*codebuild.ListProjectsOutput, error) {
-						return &codebuild.ListProjectsOutput{}, nil
+					BatchGetProjectsFunc: func(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+						return &codebuild.BatchGetProjectsOutput{
+							Projects: []*codebuild.Project{
+								{Name: aws.String("test-project")},
+							},
+						}, nil
 					},
 				}
 
-				ensure, err := EnsureCodeBuildProjectExists(mockClient, "000000000000", "test-docker-image", "test-project", "test-repo", "test-branch", "test-role")
+				ensure, err := EnsureCodeBuildProjectExists(mockClient, "000000000000", "test-project", "test-repo", "test-branch", "test-role", "/", newTestCodeBuildProjectConfig())
+
+				gomega.Expect(ensure).To(gomega.BeTrue())
+				gomega.Expect(err).To(gomega.BeNil())
+			})
+		})
+
+		ginkgo.When("the project exists with another configuration", func() {
+			ginkgo.It("should update the project to the configuration", func() {
+
+				var updateInput *codebuild.UpdateProjectInput
+
+				mockClient := &MockCodeBuildClient{
+					BatchGetProjectsFunc: func(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+						return &codebuild.BatchGetProjectsOutput{
+							Projects: []*codebuild.Project{
+								{Name: aws.String("test-project")},
+							},
+						}, nil
+					},
+					UpdateProjectFunc: func(input *codebuild.UpdateProjectInput) (*codebuild.UpdateProjectOutput, error) {
+						updateInput = input
+						return &codebuild.UpdateProjectOutput{}, nil
+					},
+				}
+
+				projectConfig := newTestCodeBuildProjectConfig()
+				projectConfig.BuildTimeout = 90
+				projectConfig.VpcID = "vpc-0a1b2c"
+				projectConfig.Subnets = []string{"subnet-0a1b2c"}
+				projectConfig.SecurityGroupIDs = []string{"sg-0a1b2c"}
+
+				ensure, err := EnsureCodeBuildProjectExists(mockClient, "000000000000", "test-project", "test-repo", "test-branch", "test-role", "/platform/", projectConfig)
 
 				gomega.Expect(ensure).To(gomega.BeTrue())
 				gomega.Expect(err).To(gomega.BeNil())
+
+				createInput := newCodeBuildProjectInput("test-project", "test-repo", "test-branch", "arn:aws:iam::000000000000:role/platform/test-role", projectConfig)
+				gomega.Expect(*updateInput.Name).To(gomega.Equal("test-project"))
+				gomega.Expect(updateInput.Environment).To(gomega.Equal(createInput.Environment))
+				gomega.Expect(updateInput.VpcConfig).To(gomega.Equal(createInput.VpcConfig))
+				gomega.Expect(updateInput.Cache).To(gomega.Equal(createInput.Cache))
+				gomega.Expect(*updateInput.ServiceRole).To(gomega.Equal("arn:aws:iam::000000000000:role/platform/test-role"))
+				gomega.Expect(*updateInput.TimeoutInMinutes).To(gomega.Equal(int64(90)))
+				gomega.Expect(updateInput.Tags).To(gomega.BeNil())
+			})
+		})
+
+		ginkgo.When("the project can't be updated", func() {
+			ginkgo.It("should return the error", func() {
+
+				mockClient := &MockCodeBuildClient{
+					BatchGetProjectsFunc: func(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+						return &codebuild.BatchGetProjectsOutput{
+							Projects: []*codebuild.Project{
+								{Name: aws.String("test-project")},
+							},
+						}, nil
+					},
+					UpdateProjectFunc: func(input *codebuild.UpdateProjectInput) (*codebuild.UpdateProjectOutput, error) {
+						return nil, errors.New("access denied")
+					},
+				}
+
+				ensure, err := EnsureCodeBuildProjectExists(mockClient, "000000000000", "test-project", "test-repo", "test-branch", "test-role", "/", newTestCodeBuildProjectConfig())
+
+				gomega.Expect(ensure).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("access denied"))
+			})
+		})
+
+		ginkgo.When("the project can't be created", func() {
+			ginkgo.It("should return the error", func() {
+
+				mockClient := &MockCodeBuildClient{
+					CreateProjectFunc: func(input *codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error) {
+						return nil, errors.New("access denied")
+					},
+					BatchGetProjectsFunc: func(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+						return &codebuild.BatchGetProjectsOutput{
+							ProjectsNotFound: input.Names,
+						}, nil
+					},
+				}
+
+				ensure, err := EnsureCodeBuildProjectExists(mockClient, "000000000000", "test-project", "test-repo", "test-branch", "test-role", "/", newTestCodeBuildProjectConfig())
+
+				gomega.Expect(ensure).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("access denied"))
 			})
 		})
 
@@ -61,12 +177,14 @@ var _ = ginkgo.Describe("Interacting with the CodeBuild API", func() {
 					CreateProjectFunc: func(input *codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error) {
 						return &codebuild.CreateProjectOutput{}, nil
 					},
-					ListProjectsFunc: func(input *codebuild.ListProjectsInput) (*codebuild.ListProjectsOutput, error) {
-						return &codebuild.ListProjectsOutput{}, nil
+					BatchGetProjectsFunc: func(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+						return &codebuild.BatchGetProjectsOutput{
+							ProjectsNotFound: input.Names,
+						}, nil
 					},
 				}
 
-				ensure, err := EnsureCodeBuildProjectExists(mockClient, "000000000000", "test-docker-image", "test-project", "test-repo", "test-branch", "test-role")
+				ensure, err := EnsureCodeBuildProjectExists(mockClient, "000000000000", "test-project", "test-repo", "test-branch", "test-role", "/", newTestCodeBuildProjectConfig())
 
 				gomega.Expect(ensure).To(gomega.BeTrue())
 				gomega.Expect(err).To(gomega.BeNil())
@@ -75,6 +193,69 @@ var _ = ginkgo.Describe("Interacting with the CodeBuild API", func() {
 
 	})
 
+	ginkgo.Context("testing the createCodeBuildProject function", func() {
+		ginkgo.When("the service role lives under a path", func() {
+			ginkgo.It("should reference the role arn with its path", func() {
+
+				var serviceRole string
+
+				mockClient := &MockCodeBuildClient{
+					CreateProjectFunc: func(input *codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error) {
+						serviceRole = *input.ServiceRole
+						return &codebuild.CreateProjectOutput{}, nil
+					},
+				}
+
+				_, err := createCodeBuildProject(mockClient, "000000000000", "test-project", "test-repo", "test-branch", "test-role", "/platform/", newTestCodeBuildProjectConfig())
+
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(serviceRole).To(gomega.Equal("arn:aws:iam::000000000000:role/platform/test-role"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the project configuration in createCodeBuildProject", func() {
+		ginkgo.When("the build runs in a VPC with custom logs and cache", func() {
+			ginkgo.It("should pass the configuration to CodeBuild", func() {
+
+				var createInput *codebuild.CreateProjectInput
+
+				mockClient := &MockCodeBuildClient{
+					CreateProjectFunc: func(input *codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error) {
+						createInput = input
+						return &codebuild.CreateProjectOutput{}, nil
+					},
+				}
+
+				projectConfig := newTestCodeBuildProjectConfig()
+				projectConfig.EnvironmentType = codebuild.EnvironmentTypeArmContainer
+				projectConfig.Image = "aws/codebuild/amazonlinux2-aarch64-standard:3.0"
+				projectConfig.BuildTimeout = 90
+				projectConfig.VpcID = "vpc-0a1b2c"
+				projectConfig.Subnets = []string{"subnet-0a1b2c"}
+				projectConfig.SecurityGroupIDs = []string{"sg-0a1b2c"}
+				projectConfig.LogGroupName = "/platform/aft"
+				projectConfig.ParameterStoreVariables = map[string]string{"TFE_TOKEN": "/platform/tfe-token"}
+				projectConfig.PluginCacheDir = "/root/.terraform.d/plugin-cache"
+
+				_, err := createCodeBuildProject(mockClient, "000000000000", "test-project", "test-repo", "test-branch", "test-role", "/", projectConfig)
+
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(*createInput.Environment.Type).To(gomega.Equal(codebuild.EnvironmentTypeArmContainer))
+				gomega.Expect(*createInput.TimeoutInMinutes).To(gomega.Equal(int64(90)))
+				gomega.Expect(createInput.QueuedTimeoutInMinutes).To(gomega.BeNil())
+				gomega.Expect(*createInput.VpcConfig.VpcId).To(gomega.Equal("vpc-0a1b2c"))
+				gomega.Expect(*createInput.LogsConfig.CloudWatchLogs.GroupName).To(gomega.Equal("/platform/aft"))
+				gomega.Expect(*createInput.Cache.Type).To(gomega.Equal(codebuild.CacheTypeLocal))
+
+				variables := createInput.Environment.EnvironmentVariables
+				gomega.Expect(variables).To(gomega.HaveLen(4))
+				gomega.Expect(*variables[2].Name).To(gomega.Equal("TF_PLUGIN_CACHE_DIR"))
+				gomega.Expect(*variables[3].Type).To(gomega.Equal(codebuild.EnvironmentVariableTypeParameterStore))
+			})
+		})
+	})
+
 	ginkgo.Context("testing the checkIfCodeBuildClientIsProvided", func() {
 		ginkgo.When("CodeBuildClient is not provided", func() {
 			ginkgo.It("should return an error", func() {
@@ -125,13 +306,13 @@ var _ = ginkgo.Describe("Interacting with the CodeBuild API", func() {
 
 	ginkgo.Context("testing the checkIfProjectExists function", func() {
 		ginkgo.When("if the project already exists", func() {
-			ginkgo.It("should return an error", func() {
+			ginkgo.It("should return true", func() {
 
 				mockClient := &MockCodeBuildClient{
-					ListProjectsFunc: func(input *codebuild.ListProjectsInput) (*codebuild.ListProjectsOutput, error) {
-						return &codebuild.ListProjectsOutput{
-							Projects: []*string{
-								aws.String("one-project"),
+					BatchGetProjectsFunc: func(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+						return &codebuild.BatchGetProjectsOutput{
+							Projects: []*codebuild.Project{
+								{Name: aws.String("one-project")},
 							},
 						}, nil
 					},
@@ -143,13 +324,13 @@ var _ = ginkgo.Describe("Interacting with the CodeBuild API", func() {
 			})
 		})
 
-		ginkgo.When("list operation fails", func() {
-			ginkgo.It("should return an error", func() {
+		ginkgo.When("the project is not found", func() {
+			ginkgo.It("should return false", func() {
 				mockClient := &MockCodeBuildClient{
-					ListProjectsFunc: func(input *codebuild.ListProjectsInput) (*codebuild.ListProjectsOutput, error) {
-						return &codebuild.ListProjectsOutput{
-							Projects: []*string{
-								aws.String("any-project"),
+					BatchGetProjectsFunc: func(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+						return &codebuild.BatchGetProjectsOutput{
+							ProjectsNotFound: []*string{
+								aws.String("one-project"),
 							},
 						}, nil
 					},
@@ -160,5 +341,20 @@ var _ = ginkgo.Describe("Interacting with the CodeBuild API", func() {
 				gomega.Expect(err).To(gomega.BeNil())
 			})
 		})
+
+		ginkgo.When("the lookup fails", func() {
+			ginkgo.It("should return an error", func() {
+				mockClient := &MockCodeBuildClient{
+					BatchGetProjectsFunc: func(input *codebuild.BatchGetProjectsInput) (*codebuild.BatchGetProjectsOutput, error) {
+						return nil, errors.New("AccessDenied")
+					},
+				}
+
+				exists, err := checkIfProjectExists(mockClient, "one-project")
+				gomega.Expect(exists).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("AccessDenied"))
+			})
+		})
 	})
+
 })
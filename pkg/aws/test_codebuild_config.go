@@ -0,0 +1,107 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package aws contains tests for aws clients and session.
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/service/codebuild"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Validating the CodeBuild project configuration", func() {
+
+	var projectConfig CodeBuildProjectConfig
+
+	ginkgo.BeforeEach(func() {
+		projectConfig = newTestCodeBuildProjectConfig()
+	})
+
+	ginkgo.When("the configuration is the default one", func() {
+		ginkgo.It("should be valid", func() {
+			gomega.Expect(projectConfig.Validate()).To(gomega.Succeed())
+		})
+	})
+
+	ginkgo.When("the compute type isn't available for ARM", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.EnvironmentType = codebuild.EnvironmentTypeArmContainer
+			projectConfig.ComputeType = codebuild.ComputeTypeBuildGeneral1Medium
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError(gomega.ContainSubstring("is not available for ARM_CONTAINER")))
+		})
+	})
+
+	ginkgo.When("the environment type is unknown", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.EnvironmentType = "WINDOWS_CONTAINER"
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError("codebuild environment type must be LINUX_CONTAINER or ARM_CONTAINER"))
+		})
+	})
+
+	ginkgo.When("the build timeout is out of range", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.BuildTimeout = 600
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError("codebuild build timeout must be between 5 and 480 minutes"))
+		})
+	})
+
+	ginkgo.When("the vpc config is incomplete", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.VpcID = "vpc-0a1b2c"
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError("codebuild vpc config requires a vpc id, at least one subnet and at least one security group"))
+		})
+	})
+
+	ginkgo.When("a subnet id is malformed", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.VpcID = "vpc-0a1b2c"
+			projectConfig.Subnets = []string{"private-a"}
+			projectConfig.SecurityGroupIDs = []string{"sg-0a1b2c"}
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError(`invalid subnet id "private-a"`))
+		})
+	})
+
+	ginkgo.When("a log stream is set without a log group", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.LogStreamName = "aft"
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError("codebuild log stream requires a log group"))
+		})
+	})
+
+	ginkgo.When("the s3 logs bucket name is invalid", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.S3LogsLocation = "Logs_Bucket/aft"
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError(gomega.ContainSubstring("invalid codebuild s3 logs location")))
+		})
+	})
+
+	ginkgo.When("an environment variable overrides a reserved one", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.SecretsManagerVariables = map[string]string{"REPOSITORY_NAME": "secret"}
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError("codebuild environment variable REPOSITORY_NAME is defined more than once"))
+		})
+	})
+
+	ginkgo.When("an environment variable name is invalid", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.PlainTextVariables = map[string]string{"1VAR": "value"}
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError(`invalid codebuild environment variable name "1VAR"`))
+		})
+	})
+
+	ginkgo.When("a secret arn is malformed", func() {
+		ginkgo.It("should return an error", func() {
+			projectConfig.SecretsManagerVariables = map[string]string{"TOKEN": "arn:aws:secretsmanager:us-east-1"}
+			gomega.Expect(projectConfig.Validate()).To(gomega.MatchError(`codebuild environment variable TOKEN has an invalid secret arn "arn:aws:secretsmanager:us-east-1"`))
+		})
+	})
+
+	ginkgo.When("several variables read the same secret", func() {
+		ginkgo.It("should list the secret once", func() {
+			projectConfig.SecretsManagerVariables = map[string]string{"USER": "registry", "TOKEN": "registry", "OTHER": "other"}
+			gomega.Expect(projectConfig.SecretIDs()).To(gomega.Equal([]string{"other", "registry"}))
+		})
+	})
+})
@@ -6,6 +6,9 @@ Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 package aws
 
 import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/codepipeline"
 	"github.com/aws/aws-sdk-go/service/codepipeline/codepipelineiface"
 	ginkgo "github.com/onsi/ginkgo/v2"
@@ -16,7 +19,7 @@ import (
 type MockCodePipelineClient struct {
 	codepipelineiface.CodePipelineAPI
 	CreatePipelineFunc func(*codepipeline.CreatePipelineInput) (*codepipeline.CreatePipelineOutput, error)
-	ListPipelinesFunc  func(*codepipeline.ListPipelinesInput) (*codepipeline.ListPipelinesOutput, error)
+	GetPipelineFunc    func(*codepipeline.GetPipelineInput) (*codepipeline.GetPipelineOutput, error)
 }
 
 // CreatePipeline is a mock implementation of the CreatePipeline method.
@@ -24,6 +27,11 @@ func (m *MockCodePipelineClient) CreatePipeline(input *codepipeline.CreatePipeli
 	return m.CreatePipelineFunc(input)
 }
 
+// GetPipeline is a mock implementation of the GetPipeline method.
+func (m *MockCodePipelineClient) GetPipeline(input *codepipeline.GetPipelineInput) (*codepipeline.GetPipelineOutput, error) {
+	return m.GetPipelineFunc(input)
+}
+
 var _ = ginkgo.Describe("Interacting with the CodePipeline API", func() {
 
 	ginkgo.Context("testing the checkIfCodePipelineClientIsProvided", func() {
@@ -74,4 +82,50 @@ var _ = ginkgo.Describe("Interacting with the CodePipeline API", func() {
 
 	})
 
+	ginkgo.Context("testing the checkIfPipelineExists function", func() {
+		ginkgo.When("the pipeline exists", func() {
+			ginkgo.It("should return true", func() {
+				mockClient := &MockCodePipelineClient{
+					GetPipelineFunc: func(input *codepipeline.GetPipelineInput) (*codepipeline.GetPipelineOutput, error) {
+						return &codepipeline.GetPipelineOutput{
+							Pipeline: &codepipeline.PipelineDeclaration{Name: input.Name},
+						}, nil
+					},
+				}
+
+				exists, err := checkIfPipelineExists(mockClient, "one-pipeline")
+				gomega.Expect(exists).To(gomega.BeTrue())
+				gomega.Expect(err).To(gomega.BeNil())
+			})
+		})
+
+		ginkgo.When("the pipeline is not found", func() {
+			ginkgo.It("should return false", func() {
+				mockClient := &MockCodePipelineClient{
+					GetPipelineFunc: func(input *codepipeline.GetPipelineInput) (*codepipeline.GetPipelineOutput, error) {
+						return nil, awserr.New(codepipeline.ErrCodePipelineNotFoundException, "pipeline not found", nil)
+					},
+				}
+
+				exists, err := checkIfPipelineExists(mockClient, "one-pipeline")
+				gomega.Expect(exists).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.BeNil())
+			})
+		})
+
+		ginkgo.When("the lookup fails", func() {
+			ginkgo.It("should return an error", func() {
+				mockClient := &MockCodePipelineClient{
+					GetPipelineFunc: func(input *codepipeline.GetPipelineInput) (*codepipeline.GetPipelineOutput, error) {
+						return nil, errors.New("AccessDenied")
+					},
+				}
+
+				exists, err := checkIfPipelineExists(mockClient, "one-pipeline")
+				gomega.Expect(exists).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("AccessDenied"))
+			})
+		})
+	})
+
 })
@@ -0,0 +1,106 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package aws contains tests for aws clients and session.
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codebuild"
+	"github.com/aws/aws-sdk-go/service/codepipeline"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Describing the deployment resources", func() {
+
+	deployment := Deployment{
+		Region:                 "us-east-1",
+		AftManagementAccountID: "123456789012",
+		PipelineRole: DeploymentRole{
+			Name:    "aft-deployment-codepipeline-service-role",
+			Options: RoleOptions{Path: "/platform/"},
+		},
+		CodeBuildRole: DeploymentRole{
+			Name:    "aft-deployment-codebuild-service-role",
+			Options: RoleOptions{Path: "/platform/"},
+		},
+		StateBucketName:    "123456789012-aft-deployment-terraform-tfstate",
+		ArtifactBucketName: "123456789012-aft-deployment-codepipeline-artifact",
+		Repository: DeploymentRepository{
+			Name:   "aft-deployment",
+			Branch: "main",
+		},
+		Project: DeploymentProject{
+			Name:   "aft-deployment-build",
+			Config: newTestCodeBuildProjectConfig(),
+		},
+		PipelineName: "aft-deployment-pipeline",
+	}
+
+	ginkgo.Context("testing the ProjectInput function", func() {
+		ginkgo.When("the project is created through the API", func() {
+			ginkgo.It("should match the described project", func() {
+				var createInput *codebuild.CreateProjectInput
+
+				mockClient := &MockCodeBuildClient{
+					CreateProjectFunc: func(input *codebuild.CreateProjectInput) (*codebuild.CreateProjectOutput, error) {
+						createInput = input
+						return &codebuild.CreateProjectOutput{}, nil
+					},
+				}
+
+				_, err := createCodeBuildProject(mockClient, "123456789012", "aft-deployment-build", "aft-deployment", "main", "aft-deployment-codebuild-service-role", "/platform/", newTestCodeBuildProjectConfig())
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(deployment.ProjectInput()).To(gomega.Equal(createInput))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the PipelineInput function", func() {
+		ginkgo.When("the pipeline is created through the API", func() {
+			ginkgo.It("should match the described pipeline", func() {
+				var createInput *codepipeline.CreatePipelineInput
+
+				mockClient := &MockCodePipelineClient{
+					CreatePipelineFunc: func(input *codepipeline.CreatePipelineInput) (*codepipeline.CreatePipelineOutput, error) {
+						createInput = input
+						return &codepipeline.CreatePipelineOutput{}, nil
+					},
+				}
+
+				_, err := createCodePipelinePipeline(mockClient, "123456789012", "aft-deployment-codepipeline-service-role", "/platform/", "aft-deployment-pipeline", "123456789012-aft-deployment-codepipeline-artifact", "aft-deployment", "main", "aft-deployment-build")
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(deployment.PipelineInput()).To(gomega.Equal(createInput))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the BucketPolicy function", func() {
+		ginkgo.When("the roles have a path", func() {
+			ginkgo.It("should grant the CodeBuild role including its path", func() {
+				policy := deployment.BucketPolicy(deployment.StateBucketName)
+				gomega.Expect(policy.Statement[1].Principal["AWS"]).To(gomega.Equal("arn:aws:iam::123456789012:role/platform/aft-deployment-codebuild-service-role"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the TopicArn function", func() {
+		ginkgo.When("the notifications are disabled", func() {
+			ginkgo.It("should return an empty arn", func() {
+				gomega.Expect(deployment.TopicArn()).To(gomega.BeEmpty())
+			})
+		})
+
+		ginkgo.When("the notifications are enabled", func() {
+			ginkgo.It("should return the arn of the topic", func() {
+				withNotifications := deployment
+				withNotifications.Notifications = &DeploymentNotifications{TopicName: "aft-deployment-notifications"}
+
+				gomega.Expect(withNotifications.TopicArn()).To(gomega.Equal("arn:aws:sns:us-east-1:123456789012:aft-deployment-notifications"))
+				gomega.Expect(aws.StringValue(withNotifications.NotificationRuleInput().Resource)).To(gomega.Equal("arn:aws:codepipeline:us-east-1:123456789012:aft-deployment-pipeline"))
+			})
+		})
+	})
+})
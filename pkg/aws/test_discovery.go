@@ -0,0 +1,183 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package aws
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/organizations/organizationsiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// MockDiscoverySTSClient is a mock implementation of an STS client for testing.
+type MockDiscoverySTSClient struct {
+	stsiface.STSAPI
+	GetCallerIdentityFunc func(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
+}
+
+// GetCallerIdentity is a mock implementation of the GetCallerIdentity method.
+func (m *MockDiscoverySTSClient) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return m.GetCallerIdentityFunc(input)
+}
+
+// MockOrganizationsClient is a mock implementation of an Organizations client for testing.
+type MockOrganizationsClient struct {
+	organizationsiface.OrganizationsAPI
+	DescribeOrganizationFunc func(*organizations.DescribeOrganizationInput) (*organizations.DescribeOrganizationOutput, error)
+	Pages                    []*organizations.ListAccountsOutput
+	Roots                    []*organizations.Root
+	Units                    map[string][]*organizations.OrganizationalUnit
+	ParentAccounts           map[string][]*organizations.Account
+	Tags                     map[string][]*organizations.Tag
+}
+
+// DescribeOrganization is a mock implementation of the DescribeOrganization method.
+func (m *MockOrganizationsClient) DescribeOrganization(input *organizations.DescribeOrganizationInput) (*organizations.DescribeOrganizationOutput, error) {
+	return m.DescribeOrganizationFunc(input)
+}
+
+// ListAccountsPages is a mock implementation of the ListAccountsPages method going through the Pages.
+func (m *MockOrganizationsClient) ListAccountsPages(input *organizations.ListAccountsInput, fn func(*organizations.ListAccountsOutput, bool) bool) error {
+	for i, page := range m.Pages {
+		if !fn(page, i == len(m.Pages)-1) {
+			break
+		}
+	}
+
+	return nil
+}
+
+var _ = ginkgo.Describe("Discovering the landing zone", func() {
+
+	ginkgo.Context("testing the GetCallerAccountID function", func() {
+		ginkgo.When("the caller identity is returned", func() {
+			ginkgo.It("should return its account", func() {
+				mockClient := &MockDiscoverySTSClient{
+					GetCallerIdentityFunc: func(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+						return &sts.GetCallerIdentityOutput{Account: aws.String("111111111111")}, nil
+					},
+				}
+
+				accountID, err := GetCallerAccountID(mockClient)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(accountID).To(gomega.Equal("111111111111"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the FindAccountIDByName function", func() {
+		mockClient := &MockOrganizationsClient{
+			Pages: []*organizations.ListAccountsOutput{
+				{Accounts: []*organizations.Account{{Id: aws.String("111111111111"), Name: aws.String("Management")}}},
+				{Accounts: []*organizations.Account{{Id: aws.String("222222222222"), Name: aws.String("Log Archive")}}},
+			},
+		}
+
+		ginkgo.When("the account is on a later page", func() {
+			ginkgo.It("should return its ID ignoring the case", func() {
+				accountID, err := FindAccountIDByName(mockClient, "log archive")
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(accountID).To(gomega.Equal("222222222222"))
+			})
+		})
+
+		ginkgo.When("the account doesn't exist", func() {
+			ginkgo.It("should return an error", func() {
+				_, err := FindAccountIDByName(mockClient, "Audit")
+				gomega.Expect(err).To(gomega.MatchError(`account "Audit" not found in the organization`))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the ListActiveAccountIDs function", func() {
+		ginkgo.When("the organization has suspended accounts", func() {
+			ginkgo.It("should only return the active accounts of every page", func() {
+				mockClient := &MockOrganizationsClient{
+					Pages: []*organizations.ListAccountsOutput{
+						{Accounts: []*organizations.Account{
+							{Id: aws.String("111111111111"), Status: aws.String(organizations.AccountStatusActive)},
+							{Id: aws.String("222222222222"), Status: aws.String(organizations.AccountStatusSuspended)},
+						}},
+						{Accounts: []*organizations.Account{
+							{Id: aws.String("333333333333"), Status: aws.String(organizations.AccountStatusActive)},
+						}},
+					},
+				}
+
+				accountIDs, err := ListActiveAccountIDs(mockClient)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(accountIDs).To(gomega.Equal([]string{"111111111111", "333333333333"}))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the GetManagementAccountID function", func() {
+		ginkgo.When("the organization is described", func() {
+			ginkgo.It("should return its management account", func() {
+				mockClient := &MockOrganizationsClient{
+					DescribeOrganizationFunc: func(*organizations.DescribeOrganizationInput) (*organizations.DescribeOrganizationOutput, error) {
+						return &organizations.DescribeOrganizationOutput{
+							Organization: &organizations.Organization{MasterAccountId: aws.String("111111111111")},
+						}, nil
+					},
+				}
+
+				accountID, err := GetManagementAccountID(mockClient)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(accountID).To(gomega.Equal("111111111111"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the IsControlTowerHomeRegion function", func() {
+		ginkgo.When("the baseline stack set exists", func() {
+			ginkgo.It("should return true", func() {
+				mockClient := &MockCloudformationClient{
+					DescribeStackSetFunc: func(input *cloudformation.DescribeStackSetInput) (*cloudformation.DescribeStackSetOutput, error) {
+						return &cloudformation.DescribeStackSetOutput{}, nil
+					},
+				}
+
+				homeRegion, err := IsControlTowerHomeRegion(mockClient)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(homeRegion).To(gomega.BeTrue())
+			})
+		})
+
+		ginkgo.When("the baseline stack set doesn't exist", func() {
+			ginkgo.It("should return false", func() {
+				mockClient := &MockCloudformationClient{
+					DescribeStackSetFunc: func(input *cloudformation.DescribeStackSetInput) (*cloudformation.DescribeStackSetOutput, error) {
+						return nil, awserr.New(cloudformation.ErrCodeStackSetNotFoundException, "not found", nil)
+					},
+				}
+
+				homeRegion, err := IsControlTowerHomeRegion(mockClient)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(homeRegion).To(gomega.BeFalse())
+			})
+		})
+
+		ginkgo.When("the stack set can't be described", func() {
+			ginkgo.It("should return the error", func() {
+				mockClient := &MockCloudformationClient{
+					DescribeStackSetFunc: func(input *cloudformation.DescribeStackSetInput) (*cloudformation.DescribeStackSetOutput, error) {
+						return nil, errors.New("access denied")
+					},
+				}
+
+				_, err := IsControlTowerHomeRegion(mockClient)
+				gomega.Expect(err).To(gomega.MatchError("access denied"))
+			})
+		})
+	})
+})
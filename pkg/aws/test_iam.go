@@ -6,7 +6,10 @@ Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 package aws
 
 import (
+	"errors"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	ginkgo "github.com/onsi/ginkgo/v2"
@@ -19,6 +22,11 @@ type MockIAMClient struct {
 	CreateRoleFunc    func(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error)
 	PutRolePolicyFunc func(input *iam.PutRolePolicyInput) (*iam.PutRolePolicyOutput, error)
 	GetRoleFunc       func(input *iam.GetRoleInput) (*iam.GetRoleOutput, error)
+
+	AttachRolePolicyFunc func(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error)
+
+	UpdateRoleFunc                 func(input *iam.UpdateRoleInput) (*iam.UpdateRoleOutput, error)
+	PutRolePermissionsBoundaryFunc func(input *iam.PutRolePermissionsBoundaryInput) (*iam.PutRolePermissionsBoundaryOutput, error)
 }
 
 // CreateRole is a mock implementation of the CreateRole method.
@@ -39,12 +47,36 @@ func (m *MockIAMClient) PutRolePolicy(input *iam.PutRolePolicyInput) (*iam.PutRo
 
 // GetRole is a mock implementation of the GetRoleFunc method.
 func (m *MockIAMClient) GetRole(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
-	if m.PutRolePolicyFunc != nil {
+	if m.GetRoleFunc != nil {
 		return m.GetRoleFunc(input)
 	}
 	return nil, nil
 }
 
+// AttachRolePolicy is a mock implementation of the AttachRolePolicy method.
+func (m *MockIAMClient) AttachRolePolicy(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error) {
+	if m.AttachRolePolicyFunc != nil {
+		return m.AttachRolePolicyFunc(input)
+	}
+	return nil, nil
+}
+
+// UpdateRole is a mock implementation of the UpdateRole method.
+func (m *MockIAMClient) UpdateRole(input *iam.UpdateRoleInput) (*iam.UpdateRoleOutput, error) {
+	if m.UpdateRoleFunc != nil {
+		return m.UpdateRoleFunc(input)
+	}
+	return nil, nil
+}
+
+// PutRolePermissionsBoundary is a mock implementation of the PutRolePermissionsBoundary method.
+func (m *MockIAMClient) PutRolePermissionsBoundary(input *iam.PutRolePermissionsBoundaryInput) (*iam.PutRolePermissionsBoundaryOutput, error) {
+	if m.PutRolePermissionsBoundaryFunc != nil {
+		return m.PutRolePermissionsBoundaryFunc(input)
+	}
+	return nil, nil
+}
+
 var _ = ginkgo.Describe("Interacting with the IAM API", func() {
 
 	ginkgo.Context("testing the EnsureIamRoleExists function", func() {
@@ -62,7 +94,7 @@ var _ = ginkgo.Describe("Interacting with the IAM API", func() {
 					},
 				}
 
-				roleExists, err := EnsureIamRoleExists(mockClient, "test-role", "test-policy", "test-bucket", "test-input", "test-input", "test-input", "test-input", "test-bucket")
+				roleExists, err := EnsureIamRoleExists(mockClient, "test-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, RoleOptions{})
 
 				gomega.Expect(roleExists).To(gomega.BeTrue())
 				gomega.Expect(err).To(gomega.BeNil())
@@ -70,10 +102,57 @@ var _ = ginkgo.Describe("Interacting with the IAM API", func() {
 			})
 		})
 
+		ginkgo.When("the role exists with an older inline policy", func() {
+			ginkgo.It("should replace the inline policy", func() {
+
+				var putInput *iam.PutRolePolicyInput
+				mockClient := &MockIAMClient{
+					GetRoleFunc: func(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+						return &iam.GetRoleOutput{Role: &iam.Role{RoleName: input.RoleName}}, nil
+					},
+					CreateRoleFunc: func(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+						return nil, errors.New("the role must not be created")
+					},
+					PutRolePolicyFunc: func(input *iam.PutRolePolicyInput) (*iam.PutRolePolicyOutput, error) {
+						putInput = input
+						return &iam.PutRolePolicyOutput{}, nil
+					},
+				}
+
+				policy := NewAssumeRolePolicy("codebuild.amazonaws.com")
+				roleExists, err := EnsureIamRoleExists(mockClient, "test-role", "codebuild.amazonaws.com", "test-policy", policy, nil, RoleOptions{})
+
+				gomega.Expect(roleExists).To(gomega.BeTrue())
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(*putInput.RoleName).To(gomega.Equal("test-role"))
+				gomega.Expect(*putInput.PolicyName).To(gomega.Equal("test-policy"))
+				gomega.Expect(*putInput.PolicyDocument).To(gomega.Equal(policy.String()))
+			})
+		})
+
+		ginkgo.When("the role can't be looked up", func() {
+			ginkgo.It("should return the error without creating the role", func() {
+
+				mockClient := &MockIAMClient{
+					GetRoleFunc: func(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+						return nil, awserr.New("AccessDenied", "not authorized to perform iam:GetRole", nil)
+					},
+					CreateRoleFunc: func(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+						return nil, errors.New("the role must not be created")
+					},
+				}
+
+				roleExists, err := EnsureIamRoleExists(mockClient, "test-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, RoleOptions{})
+
+				gomega.Expect(roleExists).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("AccessDenied")))
+			})
+		})
+
 		ginkgo.When("IAM client is not provided", func() {
 			ginkgo.It("should return an error", func() {
 
-				roleExists, err := EnsureIamRoleExists(nil, "test-role", "test-policy", "test-bucket", "test-input", "test-input", "test-input", "test-input", "test-bucket")
+				roleExists, err := EnsureIamRoleExists(nil, "test-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, RoleOptions{})
 
 				gomega.Expect(roleExists).To(gomega.BeFalse())
 				gomega.Expect(err).To(gomega.MatchError("IAMClient is not provided"))
@@ -94,7 +173,7 @@ var _ = ginkgo.Describe("Interacting with the IAM API", func() {
 					},
 				}
 
-				roleExists, err := EnsureIamRoleExists(mockClient, "", "test-policy", "test-bucket", "test-input", "test-input", "test-input", "test-input", "test-bucket")
+				roleExists, err := EnsureIamRoleExists(mockClient, "", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, RoleOptions{})
 
 				gomega.Expect(roleExists).To(gomega.BeFalse())
 				gomega.Expect(err).To(gomega.MatchError("role name is not provided"))
@@ -115,7 +194,7 @@ var _ = ginkgo.Describe("Interacting with the IAM API", func() {
 					},
 				}
 
-				roleExists, err := EnsureIamRoleExists(mockClient, "new-role", "test-policy", "test-bucket", "test-input", "test-input", "test-input", "test-input", "test-bucket")
+				roleExists, err := EnsureIamRoleExists(mockClient, "new-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, RoleOptions{})
 
 				gomega.Expect(roleExists).To(gomega.BeTrue())
 				gomega.Expect(err).To(gomega.BeNil())
@@ -124,6 +203,252 @@ var _ = ginkgo.Describe("Interacting with the IAM API", func() {
 		})
 	})
 
+	ginkgo.Context("testing the managed policy attachment", func() {
+
+		ginkgo.When("managed policies are requested", func() {
+			ginkgo.It("should attach each of them to the role", func() {
+
+				var attached []string
+
+				mockClient := &MockIAMClient{
+					AttachRolePolicyFunc: func(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error) {
+						attached = append(attached, *input.PolicyArn)
+						return &iam.AttachRolePolicyOutput{}, nil
+					},
+				}
+
+				managedPolicies := []string{"arn:aws:iam::aws:policy/AdministratorAccess"}
+
+				roleExists, err := EnsureIamRoleExists(mockClient, "new-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, managedPolicies, RoleOptions{})
+
+				gomega.Expect(roleExists).To(gomega.BeTrue())
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(attached).To(gomega.Equal(managedPolicies))
+			})
+		})
+
+		ginkgo.When("a managed policy arn is invalid", func() {
+			ginkgo.It("should return an error before creating anything", func() {
+
+				mockClient := &MockIAMClient{
+					CreateRoleFunc: func(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+						ginkgo.Fail("CreateRole should not be called")
+						return nil, nil
+					},
+				}
+
+				roleExists, err := EnsureIamRoleExists(mockClient, "new-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, []string{"AdministratorAccess"}, RoleOptions{})
+
+				gomega.Expect(roleExists).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError(`invalid managed policy arn "AdministratorAccess"`))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the role options", func() {
+
+		ginkgo.When("the role is created with options", func() {
+			ginkgo.It("should pass the path, boundary, session duration and description to IAM", func() {
+
+				var createInput *iam.CreateRoleInput
+
+				mockClient := &MockIAMClient{
+					GetRoleFunc: func(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+						return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "role not found", nil)
+					},
+					CreateRoleFunc: func(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+						createInput = input
+						return &iam.CreateRoleOutput{}, nil
+					},
+				}
+
+				options := RoleOptions{
+					Path:                "/platform/",
+					PermissionsBoundary: "arn:aws:iam::000000000000:policy/platform-boundary",
+					MaxSessionDuration:  7200,
+					Description:         "AFT deployment role",
+				}
+
+				roleExists, err := EnsureIamRoleExists(mockClient, "new-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, options)
+
+				gomega.Expect(roleExists).To(gomega.BeTrue())
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(*createInput.Path).To(gomega.Equal("/platform/"))
+				gomega.Expect(*createInput.PermissionsBoundary).To(gomega.Equal("arn:aws:iam::000000000000:policy/platform-boundary"))
+				gomega.Expect(*createInput.MaxSessionDuration).To(gomega.Equal(int64(7200)))
+				gomega.Expect(*createInput.Description).To(gomega.Equal("AFT deployment role"))
+			})
+		})
+
+		ginkgo.When("no options are given", func() {
+			ginkgo.It("should create the role under the root path", func() {
+
+				var createInput *iam.CreateRoleInput
+
+				mockClient := &MockIAMClient{
+					GetRoleFunc: func(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+						return nil, awserr.New(iam.ErrCodeNoSuchEntityException, "role not found", nil)
+					},
+					CreateRoleFunc: func(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+						createInput = input
+						return &iam.CreateRoleOutput{}, nil
+					},
+				}
+
+				_, err := EnsureIamRoleExists(mockClient, "new-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, RoleOptions{})
+
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(*createInput.Path).To(gomega.Equal("/"))
+				gomega.Expect(createInput.PermissionsBoundary).To(gomega.BeNil())
+				gomega.Expect(createInput.MaxSessionDuration).To(gomega.BeNil())
+			})
+		})
+
+		ginkgo.When("the role exists with other options", func() {
+			ginkgo.It("should update the boundary, session duration and description", func() {
+
+				var boundaryInput *iam.PutRolePermissionsBoundaryInput
+				var updateInput *iam.UpdateRoleInput
+
+				mockClient := &MockIAMClient{
+					GetRoleFunc: func(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+						return &iam.GetRoleOutput{
+							Role: &iam.Role{
+								RoleName:           aws.String("test-role"),
+								Path:               aws.String("/platform/"),
+								MaxSessionDuration: aws.Int64(3600),
+							},
+						}, nil
+					},
+					PutRolePermissionsBoundaryFunc: func(input *iam.PutRolePermissionsBoundaryInput) (*iam.PutRolePermissionsBoundaryOutput, error) {
+						boundaryInput = input
+						return &iam.PutRolePermissionsBoundaryOutput{}, nil
+					},
+					UpdateRoleFunc: func(input *iam.UpdateRoleInput) (*iam.UpdateRoleOutput, error) {
+						updateInput = input
+						return &iam.UpdateRoleOutput{}, nil
+					},
+				}
+
+				options := RoleOptions{
+					Path:                "/platform/",
+					PermissionsBoundary: "arn:aws:iam::000000000000:policy/platform-boundary",
+					MaxSessionDuration:  7200,
+					Description:         "AFT deployment role",
+				}
+
+				roleExists, err := EnsureIamRoleExists(mockClient, "test-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, options)
+
+				gomega.Expect(roleExists).To(gomega.BeTrue())
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(*boundaryInput.RoleName).To(gomega.Equal("test-role"))
+				gomega.Expect(*boundaryInput.PermissionsBoundary).To(gomega.Equal("arn:aws:iam::000000000000:policy/platform-boundary"))
+				gomega.Expect(*updateInput.RoleName).To(gomega.Equal("test-role"))
+				gomega.Expect(*updateInput.MaxSessionDuration).To(gomega.Equal(int64(7200)))
+				gomega.Expect(*updateInput.Description).To(gomega.Equal("AFT deployment role"))
+			})
+		})
+
+		ginkgo.When("the role exists with the same options", func() {
+			ginkgo.It("should not update the role", func() {
+
+				updated := false
+
+				mockClient := &MockIAMClient{
+					GetRoleFunc: func(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+						return &iam.GetRoleOutput{
+							Role: &iam.Role{
+								RoleName: aws.String("test-role"),
+								Path:     aws.String("/"),
+								PermissionsBoundary: &iam.AttachedPermissionsBoundary{
+									PermissionsBoundaryArn: aws.String("arn:aws:iam::000000000000:policy/platform-boundary"),
+								},
+								MaxSessionDuration: aws.Int64(7200),
+							},
+						}, nil
+					},
+					PutRolePermissionsBoundaryFunc: func(input *iam.PutRolePermissionsBoundaryInput) (*iam.PutRolePermissionsBoundaryOutput, error) {
+						updated = true
+						return &iam.PutRolePermissionsBoundaryOutput{}, nil
+					},
+					UpdateRoleFunc: func(input *iam.UpdateRoleInput) (*iam.UpdateRoleOutput, error) {
+						updated = true
+						return &iam.UpdateRoleOutput{}, nil
+					},
+				}
+
+				options := RoleOptions{
+					PermissionsBoundary: "arn:aws:iam::000000000000:policy/platform-boundary",
+					MaxSessionDuration:  7200,
+				}
+
+				_, err := EnsureIamRoleExists(mockClient, "test-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, options)
+
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(updated).To(gomega.BeFalse())
+			})
+		})
+
+		ginkgo.When("the role exists under another path", func() {
+			ginkgo.It("should return an error", func() {
+
+				mockClient := &MockIAMClient{
+					GetRoleFunc: func(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+						return &iam.GetRoleOutput{
+							Role: &iam.Role{
+								RoleName: aws.String("test-role"),
+								Path:     aws.String("/"),
+							},
+						}, nil
+					},
+				}
+
+				roleExists, err := EnsureIamRoleExists(mockClient, "test-role", "codebuild.amazonaws.com", "test-policy", PolicyDocument{}, nil, RoleOptions{Path: "/platform/"})
+
+				gomega.Expect(roleExists).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("IAM Role test-role exists under the path / instead of /platform/, the path of a role can't be changed, delete the role or set its path"))
+			})
+		})
+
+		ginkgo.When("the role path doesn't end with a slash", func() {
+			ginkgo.It("should return an error", func() {
+				isValid, err := checkRoleOptionsCompliance(RoleOptions{Path: "/platform"})
+				gomega.Expect(isValid).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("role path must begin and end with / and be at most 512 characters long"))
+			})
+		})
+
+		ginkgo.When("the max session duration is out of range", func() {
+			ginkgo.It("should return an error", func() {
+				isValid, err := checkRoleOptionsCompliance(RoleOptions{MaxSessionDuration: 60})
+				gomega.Expect(isValid).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("role max session duration must be between 3600 and 43200 seconds"))
+			})
+		})
+
+		ginkgo.When("the permissions boundary is not a policy arn", func() {
+			ginkgo.It("should return an error", func() {
+				isValid, err := checkRoleOptionsCompliance(RoleOptions{PermissionsBoundary: "platform-boundary"})
+				gomega.Expect(isValid).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.HaveOccurred())
+			})
+		})
+	})
+
+	ginkgo.Context("testing the RoleArn function", func() {
+		ginkgo.When("the role has a path", func() {
+			ginkgo.It("should include the path in the arn", func() {
+				gomega.Expect(RoleArn("000000000000", "/platform/", "role-name")).To(gomega.Equal("arn:aws:iam::000000000000:role/platform/role-name"))
+			})
+		})
+
+		ginkgo.When("the role path is empty", func() {
+			ginkgo.It("should use the root path", func() {
+				gomega.Expect(RoleArn("000000000000", "", "role-name")).To(gomega.Equal("arn:aws:iam::000000000000:role/role-name"))
+			})
+		})
+	})
+
 	ginkgo.Context("testing the checkIfRoleNameIsProvided function", func() {
 		ginkgo.When("roleName is not provided", func() {
 			ginkgo.It("should return an error", func() {
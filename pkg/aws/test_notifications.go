@@ -0,0 +1,176 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package aws contains tests for aws clients and session.
+package aws
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/codestarnotifications"
+	"github.com/aws/aws-sdk-go/service/codestarnotifications/codestarnotificationsiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// MockSNSClient is a mock implementation of an SNS client for testing.
+type MockSNSClient struct {
+	snsiface.SNSAPI
+	CreateTopicFunc        func(*sns.CreateTopicInput) (*sns.CreateTopicOutput, error)
+	SetTopicAttributesFunc func(*sns.SetTopicAttributesInput) (*sns.SetTopicAttributesOutput, error)
+	SubscribeFunc          func(*sns.SubscribeInput) (*sns.SubscribeOutput, error)
+}
+
+// CreateTopic is a mock implementation of the CreateTopic method.
+func (m *MockSNSClient) CreateTopic(input *sns.CreateTopicInput) (*sns.CreateTopicOutput, error) {
+	return m.CreateTopicFunc(input)
+}
+
+// SetTopicAttributes is a mock implementation of the SetTopicAttributes method.
+func (m *MockSNSClient) SetTopicAttributes(input *sns.SetTopicAttributesInput) (*sns.SetTopicAttributesOutput, error) {
+	return m.SetTopicAttributesFunc(input)
+}
+
+// Subscribe is a mock implementation of the Subscribe method.
+func (m *MockSNSClient) Subscribe(input *sns.SubscribeInput) (*sns.SubscribeOutput, error) {
+	return m.SubscribeFunc(input)
+}
+
+// MockCodeStarNotificationsClient is a mock implementation of a CodeStar Notifications client for testing.
+type MockCodeStarNotificationsClient struct {
+	codestarnotificationsiface.CodeStarNotificationsAPI
+	CreateNotificationRuleFunc func(*codestarnotifications.CreateNotificationRuleInput) (*codestarnotifications.CreateNotificationRuleOutput, error)
+}
+
+// CreateNotificationRule is a mock implementation of the CreateNotificationRule method.
+func (m *MockCodeStarNotificationsClient) CreateNotificationRule(input *codestarnotifications.CreateNotificationRuleInput) (*codestarnotifications.CreateNotificationRuleOutput, error) {
+	return m.CreateNotificationRuleFunc(input)
+}
+
+var _ = ginkgo.Describe("Interacting with the SNS and CodeStar Notifications APIs", func() {
+
+	var (
+		snsClient           *MockSNSClient
+		notificationsClient *MockCodeStarNotificationsClient
+		topicPolicy         string
+		subscribed          []string
+		ruleInput           *codestarnotifications.CreateNotificationRuleInput
+	)
+
+	topicArn := "arn:aws:sns:us-east-1:123456789012:aft-deployment-notifications"
+
+	ginkgo.BeforeEach(func() {
+		topicPolicy = ""
+		subscribed = nil
+		ruleInput = nil
+
+		snsClient = &MockSNSClient{
+			CreateTopicFunc: func(input *sns.CreateTopicInput) (*sns.CreateTopicOutput, error) {
+				return &sns.CreateTopicOutput{TopicArn: aws.String(topicArn)}, nil
+			},
+			SetTopicAttributesFunc: func(input *sns.SetTopicAttributesInput) (*sns.SetTopicAttributesOutput, error) {
+				topicPolicy = aws.StringValue(input.AttributeValue)
+				return &sns.SetTopicAttributesOutput{}, nil
+			},
+			SubscribeFunc: func(input *sns.SubscribeInput) (*sns.SubscribeOutput, error) {
+				subscribed = append(subscribed, aws.StringValue(input.Endpoint))
+				return &sns.SubscribeOutput{}, nil
+			},
+		}
+
+		notificationsClient = &MockCodeStarNotificationsClient{
+			CreateNotificationRuleFunc: func(input *codestarnotifications.CreateNotificationRuleInput) (*codestarnotifications.CreateNotificationRuleOutput, error) {
+				ruleInput = input
+				return &codestarnotifications.CreateNotificationRuleOutput{}, nil
+			},
+		}
+	})
+
+	ginkgo.Context("testing the EnsurePipelineNotificationsExist function", func() {
+		ginkgo.When("the clients are not provided", func() {
+			ginkgo.It("should return an error", func() {
+				ensure, err := EnsurePipelineNotificationsExist(nil, notificationsClient, "us-east-1", "123456789012", "aft-deployment-pipeline", "aft-deployment-notifications", nil)
+				gomega.Expect(ensure).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("SNS client is not provided"))
+
+				ensure, err = EnsurePipelineNotificationsExist(snsClient, nil, "us-east-1", "123456789012", "aft-deployment-pipeline", "aft-deployment-notifications", nil)
+				gomega.Expect(ensure).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("CodeStar Notifications client is not provided"))
+			})
+		})
+
+		ginkgo.When("an email address is invalid", func() {
+			ginkgo.It("should return an error before creating anything", func() {
+				ensure, err := EnsurePipelineNotificationsExist(snsClient, notificationsClient, "us-east-1", "123456789012", "aft-deployment-pipeline", "aft-deployment-notifications", []string{"not-an-email"})
+				gomega.Expect(ensure).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError(`invalid email address "not-an-email"`))
+				gomega.Expect(topicPolicy).To(gomega.BeEmpty())
+			})
+		})
+
+		ginkgo.When("everything is created", func() {
+			ginkgo.It("should subscribe the emails and create the rule on the pipeline", func() {
+				ensure, err := EnsurePipelineNotificationsExist(snsClient, notificationsClient, "us-east-1", "123456789012", "aft-deployment-pipeline", "aft-deployment-notifications", []string{"ops@example.com", "cloud@example.com"})
+				gomega.Expect(ensure).To(gomega.BeTrue())
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				gomega.Expect(topicPolicy).To(gomega.ContainSubstring(notificationsServicePrincipal))
+				gomega.Expect(subscribed).To(gomega.Equal([]string{"ops@example.com", "cloud@example.com"}))
+
+				gomega.Expect(aws.StringValue(ruleInput.Name)).To(gomega.Equal("aft-deployment-pipeline-notifications"))
+				gomega.Expect(aws.StringValue(ruleInput.Resource)).To(gomega.Equal("arn:aws:codepipeline:us-east-1:123456789012:aft-deployment-pipeline"))
+				gomega.Expect(aws.StringValueSlice(ruleInput.EventTypeIds)).To(gomega.Equal(PipelineNotificationEvents))
+				gomega.Expect(aws.StringValue(ruleInput.Targets[0].TargetAddress)).To(gomega.Equal(topicArn))
+			})
+		})
+
+		ginkgo.When("the notification rule already exists", func() {
+			ginkgo.It("should return success", func() {
+				notificationsClient.CreateNotificationRuleFunc = func(input *codestarnotifications.CreateNotificationRuleInput) (*codestarnotifications.CreateNotificationRuleOutput, error) {
+					return nil, awserr.New(codestarnotifications.ErrCodeResourceAlreadyExistsException, "rule already exists", nil)
+				}
+
+				ensure, err := EnsurePipelineNotificationsExist(snsClient, notificationsClient, "us-east-1", "123456789012", "aft-deployment-pipeline", "aft-deployment-notifications", nil)
+				gomega.Expect(ensure).To(gomega.BeTrue())
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			})
+		})
+
+		ginkgo.When("the topic can't be created", func() {
+			ginkgo.It("should return an error", func() {
+				snsClient.CreateTopicFunc = func(input *sns.CreateTopicInput) (*sns.CreateTopicOutput, error) {
+					return nil, errors.New("access denied")
+				}
+
+				ensure, err := EnsurePipelineNotificationsExist(snsClient, notificationsClient, "us-east-1", "123456789012", "aft-deployment-pipeline", "aft-deployment-notifications", nil)
+				gomega.Expect(ensure).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("failed to create SNS topic aft-deployment-notifications: access denied"))
+				gomega.Expect(ruleInput).To(gomega.BeNil())
+			})
+		})
+	})
+
+	ginkgo.Context("testing the notificationRuleName function", func() {
+		ginkgo.When("the pipeline name is long", func() {
+			ginkgo.It("should keep the rule name within 64 characters", func() {
+				gomega.Expect(notificationRuleName(strings.Repeat("a", 100))).To(gomega.HaveLen(64))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the checkTopicNameCompliance function", func() {
+		ginkgo.When("the topic name contains invalid characters", func() {
+			ginkgo.It("should return an error", func() {
+				isValid, err := checkTopicNameCompliance("aft notifications")
+				gomega.Expect(isValid).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError(`invalid SNS topic name "aft notifications"`))
+			})
+		})
+	})
+})
@@ -0,0 +1,214 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package aws contains tests for AWS clients and session.
+package aws
+
+import (
+	"encoding/json"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// statementBySid returns the statement with the given sid, or nil if the policy doesn't have it.
+func statementBySid(policy PolicyDocument, sid string) *PolicyStatement {
+	for i := range policy.Statement {
+		if policy.Statement[i].Sid == sid {
+			return &policy.Statement[i]
+		}
+	}
+	return nil
+}
+
+var _ = ginkgo.Describe("Building IAM policies", func() {
+
+	scope := PolicyScope{
+		Region:                 "us-east-1",
+		AftManagementAccountID: "111111111111",
+		RepoName:               "aft-deployment",
+		ProjectName:            "aft-deployment-build",
+		ArtifactBucketName:     "111111111111-aft-deployment-codepipeline-artifact",
+		StateBucketName:        "111111111111-aft-deployment-terraform-tfstate",
+		ControlTowerAccountIDs: []string{"222222222222", "333333333333", ""},
+	}
+
+	ginkgo.Context("testing the NewAssumeRolePolicy function", func() {
+		ginkgo.It("should trust the given service", func() {
+			var rendered map[string]interface{}
+
+			err := json.Unmarshal([]byte(NewAssumeRolePolicy("codebuild.amazonaws.com").String()), &rendered)
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(rendered["Version"]).To(gomega.Equal("2012-10-17"))
+			gomega.Expect(rendered["Statement"]).To(gomega.HaveLen(1))
+		})
+	})
+
+	ginkgo.Context("testing the NewRolePolicy function", func() {
+
+		ginkgo.When("the role is the pipeline role", func() {
+			ginkgo.It("should not grant EC2 or state bucket access", func() {
+				policy, err := NewRolePolicy(PipelineRolePurpose, scope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(policy.String()).NotTo(gomega.ContainSubstring("ec2:"))
+				gomega.Expect(policy.String()).NotTo(gomega.ContainSubstring(scope.StateBucketName))
+				gomega.Expect(statementBySid(policy, "BuildProject").Resource).To(gomega.Equal([]string{
+					"arn:aws:codebuild:us-east-1:111111111111:project/aft-deployment-build",
+				}))
+			})
+		})
+
+		ginkgo.When("the role is the apply role", func() {
+			ginkgo.It("should read and write the state bucket", func() {
+				policy, err := NewRolePolicy(ApplyRolePurpose, scope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "StateBucketRead")).NotTo(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "StateBucketWrite").Resource).To(gomega.Equal([]string{
+					"arn:aws:s3:::111111111111-aft-deployment-terraform-tfstate/*",
+				}))
+			})
+
+			ginkgo.It("should assume the CT execution role only in the given accounts", func() {
+				policy, err := NewRolePolicy(ApplyRolePurpose, scope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "ControlTowerAccounts").Resource).To(gomega.Equal([]string{
+					"arn:aws:iam::222222222222:role/AWSControlTowerExecution",
+					"arn:aws:iam::333333333333:role/AWSControlTowerExecution",
+				}))
+			})
+		})
+
+		ginkgo.When("the role is the plan role", func() {
+			ginkgo.It("should only read the state bucket and the AFT resources", func() {
+				policy, err := NewRolePolicy(PlanRolePurpose, scope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "StateBucketRead")).NotTo(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "AftResourcesRead")).NotTo(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "StateBucketWrite")).To(gomega.BeNil())
+
+				for _, statement := range policy.Statement {
+					for _, action := range statement.Action {
+						gomega.Expect(action).NotTo(gomega.HaveSuffix(":*"), statement.Sid)
+					}
+				}
+			})
+
+			ginkgo.It("should be extended by the apply role", func() {
+				plan, _ := NewRolePolicy(PlanRolePurpose, scope)
+				apply, err := NewRolePolicy(ApplyRolePurpose, scope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(apply.Statement[:len(plan.Statement)]).To(gomega.Equal(plan.Statement))
+			})
+		})
+
+		ginkgo.When("the apply role writes IAM entities", func() {
+			ginkgo.It("should only write the AFT roles and policies", func() {
+				policy, err := NewRolePolicy(ApplyRolePurpose, scope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(policy.String()).NotTo(gomega.ContainSubstring(`"iam:*"`))
+
+				roles := statementBySid(policy, "AftRolesWrite")
+				gomega.Expect(roles.Action).NotTo(gomega.ContainElement("iam:PassRole"))
+				gomega.Expect(roles.Action).NotTo(gomega.ContainElement(gomega.HavePrefix("iam:CreateUser")))
+				gomega.Expect(roles.Resource).To(gomega.ContainElements(
+					"arn:aws:iam::111111111111:role/aft-*",
+					"arn:aws:iam::111111111111:role/*/AWSAFT*",
+					"arn:aws:iam::111111111111:policy/aft-*",
+				))
+
+				pass := statementBySid(policy, "AftRolesPass")
+				gomega.Expect(pass.Action).To(gomega.Equal([]string{"iam:PassRole"}))
+				gomega.Expect(pass.Resource).To(gomega.HaveEach(gomega.ContainSubstring(":role/")))
+				gomega.Expect(pass.Condition["StringEquals"]["iam:PassedToService"]).To(gomega.ContainElement("lambda.amazonaws.com"))
+			})
+
+			ginkgo.It("should only write the named AFT resources of the named services", func() {
+				policy, err := NewRolePolicy(ApplyRolePurpose, scope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "AftNamedResourcesWrite").Resource).To(gomega.ContainElement(
+					"arn:aws:lambda:*:111111111111:function:aft-*"))
+				gomega.Expect(statementBySid(policy, "AftRegionalResourcesWrite").Action).NotTo(gomega.ContainElement("lambda:*"))
+			})
+		})
+
+		ginkgo.When("the build uses custom logs and a VPC", func() {
+			ginkgo.It("should grant access to the log destinations and network interfaces", func() {
+				customScope := scope
+				customScope.BuildLogGroupName = "/platform/aft"
+				customScope.BuildLogsS3Location = "platform-logs/aft"
+				customScope.BuildInVpc = true
+
+				policy, err := NewRolePolicy(ApplyRolePurpose, customScope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "BuildLogs").Resource).To(gomega.ContainElement("arn:aws:logs:us-east-1:111111111111:log-group:/platform/aft*"))
+				gomega.Expect(statementBySid(policy, "BuildLogsBucket").Resource).To(gomega.Equal([]string{
+					"arn:aws:s3:::platform-logs",
+					"arn:aws:s3:::platform-logs/aft/*",
+				}))
+				gomega.Expect(statementBySid(policy, "BuildVpc")).NotTo(gomega.BeNil())
+			})
+		})
+
+		ginkgo.When("the build reads Secrets Manager secrets", func() {
+			ginkgo.It("should only grant access to those secrets", func() {
+				customScope := scope
+				customScope.BuildSecretIDs = []string{
+					"platform/registry-token:token::",
+					"platform/registry-token:user::",
+					"arn:aws:secretsmanager:eu-west-1:444444444444:secret:shared/key-AbCdEf:password:AWSCURRENT:",
+				}
+
+				policy, err := NewRolePolicy(ApplyRolePurpose, customScope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(statementBySid(policy, "BuildSecrets").Action).To(gomega.Equal([]string{"secretsmanager:GetSecretValue"}))
+				gomega.Expect(statementBySid(policy, "BuildSecrets").Resource).To(gomega.Equal([]string{
+					"arn:aws:secretsmanager:us-east-1:111111111111:secret:platform/registry-token-??????",
+					"arn:aws:secretsmanager:eu-west-1:444444444444:secret:shared/key-AbCdEf",
+				}))
+				gomega.Expect(statementBySid(policy, "BuildSecretsDecrypt").Condition).To(gomega.Equal(PolicyCondition{
+					"StringEquals": {"kms:ViaService": {"secretsmanager.us-east-1.amazonaws.com", "secretsmanager.eu-west-1.amazonaws.com"}},
+				}))
+			})
+
+			ginkgo.It("should not grant access to secrets when there are none", func() {
+				policy, err := NewRolePolicy(ApplyRolePurpose, scope)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(policy.String()).NotTo(gomega.ContainSubstring("secretsmanager"))
+			})
+		})
+
+		ginkgo.When("the purpose is unknown", func() {
+			ginkgo.It("should return an error", func() {
+				_, err := NewRolePolicy("admin", scope)
+				gomega.Expect(err).To(gomega.MatchError(`unknown role purpose "admin"`))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the checkManagedPolicyArnCompliance function", func() {
+		ginkgo.When("the arn is an AWS managed policy", func() {
+			ginkgo.It("should return true", func() {
+				isValid, err := checkManagedPolicyArnCompliance("arn:aws:iam::aws:policy/AdministratorAccess")
+				gomega.Expect(isValid).To(gomega.BeTrue())
+				gomega.Expect(err).To(gomega.BeNil())
+			})
+		})
+
+		ginkgo.When("the arn is a customer managed policy with a path", func() {
+			ginkgo.It("should return true", func() {
+				isValid, err := checkManagedPolicyArnCompliance("arn:aws:iam::111111111111:policy/platform/aft-extra")
+				gomega.Expect(isValid).To(gomega.BeTrue())
+				gomega.Expect(err).To(gomega.BeNil())
+			})
+		})
+
+		ginkgo.When("the arn is a policy name", func() {
+			ginkgo.It("should return an error", func() {
+				isValid, err := checkManagedPolicyArnCompliance("AdministratorAccess")
+				gomega.Expect(isValid).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.HaveOccurred())
+			})
+		})
+	})
+})
@@ -12,6 +12,7 @@ import (
 	gomega "github.com/onsi/gomega"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
@@ -19,7 +20,7 @@ import (
 // MockS3Client is a mock implementation of an S3 client for testing.
 type MockS3Client struct {
 	s3iface.S3API
-	ListBucketsFunc           func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error)
+	HeadBucketFunc            func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error)
 	CreateBucketFunc          func(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error)
 	WaitUntilBucketExistsFunc func(*s3.HeadBucketInput) error
 	PutPublicAccessBlockFunc  func(input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error)
@@ -28,9 +29,14 @@ type MockS3Client struct {
 	PutObjectFunc             func(*s3.PutObjectInput) (*s3.PutObjectOutput, error)
 }
 
-// ListBuckets is a mock implementation of the ListBuckets method.
-func (m *MockS3Client) ListBuckets(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-	return m.ListBucketsFunc(input)
+// HeadBucket is a mock implementation of the HeadBucket method.
+func (m *MockS3Client) HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	return m.HeadBucketFunc(input)
+}
+
+// headBucketError builds the error returned by the S3 API for a HeadBucket call with the given status code.
+func headBucketError(code string, statusCode int) error {
+	return awserr.NewRequestFailure(awserr.New(code, code, nil), statusCode, "request-id")
 }
 
 // CreateBucket is a mock implementation of the CreateBucket method.
@@ -69,15 +75,11 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 			ginkgo.It("should return an success", func() {
 
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{
-								{Name: aws.String("another-bucket")},
-							},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return &s3.HeadBucketOutput{}, nil
 					},
 				}
-				ensure, err := EnsureS3BucketExists(mockClient, "another-bucket", "000000000000", "test-kms-key-id", "codeBuildRole")
+				ensure, err := EnsureS3BucketExists(mockClient, "another-bucket", "000000000000", "test-kms-key-id", "arn:aws:iam::000000000000:role/codeBuildRole")
 				gomega.Expect(ensure).To(gomega.BeTrue())
 				gomega.Expect(err).To(gomega.BeNil())
 			})
@@ -87,12 +89,8 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 			ginkgo.It("should create the bucket", func() {
 
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{
-								{Name: aws.String("")},
-							},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, headBucketError("NotFound", 404)
 					},
 					CreateBucketFunc: func(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
 						return &s3.CreateBucketOutput{}, nil
@@ -107,7 +105,7 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 						return nil, nil
 					},
 				}
-				ensure, err := EnsureS3BucketExists(mockClient, "new-bucket", "000000000000", "test-kms-key-id", "codeBuildRole")
+				ensure, err := EnsureS3BucketExists(mockClient, "new-bucket", "000000000000", "test-kms-key-id", "arn:aws:iam::000000000000:role/codeBuildRole")
 				gomega.Expect(ensure).To(gomega.BeTrue())
 				gomega.Expect(err).To(gomega.BeNil())
 			})
@@ -119,16 +117,14 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 					CreateBucketFunc: func(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
 						return nil, errors.New("AWS create bucket error")
 					},
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, headBucketError("NotFound", 404)
 					},
 					WaitUntilBucketExistsFunc: func(input *s3.HeadBucketInput) error {
 						return nil
 					},
 				}
-				ensure, err := EnsureS3BucketExists(mockClient, "failed-bucket", "000000000000", "test-kms-key-id", "codeBuildRole")
+				ensure, err := EnsureS3BucketExists(mockClient, "failed-bucket", "000000000000", "test-kms-key-id", "arn:aws:iam::000000000000:role/codeBuildRole")
 				gomega.Expect(ensure).To(gomega.BeFalse())
 				gomega.Expect(err).To(gomega.MatchError("AWS create bucket error"))
 			})
@@ -140,16 +136,14 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 					CreateBucketFunc: func(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
 						return nil, errors.New("AWS WaitUntilBucketExists error")
 					},
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, headBucketError("NotFound", 404)
 					},
 					WaitUntilBucketExistsFunc: func(input *s3.HeadBucketInput) error {
 						return nil
 					},
 				}
-				ensure, err := EnsureS3BucketExists(mockClient, "existing-bucket", "000000000000", "test-kms-key-id", "codeBuildRole")
+				ensure, err := EnsureS3BucketExists(mockClient, "existing-bucket", "000000000000", "test-kms-key-id", "arn:aws:iam::000000000000:role/codeBuildRole")
 				gomega.Expect(ensure).To(gomega.BeFalse())
 				gomega.Expect(err).To(gomega.MatchError("AWS WaitUntilBucketExists error"))
 			})
@@ -161,10 +155,8 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 					CreateBucketFunc: func(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
 						return &s3.CreateBucketOutput{}, nil
 					},
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, headBucketError("NotFound", 404)
 					},
 					WaitUntilBucketExistsFunc: func(input *s3.HeadBucketInput) error {
 						return nil
@@ -188,31 +180,27 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 
 	ginkgo.Context("testing the bucketExists function", func() {
 
-		ginkgo.When("S3 list bucket operation fails", func() {
+		ginkgo.When("S3 head bucket operation fails", func() {
 			ginkgo.It("should return an error", func() {
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
 						return nil, errors.New("AWS S3 error")
 					},
 				}
-				exists, err := bucketExists(mockClient, "bucketname")
+				exists, err := bucketExists(mockClient, "bucketname", "000000000000")
 				gomega.Expect(exists).To(gomega.BeFalse())
-				gomega.Expect(err).To(gomega.MatchError("failed to list S3 buckets: AWS S3 error"))
+				gomega.Expect(err).To(gomega.MatchError("failed to look up S3 bucket bucketname: AWS S3 error"))
 			})
 		})
 
 		ginkgo.When("if bucket name is not provided", func() {
 			ginkgo.It("should return an error", func() {
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{
-								{Name: aws.String("another-bucket")},
-							},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, headBucketError("NotFound", 404)
 					},
 				}
-				ensure, err := bucketExists(mockClient, "")
+				ensure, err := bucketExists(mockClient, "", "000000000000")
 				gomega.Expect(ensure).To(gomega.BeFalse())
 				gomega.Expect(err).To(gomega.BeNil())
 			})
@@ -222,16 +210,12 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 			ginkgo.It("should return true", func() {
 
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{
-								{Name: aws.String("bucketname")},
-							},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return &s3.HeadBucketOutput{}, nil
 					},
 				}
 
-				exists, err := bucketExists(mockClient, "bucketname")
+				exists, err := bucketExists(mockClient, "bucketname", "000000000000")
 				gomega.Expect(exists).To(gomega.BeTrue())
 				gomega.Expect(err).To(gomega.BeNil())
 			})
@@ -240,28 +224,24 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 		ginkgo.When("Bucket does not exist", func() {
 			ginkgo.It("should return false", func() {
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{
-								{Name: aws.String("another-bucket")},
-							},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, headBucketError("NotFound", 404)
 					},
 				}
-				exists, err := bucketExists(mockClient, "bucketname")
+				exists, err := bucketExists(mockClient, "bucketname", "000000000000")
 				gomega.Expect(exists).To(gomega.BeFalse())
 				gomega.Expect(err).To(gomega.BeNil())
 			})
 		})
 
-		ginkgo.When("AWS returns an empty bucket list", func() {
+		ginkgo.When("AWS answers the lookup with not found", func() {
 			ginkgo.It("should return false", func() {
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, headBucketError("NotFound", 404)
 					},
 				}
-				exists, err := bucketExists(mockClient, "non-existent-bucket")
+				exists, err := bucketExists(mockClient, "non-existent-bucket", "000000000000")
 				gomega.Expect(exists).To(gomega.BeFalse())
 				gomega.Expect(err).To(gomega.BeNil())
 			})
@@ -351,30 +331,59 @@ var _ = ginkgo.Describe("Interacting with the S3 API", func() {
 		ginkgo.When("if the bucket already exists", func() {
 			ginkgo.It("should return an error", func() {
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return &s3.ListBucketsOutput{
-							Buckets: []*s3.Bucket{
-								{Name: aws.String("one-bucket")},
-							},
-						}, nil
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return &s3.HeadBucketOutput{}, nil
 					},
 				}
-				ensure, err := checkIfBucketExists(mockClient, "one-bucket")
+				ensure, err := checkIfBucketExists(mockClient, "one-bucket", "000000000000")
 				gomega.Expect(ensure).To(gomega.BeTrue())
 				gomega.Expect(err).To(gomega.BeNil())
 			})
 		})
 
-		ginkgo.When("list operation fails", func() {
+		ginkgo.When("head operation fails", func() {
 			ginkgo.It("should return an error", func() {
 				mockClient := &MockS3Client{
-					ListBucketsFunc: func(input *s3.ListBucketsInput) (*s3.ListBucketsOutput, error) {
-						return nil, errors.New("AWS S3 list operation error")
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, errors.New("AWS S3 head operation error")
+					},
+				}
+				exists, err := checkIfBucketExists(mockClient, "any-bucket", "000000000000")
+				gomega.Expect(exists).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError("failed to look up S3 bucket any-bucket: AWS S3 head operation error"))
+			})
+		})
+
+		ginkgo.When("the bucket is owned by another account", func() {
+			ginkgo.It("should return an ownership error", func() {
+				var expectedOwners []string
+				mockClient := &MockS3Client{
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						expectedOwners = append(expectedOwners, aws.StringValue(input.ExpectedBucketOwner))
+						if input.ExpectedBucketOwner != nil {
+							return nil, headBucketError("Forbidden", 403)
+						}
+						return &s3.HeadBucketOutput{}, nil
+					},
+				}
+				exists, err := checkIfBucketExists(mockClient, "taken-bucket", "000000000000")
+				gomega.Expect(exists).To(gomega.BeFalse())
+				gomega.Expect(errors.Is(err, ErrBucketOwnedByAnotherAccount)).To(gomega.BeTrue())
+				gomega.Expect(expectedOwners).To(gomega.Equal([]string{"000000000000", ""}))
+			})
+		})
+
+		ginkgo.When("the caller can't list the bucket", func() {
+			ginkgo.It("should return the access denied error", func() {
+				mockClient := &MockS3Client{
+					HeadBucketFunc: func(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+						return nil, headBucketError("Forbidden", 403)
 					},
 				}
-				exists, err := checkIfBucketExists(mockClient, "any-bucket")
+				exists, err := checkIfBucketExists(mockClient, "own-bucket", "000000000000")
 				gomega.Expect(exists).To(gomega.BeFalse())
-				gomega.Expect(err).To(gomega.MatchError("failed to list S3 buckets: AWS S3 list operation error"))
+				gomega.Expect(errors.Is(err, ErrBucketOwnedByAnotherAccount)).To(gomega.BeFalse())
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("failed to look up S3 bucket own-bucket: Forbidden")))
 			})
 		})
 	})
@@ -0,0 +1,89 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package cache keeps the results of slow lookups on disk for a while.
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EnvVar overrides the location of the cache directory.
+const EnvVar = "AFTCTL_CACHE_DIR"
+
+// Dir returns the cache directory, $AFTCTL_CACHE_DIR or the aftctl folder of the user cache directory.
+func Dir() (string, error) {
+	if dir := os.Getenv(EnvVar); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting the cache directory: %w", err)
+	}
+
+	return filepath.Join(dir, "aftctl"), nil
+}
+
+// Load decodes the cached entry into value, it returns false when the entry is missing or older than the ttl.
+func Load(name string, ttl time.Duration, value interface{}) (bool, error) {
+	dir, err := Dir()
+	if err != nil {
+		return false, err
+	}
+
+	path := filepath.Join(dir, name)
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading the cache: %w", err)
+	}
+
+	if time.Since(info.ModTime()) > ttl {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("error reading the cache: %w", err)
+	}
+
+	// a corrupted entry is looked up again
+	if err := json.Unmarshal(content, value); err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Save writes the entry, readable by the user only as it may describe the organization.
+func Save(name string, value interface{}) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error encoding the cache: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating the cache directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+		return fmt.Errorf("error writing the cache: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Caching the lookups", func() {
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		dir = ginkgo.GinkgoT().TempDir()
+		ginkgo.GinkgoT().Setenv(EnvVar, dir)
+	})
+
+	ginkgo.When("the entry is saved", func() {
+		ginkgo.It("should be loaded until it expires", func() {
+			gomega.Expect(Save("accounts.json", []string{"111111111111"})).To(gomega.Succeed())
+
+			var value []string
+			found, err := Load("accounts.json", time.Hour, &value)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(found).To(gomega.BeTrue())
+			gomega.Expect(value).To(gomega.Equal([]string{"111111111111"}))
+
+			old := time.Now().Add(-2 * time.Hour)
+			gomega.Expect(os.Chtimes(filepath.Join(dir, "accounts.json"), old, old)).To(gomega.Succeed())
+
+			found, err = Load("accounts.json", time.Hour, &value)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(found).To(gomega.BeFalse())
+		})
+	})
+
+	ginkgo.When("the entry is missing or corrupted", func() {
+		ginkgo.It("should not be found", func() {
+			var value []string
+			found, err := Load("missing.json", time.Hour, &value)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(found).To(gomega.BeFalse())
+
+			gomega.Expect(os.WriteFile(filepath.Join(dir, "corrupted.json"), []byte("{"), 0600)).To(gomega.Succeed())
+			found, err = Load("corrupted.json", time.Hour, &value)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(found).To(gomega.BeFalse())
+		})
+	})
+})
@@ -0,0 +1,202 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package config manages the aftctl config file holding the named contexts.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvVar overrides the location of the config file.
+const EnvVar = "AFTCTL_CONFIG"
+
+// Context holds the settings of one AFT environment, e.g. sandbox or production.
+type Context struct {
+	// Profile is the AWS profile used to reach the AFT management account.
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+
+	// Region is the region of the AFT deployment.
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+
+	AftManagementAccountID string `json:"aft-management-account-id,omitempty" yaml:"aft-management-account-id,omitempty"`
+	CtManagementAccountID  string `json:"ct-management-account-id,omitempty" yaml:"ct-management-account-id,omitempty"`
+	LogArchiveAccountID    string `json:"ct-log-archive-account-id,omitempty" yaml:"ct-log-archive-account-id,omitempty"`
+	AuditAccountID         string `json:"ct-audit-account-id,omitempty" yaml:"ct-audit-account-id,omitempty"`
+	CtHomeRegion           string `json:"ct-home-region,omitempty" yaml:"ct-home-region,omitempty"`
+
+	// SSMParameters overrides the values aftctl would read from the AFT SSM parameters, by parameter name.
+	SSMParameters map[string]string `json:"ssm-parameters,omitempty" yaml:"ssm-parameters,omitempty"`
+}
+
+// Config is the content of the aftctl config file.
+type Config struct {
+	CurrentContext string             `yaml:"current-context,omitempty"`
+	Contexts       map[string]Context `yaml:"contexts,omitempty"`
+}
+
+var contextName string
+
+// AddFlag adds the context flag to the given set of command line flags.
+func AddFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&contextName,
+		"context",
+		"",
+		"Name of the context to use instead of the current context of the aftctl config file",
+	)
+
+	cmd.RegisterFlagCompletionFunc("context", completion)
+}
+
+func completion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	path, err := Path()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	config, err := Load(path)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	return config.ContextNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// Path returns the location of the config file, $AFTCTL_CONFIG or ~/.aftctl/config.
+func Path() (string, error) {
+	if path := os.Getenv(EnvVar); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting the home directory: %w", err)
+	}
+
+	return filepath.Join(home, ".aftctl", "config"), nil
+}
+
+// Load reads the config file, a missing file is an empty config.
+func Load(path string) (*Config, error) {
+	config := &Config{}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading the aftctl config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("error parsing the aftctl config %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Save writes the config file, creating its directory if needed.
+func (c *Config) Save(path string) error {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error encoding the aftctl config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error creating the aftctl config directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("error writing the aftctl config: %w", err)
+	}
+
+	return nil
+}
+
+// ContextNames returns the names of the contexts in lexical order.
+func (c *Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Context returns the named context.
+func (c *Config) Context(name string) (Context, error) {
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return Context{}, fmt.Errorf("context %q not found, available contexts are %s", name, c.ContextNames())
+	}
+
+	return ctx, nil
+}
+
+// Use makes the named context the current context.
+func (c *Config) Use(name string) error {
+	if _, err := c.Context(name); err != nil {
+		return err
+	}
+
+	c.CurrentContext = name
+
+	return nil
+}
+
+// SetContext creates or replaces the named context.
+func (c *Config) SetContext(name string, ctx Context) error {
+	if name == "" {
+		return errors.New("context name is not provided")
+	}
+
+	if c.Contexts == nil {
+		c.Contexts = map[string]Context{}
+	}
+
+	c.Contexts[name] = ctx
+
+	return nil
+}
+
+// Active returns the context selected with --context, or else the current context of the config file.
+// An empty name means no context is in use and the commands rely on their flags only.
+func Active() (string, Context, error) {
+	path, err := Path()
+	if err != nil {
+		return "", Context{}, err
+	}
+
+	config, err := Load(path)
+	if err != nil {
+		return "", Context{}, err
+	}
+
+	name := contextName
+	if name == "" {
+		name = config.CurrentContext
+	}
+
+	if name == "" {
+		return "", Context{}, nil
+	}
+
+	ctx, err := config.Context(name)
+	if err != nil {
+		return "", Context{}, err
+	}
+
+	return name, ctx, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Managing the aftctl contexts", func() {
+
+	var path string
+
+	ginkgo.BeforeEach(func() {
+		path = filepath.Join(ginkgo.GinkgoT().TempDir(), ".aftctl", "config")
+		ginkgo.GinkgoT().Setenv(EnvVar, path)
+		contextName = ""
+	})
+
+	ginkgo.Context("testing the Load function", func() {
+		ginkgo.When("the config file doesn't exist", func() {
+			ginkgo.It("should return an empty config", func() {
+				config, err := Load(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(config.ContextNames()).To(gomega.BeEmpty())
+			})
+		})
+
+		ginkgo.When("the config file is invalid", func() {
+			ginkgo.It("should return an error", func() {
+				gomega.Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(gomega.Succeed())
+				gomega.Expect(os.WriteFile(path, []byte("contexts: ["), 0600)).To(gomega.Succeed())
+
+				_, err := Load(path)
+				gomega.Expect(err).To(gomega.HaveOccurred())
+			})
+		})
+
+		ginkgo.When("the config file was saved", func() {
+			ginkgo.It("should read the same contexts", func() {
+				config := &Config{}
+				gomega.Expect(config.SetContext("prod", Context{
+					Profile:                "prod-admin",
+					Region:                 "us-east-1",
+					AftManagementAccountID: "123456789012",
+					SSMParameters:          map[string]string{"/aft/config/terraform/distribution": "oss"},
+				})).To(gomega.Succeed())
+				gomega.Expect(config.Use("prod")).To(gomega.Succeed())
+				gomega.Expect(config.Save(path)).To(gomega.Succeed())
+
+				loaded, err := Load(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(loaded).To(gomega.Equal(config))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the Use function", func() {
+		ginkgo.When("the context doesn't exist", func() {
+			ginkgo.It("should return an error and keep the current context", func() {
+				config := &Config{CurrentContext: "sandbox", Contexts: map[string]Context{"sandbox": {}}}
+
+				err := config.Use("prod")
+				gomega.Expect(err).To(gomega.MatchError(`context "prod" not found, available contexts are [sandbox]`))
+				gomega.Expect(config.CurrentContext).To(gomega.Equal("sandbox"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the Active function", func() {
+		ginkgo.BeforeEach(func() {
+			config := &Config{
+				CurrentContext: "sandbox",
+				Contexts: map[string]Context{
+					"sandbox": {AftManagementAccountID: "111111111111"},
+					"prod":    {AftManagementAccountID: "222222222222"},
+				},
+			}
+			gomega.Expect(config.Save(path)).To(gomega.Succeed())
+		})
+
+		ginkgo.When("no context is selected on the command line", func() {
+			ginkgo.It("should return the current context", func() {
+				name, ctx, err := Active()
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(name).To(gomega.Equal("sandbox"))
+				gomega.Expect(ctx.AftManagementAccountID).To(gomega.Equal("111111111111"))
+			})
+		})
+
+		ginkgo.When("a context is selected on the command line", func() {
+			ginkgo.It("should return the selected context", func() {
+				contextName = "prod"
+
+				name, ctx, err := Active()
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(name).To(gomega.Equal("prod"))
+				gomega.Expect(ctx.AftManagementAccountID).To(gomega.Equal("222222222222"))
+			})
+		})
+
+		ginkgo.When("the selected context doesn't exist", func() {
+			ginkgo.It("should return an error", func() {
+				contextName = "staging"
+
+				_, _, err := Active()
+				gomega.Expect(err).To(gomega.HaveOccurred())
+			})
+		})
+
+		ginkgo.When("there is no config file", func() {
+			ginkgo.It("should return no context", func() {
+				ginkgo.GinkgoT().Setenv(EnvVar, filepath.Join(ginkgo.GinkgoT().TempDir(), "missing"))
+
+				name, _, err := Active()
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(name).To(gomega.BeEmpty())
+			})
+		})
+	})
+})
@@ -0,0 +1,262 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package export
+
+import (
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	"github.com/edgarsilva948/aftctl/pkg/aws/tags"
+)
+
+// cfnResource is a resource of the CloudFormation template
+type cfnResource struct {
+	Type       string
+	DependsOn  []string
+	Properties map[string]interface{}
+}
+
+// CloudFormationTemplate renders the deployment as a single CloudFormation template.
+func CloudFormationTemplate(deployment aws.Deployment, parameters []Parameter) ([]byte, error) {
+	resources := map[string]cfnResource{}
+
+	for logicalID, role := range map[string]aws.DeploymentRole{
+		"PipelineRole":  deployment.PipelineRole,
+		"CodeBuildRole": deployment.CodeBuildRole,
+	} {
+		properties, err := cfnRoleProperties(deployment, role)
+		if err != nil {
+			return nil, err
+		}
+
+		resources[logicalID] = cfnResource{Type: "AWS::IAM::Role", Properties: properties}
+	}
+
+	for logicalID, bucketName := range map[string]string{
+		"StateBucket":    deployment.StateBucketName,
+		"ArtifactBucket": deployment.ArtifactBucketName,
+	} {
+		publicAccessBlock, err := toTree(deployment.PublicAccessBlock())
+		if err != nil {
+			return nil, err
+		}
+
+		policy, err := toTree(deployment.BucketPolicy(bucketName))
+		if err != nil {
+			return nil, err
+		}
+
+		resources[logicalID] = cfnResource{
+			Type: "AWS::S3::Bucket",
+			Properties: map[string]interface{}{
+				"BucketName":                     bucketName,
+				"PublicAccessBlockConfiguration": publicAccessBlock,
+				"Tags":                           cfnTags(),
+			},
+		}
+
+		resources[logicalID+"Policy"] = cfnResource{
+			Type:      "AWS::S3::BucketPolicy",
+			DependsOn: []string{"CodeBuildRole"},
+			Properties: map[string]interface{}{
+				"Bucket":         map[string]interface{}{"Ref": logicalID},
+				"PolicyDocument": policy,
+			},
+		}
+	}
+
+	resources["Repository"] = cfnResource{
+		Type: "AWS::CodeCommit::Repository",
+		Properties: map[string]interface{}{
+			"RepositoryName":        deployment.Repository.Name,
+			"RepositoryDescription": deployment.Repository.Description,
+			"Tags":                  cfnTags(),
+		},
+	}
+
+	project, err := toTree(deployment.ProjectInput())
+	if err != nil {
+		return nil, err
+	}
+
+	resources["Project"] = cfnResource{
+		Type:       "AWS::CodeBuild::Project",
+		DependsOn:  []string{"CodeBuildRole"},
+		Properties: project.(map[string]interface{}),
+	}
+
+	pipelineInput := deployment.PipelineInput()
+
+	pipeline, err := toTree(pipelineInput.Pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineProperties := pipeline.(map[string]interface{})
+	pipelineProperties["Tags"] = cfnTags()
+
+	resources["Pipeline"] = cfnResource{
+		Type:       "AWS::CodePipeline::Pipeline",
+		DependsOn:  []string{"PipelineRole", "Project", "ArtifactBucket", "Repository"},
+		Properties: pipelineProperties,
+	}
+
+	if deployment.Notifications != nil {
+		if err := addCfnNotifications(resources, deployment); err != nil {
+			return nil, err
+		}
+	}
+
+	template := map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              "AFT deployment resources exported by aftctl",
+		"Parameters":               cfnParameters(parameters),
+		"Resources":                mapStrings(cfnResourcesTree(resources), cfnSub),
+	}
+
+	return marshal(template)
+}
+
+// addCfnNotifications adds the notifications topic, its subscriptions and the pipeline notification rule
+func addCfnNotifications(resources map[string]cfnResource, deployment aws.Deployment) error {
+	var subscriptions []interface{}
+	for _, email := range deployment.Notifications.Emails {
+		subscriptions = append(subscriptions, map[string]interface{}{
+			"Endpoint": email,
+			"Protocol": "email",
+		})
+	}
+
+	topicProperties := map[string]interface{}{
+		"TopicName": deployment.Notifications.TopicName,
+		"Tags":      cfnTags(),
+	}
+
+	if len(subscriptions) > 0 {
+		topicProperties["Subscription"] = subscriptions
+	}
+
+	resources["NotificationsTopic"] = cfnResource{
+		Type:       "AWS::SNS::Topic",
+		Properties: topicProperties,
+	}
+
+	topicPolicy, err := toTree(deployment.TopicPolicy())
+	if err != nil {
+		return err
+	}
+
+	resources["NotificationsTopicPolicy"] = cfnResource{
+		Type: "AWS::SNS::TopicPolicy",
+		Properties: map[string]interface{}{
+			"Topics":         []interface{}{map[string]interface{}{"Ref": "NotificationsTopic"}},
+			"PolicyDocument": topicPolicy,
+		},
+	}
+
+	rule, err := toTree(deployment.NotificationRuleInput())
+	if err != nil {
+		return err
+	}
+
+	resources["NotificationRule"] = cfnResource{
+		Type:       "AWS::CodeStarNotifications::NotificationRule",
+		DependsOn:  []string{"Pipeline", "NotificationsTopicPolicy"},
+		Properties: rule.(map[string]interface{}),
+	}
+
+	return nil
+}
+
+// cfnRoleProperties renders the role with its trust policy, inline policy and managed policies
+func cfnRoleProperties(deployment aws.Deployment, role aws.DeploymentRole) (map[string]interface{}, error) {
+	tree, err := toTree(deployment.RoleInput(role))
+	if err != nil {
+		return nil, err
+	}
+
+	properties := tree.(map[string]interface{})
+
+	// CloudFormation takes the policy documents as objects rather than strings
+	properties["AssumeRolePolicyDocument"], err = toTree(aws.NewAssumeRolePolicy(role.TrustService))
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := toTree(role.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	properties["Policies"] = []interface{}{
+		map[string]interface{}{
+			"PolicyName":     role.PolicyName,
+			"PolicyDocument": policy,
+		},
+	}
+
+	if len(role.ManagedPolicyArns) > 0 {
+		properties["ManagedPolicyArns"] = role.ManagedPolicyArns
+	}
+
+	return properties, nil
+}
+
+// cfnParameters renders the template parameters, the ones without default are required
+func cfnParameters(parameters []Parameter) map[string]interface{} {
+	result := map[string]interface{}{}
+
+	for _, parameter := range parameters {
+		definition := map[string]interface{}{
+			"Type":        "String",
+			"Description": parameter.Description,
+		}
+
+		if parameter.Default != "" {
+			definition["Default"] = parameter.Default
+		}
+
+		result[parameter.Name] = definition
+	}
+
+	return result
+}
+
+// cfnResourcesTree converts the resources into plain maps so their strings can be substituted
+func cfnResourcesTree(resources map[string]cfnResource) map[string]interface{} {
+	tree := map[string]interface{}{}
+
+	for logicalID, resource := range resources {
+		definition := map[string]interface{}{
+			"Type":       resource.Type,
+			"Properties": resource.Properties,
+		}
+
+		if len(resource.DependsOn) > 0 {
+			definition["DependsOn"] = resource.DependsOn
+		}
+
+		tree[logicalID] = definition
+	}
+
+	return tree
+}
+
+// cfnSub wraps the strings referring to parameters or the region into Fn::Sub
+func cfnSub(s string) interface{} {
+	if !hasRef(s) {
+		return s
+	}
+
+	return map[string]interface{}{"Fn::Sub": s}
+}
+
+// cfnTags returns the tags aftctl puts on every resource
+func cfnTags() []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"Key":   tags.Aftctl,
+			"Value": tags.True,
+		},
+	}
+}
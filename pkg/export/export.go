@@ -0,0 +1,186 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package export renders the AFT deployment resources as infrastructure as code.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+)
+
+// Format is the infrastructure as code language of the export.
+type Format string
+
+const (
+	// CloudFormationFormat renders a single CloudFormation template.
+	CloudFormationFormat Format = "cloudformation"
+
+	// TerraformFormat renders a single Terraform configuration in JSON syntax.
+	TerraformFormat Format = "terraform"
+)
+
+// Formats lists the accepted export formats.
+var Formats = []string{string(CloudFormationFormat), string(TerraformFormat)}
+
+// file names of the rendered exports
+const (
+	cloudFormationFileName = "aft-deployment.template.json"
+	terraformFileName      = "main.tf.json"
+)
+
+// RegionRef references the region the export is deployed to.
+const RegionRef = "${AWS::Region}"
+
+// Parameter is an input of the export, e.g. an account ID or a resource name.
+// The deployment refers to it with Ref, and an empty Default makes it required.
+type Parameter struct {
+	Name        string
+	Description string
+	Default     string
+}
+
+// Ref returns the reference to the named parameter, to be used in place of its value in the deployment.
+func Ref(name string) string {
+	return "${" + name + "}"
+}
+
+// Write renders the deployment in the given format into dir and returns the path of the written file.
+func Write(deployment aws.Deployment, parameters []Parameter, format Format, dir string) (string, error) {
+	var (
+		content  []byte
+		fileName string
+		err      error
+	)
+
+	switch format {
+	case CloudFormationFormat:
+		content, err = CloudFormationTemplate(deployment, parameters)
+		fileName = cloudFormationFileName
+	case TerraformFormat:
+		content, err = TerraformConfiguration(deployment, parameters)
+		fileName = terraformFileName
+	default:
+		return "", fmt.Errorf("invalid export format %q, allowed options are %s", format, Formats)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating the export directory: %w", err)
+	}
+
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return "", fmt.Errorf("error writing the export: %w", err)
+	}
+
+	return path, nil
+}
+
+// toTree converts an API input or policy into plain maps and slices, dropping unset fields
+func toTree(v interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	return prune(tree), nil
+}
+
+// prune removes the null values and empty collections left by unset API fields
+func prune(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			item = prune(item)
+			if isEmpty(item) {
+				delete(v, key)
+				continue
+			}
+			v[key] = item
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = prune(item)
+		}
+	}
+
+	return value
+}
+
+// isEmpty reports whether the pruned value holds nothing
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+
+	return false
+}
+
+// mapStrings applies fn to every string value of the tree
+func mapStrings(value interface{}, fn func(string) interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = mapStrings(item, fn)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = mapStrings(item, fn)
+		}
+	case []string:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = fn(item)
+		}
+		return result
+	case map[string]string:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = fn(item)
+		}
+		return result
+	}
+
+	return value
+}
+
+// hasRef reports whether the string refers to a parameter or the region
+func hasRef(s string) bool {
+	return strings.Contains(s, "${")
+}
+
+// marshal renders the document as indented JSON
+func marshal(document interface{}) ([]byte, error) {
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(content, '\n'), nil
+}
@@ -0,0 +1,399 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package export
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	sdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codebuild"
+	"github.com/aws/aws-sdk-go/service/codepipeline"
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	"github.com/edgarsilva948/aftctl/pkg/aws/tags"
+)
+
+// tfRegionRef is the Terraform counterpart of RegionRef
+const tfRegionRef = "${data.aws_region.current.name}"
+
+// tfResources holds the resources of the configuration by type and name
+type tfResources map[string]map[string]interface{}
+
+// add registers a resource of the given type and name
+func (r tfResources) add(resourceType string, name string, resource map[string]interface{}) {
+	if r[resourceType] == nil {
+		r[resourceType] = map[string]interface{}{}
+	}
+
+	r[resourceType][name] = resource
+}
+
+// tree converts the resources into plain maps so their strings can be interpolated
+func (r tfResources) tree() map[string]interface{} {
+	tree := map[string]interface{}{}
+
+	for resourceType, resources := range r {
+		tree[resourceType] = resources
+	}
+
+	return tree
+}
+
+// TerraformConfiguration renders the deployment as a single Terraform configuration in JSON syntax.
+func TerraformConfiguration(deployment aws.Deployment, parameters []Parameter) ([]byte, error) {
+	resources := tfResources{}
+
+	addTfRole(resources, deployment, "pipeline", deployment.PipelineRole)
+	addTfRole(resources, deployment, "codebuild", deployment.CodeBuildRole)
+
+	addTfBucket(resources, deployment, "state", deployment.StateBucketName)
+	addTfBucket(resources, deployment, "artifact", deployment.ArtifactBucketName)
+
+	resources.add("aws_codecommit_repository", "deployment", map[string]interface{}{
+		"repository_name": deployment.Repository.Name,
+		"description":     deployment.Repository.Description,
+		"tags":            tfTags(),
+	})
+
+	project := tfProject(deployment.ProjectInput())
+	project["depends_on"] = []string{"aws_iam_role_policy.codebuild"}
+	resources.add("aws_codebuild_project", "deployment", project)
+
+	pipeline := tfPipeline(deployment.PipelineInput())
+	pipeline["depends_on"] = []string{
+		"aws_iam_role_policy.pipeline",
+		"aws_codebuild_project.deployment",
+		"aws_codecommit_repository.deployment",
+		"aws_s3_bucket.artifact",
+	}
+	resources.add("aws_codepipeline", "deployment", pipeline)
+
+	if deployment.Notifications != nil {
+		addTfNotifications(resources, deployment)
+	}
+
+	configuration := map[string]interface{}{
+		"terraform": map[string]interface{}{
+			"required_providers": map[string]interface{}{
+				"aws": map[string]interface{}{
+					"source": "hashicorp/aws",
+				},
+			},
+		},
+		"data": map[string]interface{}{
+			"aws_region": map[string]interface{}{
+				"current": map[string]interface{}{},
+			},
+		},
+		"variable": tfVariables(parameters),
+		"resource": mapStrings(resources.tree(), tfInterpolation(parameters)),
+	}
+
+	return marshal(configuration)
+}
+
+// addTfRole adds the role, its inline policy and the managed policy attachments
+func addTfRole(resources tfResources, deployment aws.Deployment, name string, role aws.DeploymentRole) {
+	input := deployment.RoleInput(role)
+
+	resource := map[string]interface{}{
+		"name":               sdk.StringValue(input.RoleName),
+		"path":               sdk.StringValue(input.Path),
+		"assume_role_policy": sdk.StringValue(input.AssumeRolePolicyDocument),
+		"tags":               tfTags(),
+	}
+
+	setString(resource, "permissions_boundary", input.PermissionsBoundary)
+	setString(resource, "description", input.Description)
+
+	if input.MaxSessionDuration != nil {
+		resource["max_session_duration"] = sdk.Int64Value(input.MaxSessionDuration)
+	}
+
+	resources.add("aws_iam_role", name, resource)
+
+	resources.add("aws_iam_role_policy", name, map[string]interface{}{
+		"name":   role.PolicyName,
+		"role":   fmt.Sprintf("${aws_iam_role.%s.id}", name),
+		"policy": role.Policy.String(),
+	})
+
+	for i, policyArn := range role.ManagedPolicyArns {
+		resources.add("aws_iam_role_policy_attachment", fmt.Sprintf("%s_%d", name, i), map[string]interface{}{
+			"role":       fmt.Sprintf("${aws_iam_role.%s.name}", name),
+			"policy_arn": policyArn,
+		})
+	}
+}
+
+// addTfBucket adds the bucket, its public access block and its policy
+func addTfBucket(resources tfResources, deployment aws.Deployment, name string, bucketName string) {
+	bucketRef := fmt.Sprintf("${aws_s3_bucket.%s.id}", name)
+	publicAccessBlock := deployment.PublicAccessBlock()
+
+	resources.add("aws_s3_bucket", name, map[string]interface{}{
+		"bucket": bucketName,
+		"tags":   tfTags(),
+	})
+
+	resources.add("aws_s3_bucket_public_access_block", name, map[string]interface{}{
+		"bucket":                  bucketRef,
+		"block_public_acls":       sdk.BoolValue(publicAccessBlock.BlockPublicAcls),
+		"ignore_public_acls":      sdk.BoolValue(publicAccessBlock.IgnorePublicAcls),
+		"block_public_policy":     sdk.BoolValue(publicAccessBlock.BlockPublicPolicy),
+		"restrict_public_buckets": sdk.BoolValue(publicAccessBlock.RestrictPublicBuckets),
+	})
+
+	resources.add("aws_s3_bucket_policy", name, map[string]interface{}{
+		"bucket": bucketRef,
+		"policy": deployment.BucketPolicy(bucketName).String(),
+		"depends_on": []string{
+			"aws_iam_role.codebuild",
+			"aws_s3_bucket_public_access_block." + name,
+		},
+	})
+}
+
+// addTfNotifications adds the notifications topic, its subscriptions and the pipeline notification rule
+func addTfNotifications(resources tfResources, deployment aws.Deployment) {
+	resources.add("aws_sns_topic", "notifications", map[string]interface{}{
+		"name": deployment.Notifications.TopicName,
+		"tags": tfTags(),
+	})
+
+	resources.add("aws_sns_topic_policy", "notifications", map[string]interface{}{
+		"arn":    "${aws_sns_topic.notifications.arn}",
+		"policy": deployment.TopicPolicy().String(),
+	})
+
+	for i, email := range deployment.Notifications.Emails {
+		resources.add("aws_sns_topic_subscription", fmt.Sprintf("notifications_%d", i), map[string]interface{}{
+			"topic_arn": "${aws_sns_topic.notifications.arn}",
+			"protocol":  "email",
+			"endpoint":  email,
+		})
+	}
+
+	input := deployment.NotificationRuleInput()
+
+	var targets []interface{}
+	for _, target := range input.Targets {
+		targets = append(targets, map[string]interface{}{
+			"type":    sdk.StringValue(target.TargetType),
+			"address": sdk.StringValue(target.TargetAddress),
+		})
+	}
+
+	resources.add("aws_codestarnotifications_notification_rule", "pipeline", map[string]interface{}{
+		"name":           sdk.StringValue(input.Name),
+		"resource":       sdk.StringValue(input.Resource),
+		"detail_type":    sdk.StringValue(input.DetailType),
+		"status":         sdk.StringValue(input.Status),
+		"event_type_ids": sdk.StringValueSlice(input.EventTypeIds),
+		"target":         targets,
+		"tags":           tfTags(),
+		"depends_on": []string{
+			"aws_codepipeline.deployment",
+			"aws_sns_topic_policy.notifications",
+		},
+	})
+}
+
+// tfProject converts the CodeBuild project input into an aws_codebuild_project
+func tfProject(input *codebuild.CreateProjectInput) map[string]interface{} {
+	var variables []interface{}
+	for _, variable := range input.Environment.EnvironmentVariables {
+		v := map[string]interface{}{
+			"name":  sdk.StringValue(variable.Name),
+			"value": sdk.StringValue(variable.Value),
+		}
+		setString(v, "type", variable.Type)
+		variables = append(variables, v)
+	}
+
+	environment := map[string]interface{}{
+		"compute_type":         sdk.StringValue(input.Environment.ComputeType),
+		"image":                sdk.StringValue(input.Environment.Image),
+		"type":                 sdk.StringValue(input.Environment.Type),
+		"privileged_mode":      sdk.BoolValue(input.Environment.PrivilegedMode),
+		"environment_variable": variables,
+	}
+
+	cloudWatchLogs := map[string]interface{}{
+		"status": sdk.StringValue(input.LogsConfig.CloudWatchLogs.Status),
+	}
+	setString(cloudWatchLogs, "group_name", input.LogsConfig.CloudWatchLogs.GroupName)
+	setString(cloudWatchLogs, "stream_name", input.LogsConfig.CloudWatchLogs.StreamName)
+
+	s3Logs := map[string]interface{}{
+		"status": sdk.StringValue(input.LogsConfig.S3Logs.Status),
+	}
+	setString(s3Logs, "location", input.LogsConfig.S3Logs.Location)
+
+	cache := map[string]interface{}{
+		"type": sdk.StringValue(input.Cache.Type),
+	}
+	if len(input.Cache.Modes) > 0 {
+		cache["modes"] = sdk.StringValueSlice(input.Cache.Modes)
+	}
+
+	project := map[string]interface{}{
+		"name":         sdk.StringValue(input.Name),
+		"service_role": sdk.StringValue(input.ServiceRole),
+		"artifacts":    map[string]interface{}{"type": sdk.StringValue(input.Artifacts.Type)},
+		"source":       map[string]interface{}{"type": sdk.StringValue(input.Source.Type)},
+		"environment":  environment,
+		"logs_config": map[string]interface{}{
+			"cloudwatch_logs": cloudWatchLogs,
+			"s3_logs":         s3Logs,
+		},
+		"cache": cache,
+		"tags":  tfTags(),
+	}
+
+	if input.VpcConfig != nil {
+		project["vpc_config"] = map[string]interface{}{
+			"vpc_id":             sdk.StringValue(input.VpcConfig.VpcId),
+			"subnets":            sdk.StringValueSlice(input.VpcConfig.Subnets),
+			"security_group_ids": sdk.StringValueSlice(input.VpcConfig.SecurityGroupIds),
+		}
+	}
+
+	if input.TimeoutInMinutes != nil {
+		project["build_timeout"] = sdk.Int64Value(input.TimeoutInMinutes)
+	}
+
+	if input.QueuedTimeoutInMinutes != nil {
+		project["queued_timeout"] = sdk.Int64Value(input.QueuedTimeoutInMinutes)
+	}
+
+	return project
+}
+
+// tfPipeline converts the pipeline input into an aws_codepipeline
+func tfPipeline(input *codepipeline.CreatePipelineInput) map[string]interface{} {
+	var stages []interface{}
+
+	for _, stage := range input.Pipeline.Stages {
+		var actions []interface{}
+
+		for _, action := range stage.Actions {
+			a := map[string]interface{}{
+				"name":          sdk.StringValue(action.Name),
+				"category":      sdk.StringValue(action.ActionTypeId.Category),
+				"owner":         sdk.StringValue(action.ActionTypeId.Owner),
+				"provider":      sdk.StringValue(action.ActionTypeId.Provider),
+				"version":       sdk.StringValue(action.ActionTypeId.Version),
+				"configuration": sdk.StringValueMap(action.Configuration),
+				"run_order":     sdk.Int64Value(action.RunOrder),
+			}
+
+			var inputArtifacts []string
+			for _, artifact := range action.InputArtifacts {
+				inputArtifacts = append(inputArtifacts, sdk.StringValue(artifact.Name))
+			}
+			if len(inputArtifacts) > 0 {
+				a["input_artifacts"] = inputArtifacts
+			}
+
+			var outputArtifacts []string
+			for _, artifact := range action.OutputArtifacts {
+				outputArtifacts = append(outputArtifacts, sdk.StringValue(artifact.Name))
+			}
+			if len(outputArtifacts) > 0 {
+				a["output_artifacts"] = outputArtifacts
+			}
+
+			actions = append(actions, a)
+		}
+
+		stages = append(stages, map[string]interface{}{
+			"name":   sdk.StringValue(stage.Name),
+			"action": actions,
+		})
+	}
+
+	return map[string]interface{}{
+		"name":     sdk.StringValue(input.Pipeline.Name),
+		"role_arn": sdk.StringValue(input.Pipeline.RoleArn),
+		"artifact_store": map[string]interface{}{
+			"type":     sdk.StringValue(input.Pipeline.ArtifactStore.Type),
+			"location": sdk.StringValue(input.Pipeline.ArtifactStore.Location),
+		},
+		"stage": stages,
+		"tags":  tfTags(),
+	}
+}
+
+// tfVariables renders the parameters as input variables, the ones without default are required
+func tfVariables(parameters []Parameter) map[string]interface{} {
+	result := map[string]interface{}{}
+
+	for _, parameter := range parameters {
+		variable := map[string]interface{}{
+			"type":        "string",
+			"description": parameter.Description,
+		}
+
+		if parameter.Default != "" {
+			variable["default"] = parameter.Default
+		}
+
+		result[variableName(parameter.Name)] = variable
+	}
+
+	return result
+}
+
+// tfInterpolation returns the function replacing the parameter references by Terraform interpolations
+func tfInterpolation(parameters []Parameter) func(string) interface{} {
+	replacements := []string{RegionRef, tfRegionRef}
+
+	for _, parameter := range parameters {
+		replacements = append(replacements, Ref(parameter.Name), fmt.Sprintf("${var.%s}", variableName(parameter.Name)))
+	}
+
+	replacer := strings.NewReplacer(replacements...)
+
+	return func(s string) interface{} {
+		if !hasRef(s) {
+			return s
+		}
+
+		return replacer.Replace(s)
+	}
+}
+
+// variableName converts a parameter name to a Terraform variable name, e.g. AftManagementAccountId to aft_management_account_id
+func variableName(name string) string {
+	var builder strings.Builder
+
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				builder.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
+
+// setString sets the key when the value is set
+func setString(m map[string]interface{}, key string, value *string) {
+	if value != nil && *value != "" {
+		m[key] = *value
+	}
+}
+
+// tfTags returns the tags aftctl puts on every resource
+func tfTags() map[string]interface{} {
+	return map[string]interface{}{
+		tags.Aftctl: tags.True,
+	}
+}
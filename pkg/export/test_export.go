@@ -0,0 +1,173 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// newTestDeployment returns a deployment referring to the test parameters
+func newTestDeployment() aws.Deployment {
+	policy, _ := aws.NewRolePolicy(aws.PipelineRolePurpose, aws.PolicyScope{
+		Region:                 RegionRef,
+		AftManagementAccountID: Ref("AftManagementAccountId"),
+		RepoName:               "aft-deployment",
+		ProjectName:            "aft-deployment-build",
+		ArtifactBucketName:     Ref("AftManagementAccountId") + "-artifact",
+	})
+
+	return aws.Deployment{
+		Region:                 RegionRef,
+		AftManagementAccountID: Ref("AftManagementAccountId"),
+		PipelineRole: aws.DeploymentRole{
+			Name:         "aft-deployment-codepipeline-service-role",
+			TrustService: "codepipeline.amazonaws.com",
+			PolicyName:   "aft-deployment-codepipeline-service-role-policy",
+			Policy:       policy,
+		},
+		CodeBuildRole: aws.DeploymentRole{
+			Name:              "aft-deployment-codebuild-service-role",
+			TrustService:      "codebuild.amazonaws.com",
+			PolicyName:        "aft-deployment-build-service-role-policy",
+			Policy:            policy,
+			ManagedPolicyArns: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		},
+		StateBucketName:    Ref("AftManagementAccountId") + "-tfstate",
+		ArtifactBucketName: Ref("AftManagementAccountId") + "-artifact",
+		Repository: aws.DeploymentRepository{
+			Name:   "aft-deployment",
+			Branch: "main",
+		},
+		Project: aws.DeploymentProject{
+			Name: "aft-deployment-build",
+			Config: aws.CodeBuildProjectConfig{
+				Image:           "aws/codebuild/amazonlinux2-x86_64-standard:4.0",
+				ComputeType:     "BUILD_GENERAL1_SMALL",
+				EnvironmentType: "LINUX_CONTAINER",
+			},
+		},
+		PipelineName: "aft-deployment-pipeline",
+	}
+}
+
+var testParameters = []Parameter{
+	{Name: "AftManagementAccountId", Description: "AFT Management account ID", Default: "123456789012"},
+}
+
+// decode parses the rendered document
+func decode(content []byte) map[string]interface{} {
+	var document map[string]interface{}
+	gomega.Expect(json.Unmarshal(content, &document)).To(gomega.Succeed())
+
+	return document
+}
+
+var _ = ginkgo.Describe("Exporting the deployment", func() {
+
+	ginkgo.Context("testing the CloudFormationTemplate function", func() {
+		ginkgo.When("the deployment refers to parameters", func() {
+			ginkgo.It("should substitute them and declare the parameters", func() {
+				content, err := CloudFormationTemplate(newTestDeployment(), testParameters)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				template := decode(content)
+				gomega.Expect(template["Parameters"]).To(gomega.HaveKey("AftManagementAccountId"))
+
+				resources := template["Resources"].(map[string]interface{})
+				gomega.Expect(resources).To(gomega.HaveKey("PipelineRole"))
+				gomega.Expect(resources).To(gomega.HaveKey("StateBucketPolicy"))
+				gomega.Expect(resources).ToNot(gomega.HaveKey("NotificationsTopic"))
+
+				project := resources["Project"].(map[string]interface{})["Properties"].(map[string]interface{})
+				gomega.Expect(project["Name"]).To(gomega.Equal("aft-deployment-build"))
+				gomega.Expect(project["ServiceRole"]).To(gomega.Equal(map[string]interface{}{
+					"Fn::Sub": "arn:aws:iam::${AftManagementAccountId}:role/aft-deployment-codebuild-service-role",
+				}))
+
+				role := resources["CodeBuildRole"].(map[string]interface{})["Properties"].(map[string]interface{})
+				gomega.Expect(role["ManagedPolicyArns"]).To(gomega.Equal([]interface{}{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+				gomega.Expect(role["AssumeRolePolicyDocument"]).To(gomega.BeAssignableToTypeOf(map[string]interface{}{}))
+			})
+		})
+
+		ginkgo.When("the pipeline notifications are enabled", func() {
+			ginkgo.It("should add the topic and the notification rule", func() {
+				deployment := newTestDeployment()
+				deployment.Notifications = &aws.DeploymentNotifications{
+					TopicName: "aft-deployment-notifications",
+					Emails:    []string{"ops@example.com"},
+				}
+
+				content, err := CloudFormationTemplate(deployment, testParameters)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				resources := decode(content)["Resources"].(map[string]interface{})
+				gomega.Expect(resources).To(gomega.HaveKey("NotificationsTopic"))
+				gomega.Expect(resources).To(gomega.HaveKey("NotificationsTopicPolicy"))
+
+				rule := resources["NotificationRule"].(map[string]interface{})["Properties"].(map[string]interface{})
+				gomega.Expect(rule["EventTypeIds"]).To(gomega.HaveLen(len(aws.PipelineNotificationEvents)))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the TerraformConfiguration function", func() {
+		ginkgo.When("the deployment refers to parameters", func() {
+			ginkgo.It("should interpolate the variables and the region", func() {
+				content, err := TerraformConfiguration(newTestDeployment(), testParameters)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				configuration := decode(content)
+				gomega.Expect(configuration["variable"]).To(gomega.HaveKey("aft_management_account_id"))
+
+				resources := configuration["resource"].(map[string]interface{})
+				bucket := resources["aws_s3_bucket"].(map[string]interface{})["state"].(map[string]interface{})
+				gomega.Expect(bucket["bucket"]).To(gomega.Equal("${var.aft_management_account_id}-tfstate"))
+
+				policy := resources["aws_iam_role_policy"].(map[string]interface{})["pipeline"].(map[string]interface{})
+				gomega.Expect(policy["policy"]).To(gomega.ContainSubstring("arn:aws:codebuild:${data.aws_region.current.name}:${var.aft_management_account_id}:project/aft-deployment-build"))
+
+				gomega.Expect(resources["aws_iam_role_policy_attachment"]).To(gomega.HaveKey("codebuild_0"))
+				gomega.Expect(resources).ToNot(gomega.HaveKey("aws_sns_topic"))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the Write function", func() {
+		ginkgo.When("the format is unknown", func() {
+			ginkgo.It("should return an error", func() {
+				_, err := Write(newTestDeployment(), testParameters, Format("pulumi"), ginkgo.GinkgoT().TempDir())
+				gomega.Expect(err).To(gomega.MatchError(`invalid export format "pulumi", allowed options are [cloudformation terraform]`))
+			})
+		})
+
+		ginkgo.When("the format is terraform", func() {
+			ginkgo.It("should write the configuration into the directory", func() {
+				dir := filepath.Join(ginkgo.GinkgoT().TempDir(), "export")
+
+				path, err := Write(newTestDeployment(), testParameters, TerraformFormat, dir)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(path).To(gomega.Equal(filepath.Join(dir, "main.tf.json")))
+
+				_, err = os.Stat(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			})
+		})
+	})
+
+	ginkgo.Context("testing the variableName function", func() {
+		ginkgo.When("the parameter name is in pascal case", func() {
+			ginkgo.It("should return it in snake case", func() {
+				gomega.Expect(variableName("AftManagementAccountId")).To(gomega.Equal("aft_management_account_id"))
+			})
+		})
+	})
+})
@@ -2,35 +2,151 @@
 Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 */
 
-// Package gitignore generates a .gitignore file
+// Package gitignore keeps the files aftctl generates out of git with a marked block of the .gitignore file
 package gitignore
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
-// GenerateGitIgnore generates a .gitignore file
-func GenerateGitIgnore() bool {
-	ignoreList := []string{
-		"aft-input.auto.tfvars",
-		"aft-providers.tf",
-		"backend.tf",
-		".gitignore",
-		".terraform*",
+// markers of the aftctl block, the rules outside of it belong to the repository
+const (
+	BeginMarker = "# BEGIN aftctl generated files"
+	EndMarker   = "# END aftctl generated files"
+)
+
+// FileName is the gitignore file of a directory
+const FileName = ".gitignore"
+
+// Merge writes the patterns in the aftctl block of the .gitignore file of the directory, replacing the previous
+// block where it stands, or appending it, and keeping the other rules. The file is left untouched when the block
+// already holds the patterns.
+func Merge(dir string, patterns []string) (bool, error) {
+	path := filepath.Join(dir, FileName)
+
+	content, err := read(path)
+	if err != nil {
+		return false, err
+	}
+
+	block := strings.Join(append(append([]string{BeginMarker}, patterns...), EndMarker), "\n") + "\n"
+
+	merged := appendBlock(content, block)
+	if before, after, found := split(content); found {
+		merged = before + block + after
+	}
+
+	if merged == content {
+		return false, nil
 	}
 
-	// Join the list into a single string with newlines
-	content := strings.Join(ignoreList, "\n")
+	if err := os.WriteFile(path, []byte(merged), 0644); err != nil {
+		return false, fmt.Errorf("error writing %s: %w", path, err)
+	}
 
-	// Write the content to .gitignore
-	err := os.WriteFile(".gitignore", []byte(content), 0644)
+	return true, nil
+}
+
+// RemoveBlock removes the aftctl block of the .gitignore file of the directory, and the file when nothing else is
+// left unless keepFile is set, e.g. when git tracks it. It reports whether the file held the block.
+func RemoveBlock(dir string, keepFile bool) (bool, error) {
+	path := filepath.Join(dir, FileName)
+
+	content, err := read(path)
 	if err != nil {
-		fmt.Println("Error:", err)
-		return false
+		return false, err
+	}
+
+	before, after, found := split(content)
+	if !found {
+		return false, nil
 	}
 
-	return true
+	// the blank line separating the appended block from the rules above belongs to the block
+	if after == "" && strings.HasSuffix(before, "\n\n") {
+		before = strings.TrimSuffix(before, "\n")
+	}
+	if strings.TrimSpace(before) == "" {
+		before = ""
+	}
+
+	outside := before + after
+
+	if outside == "" && !keepFile {
+		if err := os.Remove(path); err != nil {
+			return false, fmt.Errorf("error removing %s: %w", path, err)
+		}
+		return true, nil
+	}
+
+	if err := os.WriteFile(path, []byte(outside), 0644); err != nil {
+		return false, fmt.Errorf("error writing %s: %w", path, err)
+	}
+
+	return true, nil
+}
+
+// HasBlock reports whether the .gitignore file of the directory holds the aftctl block
+func HasBlock(dir string) (bool, error) {
+	content, err := read(filepath.Join(dir, FileName))
+	if err != nil {
+		return false, err
+	}
+
+	_, _, found := split(content)
+
+	return found, nil
+}
+
+// read returns the content of the file, a missing file is empty
+func read(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	return string(content), nil
+}
+
+// split returns the content before and after the aftctl block and whether the block was found, a block without end
+// marker runs to the end of the file
+func split(content string) (string, string, bool) {
+	var before, after strings.Builder
+	found, inBlock := false, false
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case !found && trimmed == BeginMarker:
+			found, inBlock = true, true
+		case inBlock:
+			inBlock = trimmed != EndMarker
+		case found:
+			after.WriteString(line)
+		default:
+			before.WriteString(line)
+		}
+	}
+
+	return before.String(), after.String(), found
+}
+
+// appendBlock appends the block to the content, separated by a blank line from the rules above
+func appendBlock(content string, block string) string {
+	if content != "" {
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += "\n"
+	}
 
+	return content + block
 }
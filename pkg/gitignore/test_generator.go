@@ -0,0 +1,135 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package gitignore
+
+import (
+	"os"
+	"path/filepath"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Generating the .gitignore file", func() {
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		dir = ginkgo.GinkgoT().TempDir()
+	})
+
+	writeGitignore := func(content string) {
+		gomega.Expect(os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0600)).To(gomega.Succeed())
+	}
+
+	readGitignore := func() string {
+		content, err := os.ReadFile(filepath.Join(dir, FileName))
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		return string(content)
+	}
+
+	ginkgo.Context("testing the Merge function", func() {
+		ginkgo.When("the directory has no .gitignore file", func() {
+			ginkgo.It("should create it with the aftctl block", func() {
+				changed, err := Merge(dir, []string{"backend.tf", ".terraform*"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(changed).To(gomega.BeTrue())
+				gomega.Expect(readGitignore()).To(gomega.Equal(BeginMarker + "\nbackend.tf\n.terraform*\n" + EndMarker + "\n"))
+			})
+		})
+
+		ginkgo.When("the .gitignore file has rules of the repository", func() {
+			ginkgo.It("should keep them and append the block", func() {
+				writeGitignore("*.zip")
+
+				changed, err := Merge(dir, []string{"backend.tf"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(changed).To(gomega.BeTrue())
+				gomega.Expect(readGitignore()).To(gomega.Equal("*.zip\n\n" + BeginMarker + "\nbackend.tf\n" + EndMarker + "\n"))
+			})
+
+			ginkgo.It("should replace the previous block where it stands", func() {
+				writeGitignore("*.tf\n" + BeginMarker + "\nold.tf\n" + EndMarker + "\n!main.tf\nvenv/")
+
+				changed, err := Merge(dir, []string{"backend.tf"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(changed).To(gomega.BeTrue())
+				gomega.Expect(readGitignore()).To(gomega.Equal("*.tf\n" + BeginMarker + "\nbackend.tf\n" + EndMarker + "\n!main.tf\nvenv/"))
+			})
+		})
+
+		ginkgo.When("the block already holds the patterns", func() {
+			ginkgo.It("should leave the file untouched", func() {
+				_, err := Merge(dir, []string{"backend.tf"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				before := readGitignore()
+
+				changed, err := Merge(dir, []string{"backend.tf"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(changed).To(gomega.BeFalse())
+				gomega.Expect(readGitignore()).To(gomega.Equal(before))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the RemoveBlock function", func() {
+		ginkgo.When("the .gitignore file has rules of the repository", func() {
+			ginkgo.It("should restore them", func() {
+				writeGitignore("*.zip\n")
+				_, err := Merge(dir, []string{"backend.tf"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				removed, err := RemoveBlock(dir, false)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(removed).To(gomega.BeTrue())
+				gomega.Expect(readGitignore()).To(gomega.Equal("*.zip\n"))
+
+				found, err := HasBlock(dir)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(found).To(gomega.BeFalse())
+			})
+		})
+
+		ginkgo.When("the .gitignore file only holds the block", func() {
+			ginkgo.It("should remove the file", func() {
+				_, err := Merge(dir, []string{"backend.tf"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				removed, err := RemoveBlock(dir, false)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(removed).To(gomega.BeTrue())
+				gomega.Expect(filepath.Join(dir, FileName)).ToNot(gomega.BeAnExistingFile())
+			})
+
+			ginkgo.It("should empty the file when it is kept", func() {
+				_, err := Merge(dir, []string{"backend.tf"})
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				removed, err := RemoveBlock(dir, true)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(removed).To(gomega.BeTrue())
+				gomega.Expect(readGitignore()).To(gomega.BeEmpty())
+			})
+		})
+
+		ginkgo.When("rules of the repository follow the block", func() {
+			ginkgo.It("should keep them in place", func() {
+				writeGitignore("*.tf\n" + BeginMarker + "\nbackend.tf\n" + EndMarker + "\n!main.tf\n")
+
+				removed, err := RemoveBlock(dir, false)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(removed).To(gomega.BeTrue())
+				gomega.Expect(readGitignore()).To(gomega.Equal("*.tf\n!main.tf\n"))
+			})
+		})
+
+		ginkgo.When("the directory has no .gitignore file", func() {
+			ginkgo.It("should report that there was no block", func() {
+				removed, err := RemoveBlock(dir, false)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(removed).To(gomega.BeFalse())
+			})
+		})
+	})
+})
@@ -15,10 +15,10 @@ import (
 // Version represents the version information of the tool, including
 // the major, minor, and patch versions, as well as the Go runtime version.
 type Version struct {
-	Major     string
-	Minor     string
-	Patch     string
-	GoVersion string
+	Major     string `json:"major" yaml:"major"`
+	Minor     string `json:"minor" yaml:"minor"`
+	Patch     string `json:"patch" yaml:"patch"`
+	GoVersion string `json:"goVersion" yaml:"goVersion"`
 }
 
 // GetGoVersion returns the current Go runtime version as a string.
@@ -39,6 +39,11 @@ func BuildCurrentVersion() Version {
 
 // PrintVersion prints the current version of the tool to the provided writer.
 func PrintVersion(w io.Writer) {
-	v := BuildCurrentVersion()
-	fmt.Fprintf(w, "Version: {Major:\"%s\", Minor:\"%s\", Patch:\"%s\", GoVersion:\"%s\"}\n", v.Major, v.Minor, v.Patch, v.GoVersion)
+	BuildCurrentVersion().PrintTable(w)
+}
+
+// PrintTable prints the version for humans, it makes the version printable with the --output formats.
+func (v Version) PrintTable(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Version: {Major:\"%s\", Minor:\"%s\", Patch:\"%s\", GoVersion:\"%s\"}\n", v.Major, v.Minor, v.Patch, v.GoVersion)
+	return err
 }
@@ -9,7 +9,6 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -21,6 +20,9 @@ const fileEmoji = "📄"
 const dirEmoji = "📁"
 const zipEmoji = "📦"
 
+// TerraformPluginCacheDir is where the deployment buildspec keeps the Terraform plugin cache.
+const TerraformPluginCacheDir = "/root/.terraform.d/plugin-cache"
+
 // GenerateCommitFiles creates the directory and files to be pushed
 func GenerateCommitFiles(
 	repoName string,
@@ -38,12 +40,13 @@ func GenerateCommitFiles(
 	aftFeatureEnterpriseSupport bool,
 	aftFeatureDeleteDefaultVPCsEnabled bool,
 	terraformDistribution string,
+	pluginCache bool,
 ) {
 
 	// creating the dir with the repo name
 	message, color, err := ensureDirExists(repoName, dirEmoji)
 	if err != nil {
-		log.Fatalf("Error creating the directory: %v", err)
+		logging.Fatalf("Error creating the directory: %v", err)
 	}
 
 	logging.CustomLog(dirEmoji, color, message)
@@ -52,15 +55,15 @@ func GenerateCommitFiles(
 	message, err = createBackendtfFile(repoName, fileEmoji, tfBucket, region)
 
 	if err != nil {
-		log.Fatalf("Error creating the backend.tf file: %v", err)
+		logging.Fatalf("Error creating the backend.tf file: %v", err)
 	}
 
 	logging.CustomLog(fileEmoji, "green", message)
 
 	// creating the buildspec.yaml file
-	message, err = createBuildSpecFile(repoName, fileEmoji, tfVersion)
+	message, err = createBuildSpecFile(repoName, fileEmoji, tfVersion, pluginCache)
 	if err != nil {
-		log.Fatalf("Error creating the buildspec.yaml file: %v", err)
+		logging.Fatalf("Error creating the buildspec.yaml file: %v", err)
 	}
 
 	logging.CustomLog(fileEmoji, "green", message)
@@ -83,14 +86,14 @@ func GenerateCommitFiles(
 	)
 
 	if err != nil {
-		log.Fatalf("Error creating the main.tf file: %v", err)
+		logging.Fatalf("Error creating the main.tf file: %v", err)
 	}
 
 	logging.CustomLog(fileEmoji, "green", message)
 
 	message, err = zipDirectory(repoName, zipEmoji)
 	if err != nil {
-		fmt.Println("Error creating the zip file:", err)
+		logging.Errorf("Error creating the zip file: %v", err)
 	}
 
 	logging.CustomLog(zipEmoji, "green", message)
@@ -117,7 +120,7 @@ func createBackendtfFile(dir string, fileEmoji string, tfBucket string, region s
 	message := "File ./" + dir + "/backend.tf successfully created"
 	return message, nil
 }
-func createBuildSpecFile(dir string, fileEmoji string, tfVersion string) (string, error) {
+func createBuildSpecFile(dir string, fileEmoji string, tfVersion string, pluginCache bool) (string, error) {
 	buildSpecTemplate := `version: 0.2
 env:
   variables:
@@ -127,6 +130,7 @@ phases:
     commands:
       - |
         set -e
+        if [ -n "$TF_PLUGIN_CACHE_DIR" ]; then mkdir -p "$TF_PLUGIN_CACHE_DIR"; fi
         echo $TERRAFORM_VERSION
         echo "Installing terraform"
         cd /tmp
@@ -150,6 +154,11 @@ artifacts:
 `
 	content := fmt.Sprintf(buildSpecTemplate, tfVersion)
 
+	// the plugins are only kept between builds when the project has the local cache
+	if pluginCache {
+		content += fmt.Sprintf("cache:\n  paths:\n    - '%s/**/*'\n", TerraformPluginCacheDir)
+	}
+
 	path := filepath.Join(dir, "buildspec.yaml")
 	err := os.WriteFile(path, []byte(content), 0644)
 	if err != nil {
@@ -230,28 +239,28 @@ module "aft" {
 	validAccount, err := isValidAWSAccountID(ctManagementAccountID)
 	if !validAccount {
 		err := fmt.Errorf("management account ID is not valid: %v", err)
-		log.Println(err)
+		logging.Warnf("%v", err)
 	}
 
 	// check if log archive account id is valid
 	validAccount, err = isValidAWSAccountID(logArchiveAccountID)
 	if !validAccount {
 		err := fmt.Errorf("log Archive account ID is not valid: %v", err)
-		log.Println(err)
+		logging.Warnf("%v", err)
 	}
 
 	// check if audit account id is valid
 	validAccount, err = isValidAWSAccountID(auditAccountID)
 	if !validAccount {
 		err := fmt.Errorf("audit account ID is not valid: %v", err)
-		log.Println(err)
+		logging.Warnf("%v", err)
 	}
 
 	// check if aft account id is valid
 	validAccount, err = isValidAWSAccountID(aftManagementAccountID)
 	if !validAccount {
 		err := fmt.Errorf("AFT account ID is not valid: %v", err)
-		log.Println(err)
+		logging.Warnf("%v", err)
 	}
 
 	path := filepath.Join(dir, "main.tf")
@@ -2,17 +2,253 @@
 Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
 */
 
+// Package logging holds the single logger shared by the aftctl commands.
 package logging
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/edgarsilva948/aftctl/pkg/color"
+	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var logger *zap.Logger
+// Options configures the logger.
+type Options struct {
+	// Level is the minimum level written, one of Levels.
+	Level string
+
+	// Format is the console format, one of Formats.
+	Format string
+
+	// File also writes the logs as JSON to the given path.
+	File string
+
+	// Color enables the ANSI colors and the emoji.
+	Color bool
+}
+
+// Levels lists the accepted log levels.
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// Formats lists the accepted log formats.
+var Formats = []string{"console", "json"}
+
+var flags struct {
+	verbose bool
+	quiet   bool
+	level   string
+	format  string
+	file    string
+}
+
+var (
+	logger   = zap.NewNop()
+	sugar    = logger.Sugar()
+	useColor = false
+	logFile  *os.File
+)
+
+func init() {
+	// until the flags are parsed, log at the default level on the console
+	if err := Configure(Options{Level: "info", Format: "console"}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing logger: %v\n", err)
+	}
+}
+
+// AddFlags adds the log flags to the given set of command line flags.
+func AddFlags(cmd *cobra.Command) {
+	persistentFlags := cmd.PersistentFlags()
+
+	persistentFlags.BoolVarP(
+		&flags.verbose,
+		"verbose",
+		"v",
+		false,
+		"Write the debug logs, same as --log-level=debug",
+	)
+
+	persistentFlags.BoolVarP(
+		&flags.quiet,
+		"quiet",
+		"q",
+		false,
+		"Only write the warnings and errors, same as --log-level=warn",
+	)
+
+	persistentFlags.StringVar(
+		&flags.level,
+		"log-level",
+		"info",
+		fmt.Sprintf("Minimum level of the logs. Allowed options are %s", Levels),
+	)
+
+	persistentFlags.StringVar(
+		&flags.format,
+		"log-format",
+		"console",
+		fmt.Sprintf("Format of the logs, use json in CI. Allowed options are %s", Formats),
+	)
+
+	persistentFlags.StringVar(
+		&flags.file,
+		"log-file",
+		"",
+		"Also write the logs as JSON to this file",
+	)
+
+	cmd.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return Levels, cobra.ShellCompDirectiveDefault
+	})
+
+	cmd.RegisterFlagCompletionFunc("log-format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return Formats, cobra.ShellCompDirectiveDefault
+	})
+}
+
+// Setup configures the logger from the command line flags, it runs before every command.
+func Setup(cmd *cobra.Command) error {
+	level := flags.level
+
+	switch {
+	case flags.verbose && flags.quiet:
+		return errors.New("--verbose and --quiet can't be used together")
+	case (flags.verbose || flags.quiet) && cmd.Flags().Changed("log-level"):
+		return errors.New("--log-level can't be used together with --verbose or --quiet")
+	case flags.verbose:
+		level = "debug"
+	case flags.quiet:
+		level = "warn"
+	}
+
+	return Configure(Options{
+		Level:  level,
+		Format: flags.format,
+		File:   flags.file,
+		Color:  flags.format != "json" && color.UseColor(color.RealOsInfo{}),
+	})
+}
+
+// Configure replaces the shared logger.
+func Configure(options Options) error {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(options.Level)); err != nil || !contains(Levels, options.Level) {
+		return fmt.Errorf("invalid log level %q, allowed options are %s", options.Level, Levels)
+	}
+
+	if !contains(Formats, options.Format) {
+		return fmt.Errorf("invalid log format %q, allowed options are %s", options.Format, Formats)
+	}
+
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.TimeEncoder(func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.Format("02/01/2006 15:04:05"))
+	})
+
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	if options.Color {
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+
+	var encoder zapcore.Encoder
+	if options.Format == "json" {
+		encoder = zapcore.NewJSONEncoder(jsonEncoderConfig())
+	} else {
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
+
+	cores := []zapcore.Core{
+		zapcore.NewCore(encoder, zapcore.Lock(os.Stderr), level),
+	}
+
+	var file *os.File
+	if options.File != "" {
+		var err error
+		file, err = os.OpenFile(options.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("error opening the log file: %w", err)
+		}
+
+		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(jsonEncoderConfig()), zapcore.Lock(file), level))
+	}
+
+	Sync()
+	if logFile != nil {
+		logFile.Close()
+	}
+
+	logger = zap.New(&redactingCore{Core: zapcore.NewTee(cores...)})
+	sugar = logger.Sugar()
+	useColor = options.Color
+	logFile = file
+
+	return nil
+}
+
+// jsonEncoderConfig returns the encoder config of the JSON logs
+func jsonEncoderConfig() zapcore.EncoderConfig {
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.RFC3339TimeEncoder
+
+	return config
+}
+
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Logger returns the shared logger.
+func Logger() *zap.Logger {
+	return logger
+}
+
+// Sync flushes the buffered logs.
+func Sync() {
+	_ = logger.Sync()
+}
+
+// Debugf writes a debug log.
+func Debugf(format string, args ...interface{}) {
+	sugar.Debugf(format, args...)
+}
+
+// Infof writes an info log.
+func Infof(format string, args ...interface{}) {
+	sugar.Infof(format, args...)
+}
+
+// Infow writes an info log with the given key and value pairs.
+func Infow(msg string, keysAndValues ...interface{}) {
+	sugar.Infow(msg, keysAndValues...)
+}
+
+// Warnf writes a warning log.
+func Warnf(format string, args ...interface{}) {
+	sugar.Warnf(format, args...)
+}
+
+// Errorf writes an error log.
+func Errorf(format string, args ...interface{}) {
+	sugar.Errorf(format, args...)
+}
+
+// Fatalf writes an error log and exits.
+func Fatalf(format string, args ...interface{}) {
+	sugar.Errorf(format, args...)
+	Sync()
+	os.Exit(1)
+}
 
 // getColorCode returns ANSI color code for a given color name
 func getColorCode(colorName string) string {
@@ -33,23 +269,14 @@ func getColorCode(colorName string) string {
 	return "37"
 }
 
-// CustomLog centralizes the custom logging function
+// CustomLog writes an info log decorated with the emoji and color when --color allows them
 func CustomLog(emoji string, colorName string, msg string) {
-
-	config := zap.NewDevelopmentConfig()
-	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	config.DisableCaller = true
-	config.EncoderConfig.EncodeTime = zapcore.TimeEncoder(func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-		enc.AppendString(t.Format("02/01/2006 15:04:05"))
-	})
-
-	var err error
-	logger, err = config.Build()
-	if err != nil {
-		fmt.Printf("Error initializing logger: %v", err)
+	if !useColor {
+		logger.Info(msg)
+		return
 	}
 
 	colorCode := getColorCode(colorName)
-	coloredMsg := fmt.Sprintf("\x1b[%sm%s %s\x1b[0m", colorCode, emoji, msg)
+	coloredMsg := fmt.Sprintf("\x1b[%sm%s %s\x1b[0m", colorCode, strings.TrimSpace(emoji), msg)
 	logger.Info(coloredMsg)
 }
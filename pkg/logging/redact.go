@@ -0,0 +1,102 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package logging
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces the secrets in the logs
+const redacted = "[REDACTED]"
+
+// secretPatterns match the secrets that can end up in a log line, e.g. in an AWS error or a Terraform output
+var secretPatterns = []*regexp.Regexp{
+	// access key IDs
+	regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// secret keys and session tokens assigned in environment variables or credential files
+	regexp.MustCompile(`(?i)((aws_secret_access_key|aws_session_token)\s*[=:]\s*)\S+`),
+	// chat webhook URLs carry their token in the path
+	regexp.MustCompile(`https://hooks\.slack\.com/\S+`),
+	regexp.MustCompile(`https://\S+\.webhook\.office\.com/\S+`),
+}
+
+var secrets = struct {
+	sync.RWMutex
+	values []string
+}{}
+
+// AddSecret redacts the given values from every following log, e.g. assumed role credentials.
+func AddSecret(values ...string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	for _, value := range values {
+		if value != "" {
+			secrets.values = append(secrets.values, value)
+		}
+	}
+}
+
+// Redact replaces the known secrets of the string.
+func Redact(s string) string {
+	secrets.RLock()
+	for _, value := range secrets.values {
+		s = strings.ReplaceAll(s, value, redacted)
+	}
+	secrets.RUnlock()
+
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			// keep the name of the assigned variable
+			if groups := pattern.FindStringSubmatch(match); len(groups) > 1 && strings.ContainsAny(groups[1], "=:") {
+				return groups[1] + redacted
+			}
+			return redacted
+		})
+	}
+
+	return s
+}
+
+// redactingCore redacts the message and string fields before writing them
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = Redact(entry.Message)
+
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+// redactFields returns a copy of the fields with the string values redacted
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	result := make([]zapcore.Field, len(fields))
+
+	for i, field := range fields {
+		if field.Type == zapcore.StringType {
+			field.String = Redact(field.String)
+		}
+		result[i] = field
+	}
+
+	return result
+}
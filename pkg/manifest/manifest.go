@@ -0,0 +1,159 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package manifest describes the deployment manifest, the deployment.yaml file holding the settings of an AFT deployment.
+package manifest
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the name of the manifest written by aftctl init.
+const DefaultFileName = "deployment.yaml"
+
+// Manifest holds the settings of an AFT deployment.
+type Manifest struct {
+	// Region is the region where the AFT deployment resources are created.
+	Region string `json:"region" yaml:"region"`
+
+	ControlTower ControlTower `json:"controlTower" yaml:"controlTower"`
+	AFT          AFT          `json:"aft" yaml:"aft"`
+	Terraform    Terraform    `json:"terraform" yaml:"terraform"`
+	Deployment   Deployment   `json:"deployment" yaml:"deployment"`
+}
+
+// ControlTower holds the Control Tower landing zone AFT is deployed on.
+type ControlTower struct {
+	// HomeRegion is the region where Control Tower is set up.
+	HomeRegion string `json:"homeRegion" yaml:"homeRegion"`
+
+	// SecondaryRegion is where the Terraform state is replicated, it must differ from the home region.
+	SecondaryRegion string `json:"secondaryRegion,omitempty" yaml:"secondaryRegion,omitempty"`
+
+	// ManagementAccountID is the Control Tower management account, aka payer/root/master account.
+	ManagementAccountID string `json:"managementAccountId" yaml:"managementAccountId"`
+
+	// LogArchiveAccountID is the Control Tower Log Archive account.
+	LogArchiveAccountID string `json:"logArchiveAccountId" yaml:"logArchiveAccountId"`
+
+	// AuditAccountID is the Control Tower Audit account.
+	AuditAccountID string `json:"auditAccountId" yaml:"auditAccountId"`
+}
+
+// AFT holds the AFT management account and the AFT feature flags.
+type AFT struct {
+	// ManagementAccountID is the account where AFT is deployed.
+	ManagementAccountID string `json:"managementAccountId" yaml:"managementAccountId"`
+
+	Features Features `json:"features" yaml:"features"`
+}
+
+// Features holds the AFT feature flags.
+type Features struct {
+	// MetricsReporting sends anonymous usage metrics to AWS.
+	MetricsReporting bool `json:"metricsReporting" yaml:"metricsReporting"`
+
+	// CloudtrailDataEvents enables the CloudTrail data events of the S3 objects and Lambda functions in every account.
+	CloudtrailDataEvents bool `json:"cloudtrailDataEvents" yaml:"cloudtrailDataEvents"`
+
+	// EnterpriseSupport enrolls the vended accounts in Enterprise Support.
+	EnterpriseSupport bool `json:"enterpriseSupport" yaml:"enterpriseSupport"`
+
+	// DeleteDefaultVPCs deletes the default VPCs of the vended accounts in every region.
+	DeleteDefaultVPCs bool `json:"deleteDefaultVpcs" yaml:"deleteDefaultVpcs"`
+}
+
+// Terraform holds the Terraform used by the deployment and by AFT.
+type Terraform struct {
+	Version string `json:"version" yaml:"version"`
+
+	// Distribution is one of oss, tfc or tfe.
+	Distribution string `json:"distribution" yaml:"distribution"`
+
+	// OrganizationName is the Terraform Cloud or Enterprise organization, required by tfc and tfe.
+	OrganizationName string `json:"organizationName,omitempty" yaml:"organizationName,omitempty"`
+
+	// StateBucketName is the bucket of the deployment Terraform state, prefixed with the AFT management account ID.
+	StateBucketName string `json:"stateBucketName" yaml:"stateBucketName"`
+}
+
+// Deployment holds the names of the resources deploying AFT.
+type Deployment struct {
+	RepositoryName     string `json:"repositoryName" yaml:"repositoryName"`
+	Branch             string `json:"branch" yaml:"branch"`
+	ArtifactBucketName string `json:"artifactBucketName" yaml:"artifactBucketName"`
+	PipelineRoleName   string `json:"pipelineRoleName" yaml:"pipelineRoleName"`
+	CodeBuildRoleName  string `json:"codeBuildRoleName" yaml:"codeBuildRoleName"`
+	ProjectName        string `json:"projectName" yaml:"projectName"`
+	PipelineName       string `json:"pipelineName" yaml:"pipelineName"`
+}
+
+// New returns a manifest with the defaults of aftctl aft deploy.
+func New() Manifest {
+	return Manifest{
+		AFT: AFT{
+			Features: Features{
+				MetricsReporting:     true,
+				CloudtrailDataEvents: true,
+				EnterpriseSupport:    true,
+				DeleteDefaultVPCs:    true,
+			},
+		},
+		Terraform: Terraform{
+			Version:         "1.5.6",
+			Distribution:    "oss",
+			StateBucketName: "aft-deployment-terraform-tfstate",
+		},
+		Deployment: Deployment{
+			RepositoryName:     "aft-deployment",
+			Branch:             "main",
+			ArtifactBucketName: "aft-deployment-codepipeline-artifact",
+			PipelineRoleName:   "aft-deployment-codepipeline-service-role",
+			CodeBuildRoleName:  "aft-deployment-codebuild-service-role",
+			ProjectName:        "aft-deployment-build",
+			PipelineName:       "aft-deployment-pipeline",
+		},
+	}
+}
+
+// StackName returns the name of the CloudFormation stack creating the repository.
+func (m Manifest) StackName() string {
+	return m.Deployment.RepositoryName + "-cloudformation-stack"
+}
+
+// Load reads the manifest file.
+func Load(path string) (Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("error reading the deployment manifest: %w", err)
+	}
+
+	manifest := Manifest{}
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("error parsing the deployment manifest %s: %w", path, err)
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest file.
+func (m Manifest) Save(path string) error {
+	content, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("error encoding the deployment manifest: %w", err)
+	}
+
+	header := "# AFT deployment manifest generated by aftctl init\n" +
+		"# yaml-language-server: $schema=" + SchemaID + "\n"
+	content = append([]byte(header), content...)
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("error writing the deployment manifest: %w", err)
+	}
+
+	return nil
+}
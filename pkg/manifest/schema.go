@@ -0,0 +1,119 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package manifest
+
+import (
+	"encoding/json"
+	"reflect"
+
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+)
+
+// SchemaID is where the JSON Schema of the manifest is published with the documentation.
+const SchemaID = "https://edgarsilva948.github.io/aftctl/static/deployment.schema.json"
+
+// descriptions of the manifest fields shown by the editors
+var descriptions = map[string]string{
+	"region":                            "Region where the AFT deployment resources are created",
+	"controlTower":                      "Control Tower landing zone AFT is deployed on",
+	"controlTower.homeRegion":           "Region where Control Tower is set up",
+	"controlTower.secondaryRegion":      "Region where the Terraform state is replicated, it must differ from the home region",
+	"controlTower.managementAccountId":  "Control Tower management account, aka payer/root/master account",
+	"controlTower.logArchiveAccountId":  "Control Tower Log Archive account",
+	"controlTower.auditAccountId":       "Control Tower Audit account",
+	"aft":                               "AFT management account and feature flags",
+	"aft.managementAccountId":           "Account where AFT is deployed",
+	"aft.features":                      "AFT feature flags",
+	"aft.features.metricsReporting":     "Send anonymous usage metrics to AWS",
+	"aft.features.cloudtrailDataEvents": "Enable the CloudTrail data events of the S3 objects and Lambda functions in every account",
+	"aft.features.enterpriseSupport":    "Enroll the vended accounts in Enterprise Support",
+	"aft.features.deleteDefaultVpcs":    "Delete the default VPCs of the vended accounts in every region",
+	"terraform":                         "Terraform used by the deployment and by AFT",
+	"terraform.version":                 "Terraform release, e.g. 1.5.6",
+	"terraform.distribution":            "Terraform distribution",
+	"terraform.organizationName":        "Terraform Cloud or Enterprise organization, required by tfc and tfe",
+	"terraform.stateBucketName":         "Bucket of the deployment Terraform state, prefixed with the AFT management account ID",
+	"deployment":                        "Names of the resources deploying AFT",
+	"deployment.repositoryName":         "CodeCommit repository holding the AFT deployment files",
+	"deployment.branch":                 "Branch of the repository built by the pipeline",
+	"deployment.artifactBucketName":     "CodePipeline artifact bucket, prefixed with the AFT management account ID",
+	"deployment.pipelineRoleName":       "CodePipeline role name",
+	"deployment.codeBuildRoleName":      "CodeBuild role name",
+	"deployment.projectName":            "CodeBuild project name",
+	"deployment.pipelineName":           "CodePipeline pipeline name",
+}
+
+// patterns of the manifest fields, the other checks are only run by aftctl validate
+var patterns = map[string]string{
+	"region":                           validate.RegionPattern,
+	"controlTower.homeRegion":          validate.RegionPattern,
+	"controlTower.secondaryRegion":     validate.RegionPattern,
+	"controlTower.managementAccountId": validate.AccountIDPattern,
+	"controlTower.logArchiveAccountId": validate.AccountIDPattern,
+	"controlTower.auditAccountId":      validate.AccountIDPattern,
+	"aft.managementAccountId":          validate.AccountIDPattern,
+	"terraform.version":                validate.TerraformVersionPattern,
+}
+
+// Schema returns the JSON Schema of the manifest, generated from the Go types.
+func Schema() ([]byte, error) {
+	schema := schemaOf(reflect.TypeOf(Manifest{}), "")
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["$id"] = SchemaID
+	schema["title"] = "AFT deployment manifest"
+
+	terraform := schema["properties"].(map[string]interface{})["terraform"].(map[string]interface{})
+	terraform["if"] = map[string]interface{}{
+		"properties": map[string]interface{}{
+			"distribution": map[string]interface{}{"enum": []string{"tfc", "tfe"}},
+		},
+	}
+	terraform["then"] = map[string]interface{}{
+		"required": []string{"organizationName"},
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaOf returns the schema of the type at the given path of the manifest
+func schemaOf(t reflect.Type, path string) map[string]interface{} {
+	schema := map[string]interface{}{}
+
+	if description, ok := descriptions[path]; ok {
+		schema["description"] = description
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+
+		for _, field := range fields(t) {
+			properties[field.name] = schemaOf(t.Field(field.index).Type, joinPath(path, field.name))
+			if field.required {
+				required = append(required, field.name)
+			}
+		}
+
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["required"] = required
+		schema["additionalProperties"] = false
+
+	case reflect.String:
+		schema["type"] = "string"
+		if pattern, ok := patterns[path]; ok {
+			schema["pattern"] = pattern
+		}
+		if path == "terraform.distribution" {
+			schema["enum"] = validate.TerraformDistributions
+		}
+
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	}
+
+	return schema
+}
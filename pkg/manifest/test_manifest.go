@@ -0,0 +1,51 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Reading and writing the deployment manifest", func() {
+
+	ginkgo.Context("testing the Save and Load functions", func() {
+		ginkgo.When("the manifest is saved", func() {
+			ginkgo.It("should load the same values", func() {
+				path := filepath.Join(ginkgo.GinkgoT().TempDir(), DefaultFileName)
+
+				manifest := New()
+				manifest.Region = "us-east-1"
+				manifest.AFT.ManagementAccountID = "111111111111"
+				manifest.AFT.Features.EnterpriseSupport = false
+
+				gomega.Expect(manifest.Save(path)).To(gomega.Succeed())
+
+				loaded, err := Load(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(loaded).To(gomega.Equal(manifest))
+			})
+		})
+
+		ginkgo.When("the manifest is not YAML", func() {
+			ginkgo.It("should return an error", func() {
+				path := filepath.Join(ginkgo.GinkgoT().TempDir(), DefaultFileName)
+				gomega.Expect(os.WriteFile(path, []byte("region: ["), 0644)).To(gomega.Succeed())
+
+				_, err := Load(path)
+				gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("error parsing the deployment manifest")))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the StackName function", func() {
+		ginkgo.It("should derive the stack from the repository", func() {
+			gomega.Expect(New().StackName()).To(gomega.Equal("aft-deployment-cloudformation-stack"))
+		})
+	})
+})
@@ -0,0 +1,149 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package manifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// validContent is a manifest without problems
+const validContent = `region: us-east-1
+controlTower:
+  homeRegion: us-east-1
+  secondaryRegion: us-west-2
+  managementAccountId: "111111111111"
+  logArchiveAccountId: "222222222222"
+  auditAccountId: "333333333333"
+aft:
+  managementAccountId: "444444444444"
+  features:
+    metricsReporting: true
+    cloudtrailDataEvents: true
+    enterpriseSupport: false
+    deleteDefaultVpcs: true
+terraform:
+  version: 1.5.6
+  distribution: oss
+  stateBucketName: aft-deployment-terraform-tfstate
+deployment:
+  repositoryName: aft-deployment
+  branch: main
+  artifactBucketName: aft-deployment-codepipeline-artifact
+  pipelineRoleName: aft-deployment-codepipeline-service-role
+  codeBuildRoleName: aft-deployment-codebuild-service-role
+  projectName: aft-deployment-build
+  pipelineName: aft-deployment-pipeline
+`
+
+var _ = ginkgo.Describe("Validating the deployment manifest", func() {
+
+	ginkgo.Context("testing the Validate function", func() {
+		ginkgo.When("the manifest is valid", func() {
+			ginkgo.It("should return no problem", func() {
+				gomega.Expect(Validate([]byte(validContent))).To(gomega.BeEmpty())
+			})
+		})
+
+		ginkgo.When("the manifest written by init is validated", func() {
+			ginkgo.It("should return no problem", func() {
+				path := filepath.Join(ginkgo.GinkgoT().TempDir(), DefaultFileName)
+
+				manifest := New()
+				manifest.Region = "us-east-1"
+				manifest.ControlTower.HomeRegion = "us-east-1"
+				manifest.ControlTower.ManagementAccountID = "111111111111"
+				manifest.ControlTower.LogArchiveAccountID = "222222222222"
+				manifest.ControlTower.AuditAccountID = "333333333333"
+				manifest.AFT.ManagementAccountID = "444444444444"
+				gomega.Expect(manifest.Save(path)).To(gomega.Succeed())
+
+				content, err := os.ReadFile(path)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(Validate(content)).To(gomega.BeEmpty())
+			})
+		})
+
+		ginkgo.When("the manifest has several problems", func() {
+			ginkgo.It("should report all of them with their line", func() {
+				content := strings.NewReplacer(
+					"secondaryRegion: us-west-2", "secondaryRegion: us-east-1",
+					`auditAccountId: "333333333333"`, `auditAccountId: "3333"`,
+					"enterpriseSupport: false", "enterpriseSupport: maybe",
+					"distribution: oss", "distribution: tfc",
+					"pipelineName: aft-deployment-pipeline", "pipelineName: aft deployment pipeline",
+					"branch: main\n", "branch: main\n  owner: platform\n",
+				).Replace(validContent)
+
+				gomega.Expect(Validate([]byte(content))).To(gomega.Equal([]Problem{
+					{Line: 4, Field: "controlTower.secondaryRegion", Message: "the secondary region must differ from the home region"},
+					{Line: 7, Field: "controlTower.auditAccountId", Message: "account id must be 12 characters long"},
+					{Line: 13, Field: "aft.features.enterpriseSupport", Message: "must be a boolean (true or false)"},
+					{Line: 15, Field: "terraform.organizationName", Message: "the tfc distribution requires an organization name"},
+					{Line: 22, Field: "deployment.owner", Message: "unknown field"},
+					{Line: 27, Field: "deployment.pipelineName", Message: "pipeline name can only consist of lowercase letters, numbers, and hyphens, and must begin and end with a letter or number"},
+				}))
+			})
+		})
+
+		ginkgo.When("a section is missing", func() {
+			ginkgo.It("should report it as required", func() {
+				content := validContent[:strings.Index(validContent, "deployment:")]
+
+				gomega.Expect(Validate([]byte(content))).To(gomega.Equal([]Problem{
+					{Line: 1, Field: "deployment", Message: "field is required"},
+				}))
+			})
+		})
+
+		ginkgo.When("the bucket name is too long once prefixed with the account", func() {
+			ginkgo.It("should report the bucket", func() {
+				content := strings.Replace(validContent, "aft-deployment-terraform-tfstate", strings.Repeat("a", 55), 1)
+
+				gomega.Expect(Validate([]byte(content))).To(gomega.Equal([]Problem{
+					{Line: 18, Field: "terraform.stateBucketName", Message: "bucket name must be between 3 and 63 characters long"},
+				}))
+			})
+		})
+
+		ginkgo.When("the manifest is not YAML", func() {
+			ginkgo.It("should report the syntax error line", func() {
+				problems := Validate([]byte("region: us-east-1\ncontrolTower: [\n"))
+				gomega.Expect(problems).To(gomega.HaveLen(1))
+				gomega.Expect(problems[0].Line).To(gomega.Equal(2))
+			})
+		})
+
+		ginkgo.When("the manifest is empty", func() {
+			ginkgo.It("should report it", func() {
+				gomega.Expect(Validate(nil)).To(gomega.Equal([]Problem{{Line: 1, Message: "the manifest is empty"}}))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the Schema function", func() {
+		ginkgo.It("should require the fields without omitempty", func() {
+			content, err := Schema()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			schema := struct {
+				Required   []string `json:"required"`
+				Properties map[string]struct {
+					Required []string `json:"required"`
+				} `json:"properties"`
+			}{}
+			gomega.Expect(json.Unmarshal(content, &schema)).To(gomega.Succeed())
+
+			gomega.Expect(schema.Required).To(gomega.Equal([]string{"region", "controlTower", "aft", "terraform", "deployment"}))
+			gomega.Expect(schema.Properties["controlTower"].Required).ToNot(gomega.ContainElement("secondaryRegion"))
+			gomega.Expect(schema.Properties["terraform"].Required).ToNot(gomega.ContainElement("organizationName"))
+		})
+	})
+})
@@ -0,0 +1,278 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	validate "github.com/edgarsilva948/aftctl/pkg/validator"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an invalid value of the manifest.
+type Problem struct {
+	Line    int    `json:"line" yaml:"line"`
+	Field   string `json:"field,omitempty" yaml:"field,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Field == "" {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.Field, p.Message)
+}
+
+// yamlLinePattern finds the line in the YAML syntax errors
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// bucketPrefix stands for the account ID prefixing the bucket names when the account ID is invalid
+const bucketPrefix = "000000000000-"
+
+// Validate checks the types, the required fields, the values and the naming rules of the manifest content.
+// Every problem is returned at once, ordered by line.
+func Validate(content []byte) []Problem {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		line := 1
+		if match := yamlLinePattern.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+		return []Problem{{Line: line, Message: strings.TrimPrefix(err.Error(), "yaml: ")}}
+	}
+
+	if len(root.Content) == 0 {
+		return []Problem{{Line: 1, Message: "the manifest is empty"}}
+	}
+
+	v := &validator{lines: map[string]int{}}
+	document := root.Content[0]
+	v.checkNode(document, reflect.TypeOf(Manifest{}), "")
+
+	manifest := Manifest{}
+	if err := document.Decode(&manifest); err != nil && len(v.problems) == 0 {
+		v.add("", fmt.Sprintf("error decoding the manifest: %v", err))
+	}
+
+	v.checkValues(manifest)
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].Line < v.problems[j].Line
+	})
+
+	return v.problems
+}
+
+// validator collects the problems and the line of each field found in the manifest
+type validator struct {
+	lines    map[string]int
+	problems []Problem
+}
+
+// add records a problem on the line of the field, or of its closest parent when the field is missing
+func (v *validator) add(field, message string) {
+	path := field
+	line, found := v.lines[path]
+	for !found && path != "" {
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			path = path[:i]
+		} else {
+			path = ""
+		}
+		line, found = v.lines[path]
+	}
+
+	if !found {
+		line = 1
+	}
+
+	v.problems = append(v.problems, Problem{Line: line, Field: field, Message: message})
+}
+
+// checkNode checks the node matches the Go type, the unknown and missing fields are problems
+func (v *validator) checkNode(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	v.lines[path] = node.Line
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.add(path, "must be a mapping")
+			return
+		}
+
+		found := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldPath := joinPath(path, key.Value)
+
+			field, ok := fieldByName(t, key.Value)
+			if !ok {
+				v.lines[fieldPath] = key.Line
+				v.add(fieldPath, "unknown field")
+				continue
+			}
+
+			found[key.Value] = true
+			v.checkNode(value, field.Type, fieldPath)
+			v.lines[fieldPath] = key.Line
+		}
+
+		for _, field := range fields(t) {
+			if field.required && !found[field.name] {
+				v.add(joinPath(path, field.name), "field is required")
+			}
+		}
+
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!str" && node.Tag != "!!int" && node.Tag != "!!float") {
+			v.add(path, "must be a string")
+		}
+
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			v.add(path, "must be a boolean (true or false)")
+		}
+	}
+}
+
+// check records the error of the check when the field is in the manifest, the missing and mistyped fields are already reported
+func (v *validator) check(field, value string, check func(string) (bool, error)) {
+	if _, found := v.lines[field]; !found {
+		return
+	}
+
+	for _, problem := range v.problems {
+		if problem.Field == field {
+			return
+		}
+	}
+
+	if _, err := check(value); err != nil {
+		v.add(field, err.Error())
+	}
+}
+
+// checkValues checks the formats, the naming rules and the rules across fields
+func (v *validator) checkValues(m Manifest) {
+	v.check("region", m.Region, validate.CheckAWSRegion)
+	v.check("controlTower.homeRegion", m.ControlTower.HomeRegion, validate.CheckAWSRegion)
+	v.check("controlTower.secondaryRegion", m.ControlTower.SecondaryRegion, optional(validate.CheckAWSRegion))
+
+	if m.ControlTower.SecondaryRegion != "" && m.ControlTower.SecondaryRegion == m.ControlTower.HomeRegion {
+		v.add("controlTower.secondaryRegion", "the secondary region must differ from the home region")
+	}
+
+	v.check("controlTower.managementAccountId", m.ControlTower.ManagementAccountID, validate.CheckAWSAccountID)
+	v.check("controlTower.logArchiveAccountId", m.ControlTower.LogArchiveAccountID, validate.CheckAWSAccountID)
+	v.check("controlTower.auditAccountId", m.ControlTower.AuditAccountID, validate.CheckAWSAccountID)
+	v.check("aft.managementAccountId", m.AFT.ManagementAccountID, validate.CheckAWSAccountID)
+
+	if m.AFT.ManagementAccountID != "" && m.AFT.ManagementAccountID == m.ControlTower.ManagementAccountID {
+		v.add("aft.managementAccountId", "AFT must be deployed in another account than the CT management account")
+	}
+
+	v.check("terraform.version", m.Terraform.Version, validate.CheckTerraformVersion)
+	v.check("terraform.distribution", m.Terraform.Distribution, validate.CheckTerraformDistribution)
+
+	if (m.Terraform.Distribution == "tfc" || m.Terraform.Distribution == "tfe") && m.Terraform.OrganizationName == "" {
+		v.add("terraform.organizationName", fmt.Sprintf("the %s distribution requires an organization name", m.Terraform.Distribution))
+	}
+
+	// the buckets are prefixed with the AFT management account ID when they are created
+	prefix := bucketPrefix
+	if _, err := validate.CheckAWSAccountID(m.AFT.ManagementAccountID); err == nil {
+		prefix = m.AFT.ManagementAccountID + "-"
+	}
+
+	prefixed := func(check func(string) (bool, error)) func(string) (bool, error) {
+		return func(value string) (bool, error) {
+			return check(prefix + value)
+		}
+	}
+
+	v.check("terraform.stateBucketName", m.Terraform.StateBucketName, prefixed(aws.CheckBucketName))
+	v.check("deployment.artifactBucketName", m.Deployment.ArtifactBucketName, prefixed(aws.CheckBucketName))
+
+	v.check("deployment.repositoryName", m.Deployment.RepositoryName, aws.CheckRepositoryName)
+	v.check("deployment.repositoryName", m.StackName(), aws.CheckStackName)
+	v.check("deployment.branch", m.Deployment.Branch, required)
+	v.check("deployment.pipelineRoleName", m.Deployment.PipelineRoleName, aws.CheckRoleName)
+	v.check("deployment.codeBuildRoleName", m.Deployment.CodeBuildRoleName, aws.CheckRoleName)
+	v.check("deployment.projectName", m.Deployment.ProjectName, aws.CheckProjectName)
+	v.check("deployment.pipelineName", m.Deployment.PipelineName, aws.CheckPipelineName)
+}
+
+// optional skips the check of empty values
+func optional(check func(string) (bool, error)) func(string) (bool, error) {
+	return func(value string) (bool, error) {
+		if value == "" {
+			return true, nil
+		}
+		return check(value)
+	}
+}
+
+func required(value string) (bool, error) {
+	if value == "" {
+		return false, errors.New("value is required")
+	}
+	return true, nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// field is a struct field as named in the manifest
+type field struct {
+	name     string
+	required bool
+	index    int
+}
+
+// fields returns the manifest fields of the struct, the fields without omitempty are required
+func fields(t reflect.Type) []field {
+	result := make([]field, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")
+		if tag[0] == "" || tag[0] == "-" {
+			continue
+		}
+
+		result = append(result, field{
+			name:     tag[0],
+			required: len(tag) == 1 || tag[1] != "omitempty",
+			index:    i,
+		})
+	}
+
+	return result
+}
+
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, f := range fields(t) {
+		if f.name == name {
+			return t.Field(f.index), true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,290 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package notify posts aftctl events to chat webhooks.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Format is the payload format expected by the webhook.
+type Format string
+
+const (
+	// SlackFormat posts Slack incoming webhook messages.
+	SlackFormat Format = "slack"
+
+	// TeamsFormat posts Microsoft Teams connector cards.
+	TeamsFormat Format = "teams"
+)
+
+// EventType identifies what happened.
+type EventType string
+
+const (
+	// DeployStarted is sent when aftctl starts deploying the AFT prerequisites.
+	DeployStarted EventType = "deploy-started"
+
+	// DeploySucceeded is sent when the AFT prerequisites are deployed.
+	DeploySucceeded EventType = "deploy-succeeded"
+
+	// DeployFailed is sent when deploying the AFT prerequisites fails.
+	DeployFailed EventType = "deploy-failed"
+
+	// DestroyStarted is sent when aftctl starts removing the AFT prerequisites.
+	DestroyStarted EventType = "destroy-started"
+
+	// DestroySucceeded is sent when the AFT prerequisites are removed.
+	DestroySucceeded EventType = "destroy-succeeded"
+
+	// DestroyFailed is sent when removing the AFT prerequisites fails.
+	DestroyFailed EventType = "destroy-failed"
+
+	// LocalRunStarted is sent when aftctl local starts running Terraform.
+	LocalRunStarted EventType = "local-run-started"
+
+	// LocalRunSucceeded is sent when the local Terraform run completes.
+	LocalRunSucceeded EventType = "local-run-succeeded"
+
+	// LocalRunFailed is sent when the local Terraform run fails.
+	LocalRunFailed EventType = "local-run-failed"
+)
+
+// Event is a single notification.
+type Event struct {
+	Type    EventType
+	Message string
+
+	// Fields are extra details shown with the message, e.g. the target account.
+	Fields map[string]string
+}
+
+// Notifier posts events to a webhook URL. A nil Notifier discards every event.
+type Notifier struct {
+	URL    string
+	Format Format
+	Client *http.Client
+}
+
+var formats = []string{string(SlackFormat), string(TeamsFormat)}
+
+var flagArgs struct {
+	url    string
+	format string
+}
+
+// AddFlags adds the webhook flags to the given set of command line flags.
+func AddFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&flagArgs.url,
+		"webhook-url",
+		"",
+		"Webhook URL where deploy, destroy and local run events are posted",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&flagArgs.format,
+		"webhook-format",
+		string(SlackFormat),
+		fmt.Sprintf("Payload format of the webhook. Allowed options are %s", formats),
+	)
+
+	cmd.RegisterFlagCompletionFunc("webhook-format", completion)
+}
+
+func completion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return formats, cobra.ShellCompDirectiveDefault
+}
+
+// FromFlags returns the notifier configured by the command line flags, or nil when no webhook URL is set.
+func FromFlags() (*Notifier, error) {
+	if flagArgs.url == "" {
+		return nil, nil
+	}
+
+	return New(flagArgs.url, Format(flagArgs.format))
+}
+
+// New returns a notifier posting to the given URL in the given format.
+func New(url string, format Format) (*Notifier, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook url is not provided")
+	}
+
+	if format != SlackFormat && format != TeamsFormat {
+		return nil, fmt.Errorf("invalid webhook format %q, allowed options are %s", format, formats)
+	}
+
+	return &Notifier{
+		URL:    url,
+		Format: format,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Notify posts the event to the webhook.
+func (n *Notifier) Notify(event Event) error {
+	if n == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(n.payload(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode %s notification: %w", event.Type, err)
+	}
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post %s notification: %w", event.Type, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to post %s notification: webhook returned %s", event.Type, resp.Status)
+	}
+
+	return nil
+}
+
+// payload builds the webhook body for the notifier format
+func (n *Notifier) payload(event Event) interface{} {
+	if n.Format == TeamsFormat {
+		return teamsPayload(event)
+	}
+
+	return slackPayload(event)
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// slackPayload renders the event as a Slack incoming webhook message
+func slackPayload(event Event) slackMessage {
+	message := slackMessage{
+		Text: fmt.Sprintf("*aftctl %s*: %s", event.Type, event.Message),
+	}
+
+	if len(event.Fields) == 0 {
+		return message
+	}
+
+	attachment := slackAttachment{Color: slackColor(event.Type)}
+	for _, name := range sortedKeys(event.Fields) {
+		attachment.Fields = append(attachment.Fields, slackField{
+			Title: name,
+			Value: event.Fields[name],
+			Short: true,
+		})
+	}
+
+	message.Attachments = []slackAttachment{attachment}
+
+	return message
+}
+
+type teamsCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	ThemeColor string         `json:"themeColor"`
+	Summary    string         `json:"summary"`
+	Title      string         `json:"title"`
+	Text       string         `json:"text"`
+	Sections   []teamsSection `json:"sections,omitempty"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// teamsPayload renders the event as a Microsoft Teams connector card
+func teamsPayload(event Event) teamsCard {
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: teamsColor(event.Type),
+		Summary:    fmt.Sprintf("aftctl %s", event.Type),
+		Title:      fmt.Sprintf("aftctl %s", event.Type),
+		Text:       event.Message,
+	}
+
+	if len(event.Fields) == 0 {
+		return card
+	}
+
+	section := teamsSection{}
+	for _, name := range sortedKeys(event.Fields) {
+		section.Facts = append(section.Facts, teamsFact{Name: name, Value: event.Fields[name]})
+	}
+
+	card.Sections = []teamsSection{section}
+
+	return card
+}
+
+// slackColor maps the event outcome to a Slack attachment color
+func slackColor(eventType EventType) string {
+	switch eventType {
+	case DeploySucceeded, DestroySucceeded, LocalRunSucceeded:
+		return "good"
+	case DeployFailed, DestroyFailed, LocalRunFailed:
+		return "danger"
+	default:
+		return "#439FE0"
+	}
+}
+
+// teamsColor maps the event outcome to a Teams card theme color
+func teamsColor(eventType EventType) string {
+	switch eventType {
+	case DeploySucceeded, DestroySucceeded, LocalRunSucceeded:
+		return "2EB886"
+	case DeployFailed, DestroyFailed, LocalRunFailed:
+		return "A30200"
+	default:
+		return "439FE0"
+	}
+}
+
+// sortedKeys returns the keys of the map in lexical order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
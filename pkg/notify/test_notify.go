@@ -0,0 +1,120 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Posting events to a webhook", func() {
+
+	var (
+		server      *httptest.Server
+		received    map[string]interface{}
+		contentType string
+		status      int
+	)
+
+	ginkgo.BeforeEach(func() {
+		received = nil
+		status = http.StatusOK
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &received)
+			w.WriteHeader(status)
+		}))
+	})
+
+	ginkgo.AfterEach(func() {
+		server.Close()
+	})
+
+	event := Event{
+		Type:    DeployFailed,
+		Message: "CodeBuild project aft-deployment-build couldn't be created",
+		Fields: map[string]string{
+			"region":  "us-east-1",
+			"account": "123456789012",
+		},
+	}
+
+	ginkgo.Context("testing the New function", func() {
+		ginkgo.When("the url is not provided", func() {
+			ginkgo.It("should return an error", func() {
+				_, err := New("", SlackFormat)
+				gomega.Expect(err).To(gomega.MatchError("webhook url is not provided"))
+			})
+		})
+
+		ginkgo.When("the format is unknown", func() {
+			ginkgo.It("should return an error", func() {
+				_, err := New("http://localhost", Format("discord"))
+				gomega.Expect(err).To(gomega.MatchError(`invalid webhook format "discord", allowed options are [slack teams]`))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the Notify function", func() {
+		ginkgo.When("the notifier is nil", func() {
+			ginkgo.It("should discard the event", func() {
+				var notifier *Notifier
+				gomega.Expect(notifier.Notify(event)).To(gomega.Succeed())
+			})
+		})
+
+		ginkgo.When("the format is slack", func() {
+			ginkgo.It("should post a slack message", func() {
+				notifier, err := New(server.URL, SlackFormat)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				gomega.Expect(notifier.Notify(event)).To(gomega.Succeed())
+				gomega.Expect(contentType).To(gomega.Equal("application/json"))
+				gomega.Expect(received["text"]).To(gomega.Equal("*aftctl deploy-failed*: CodeBuild project aft-deployment-build couldn't be created"))
+
+				attachment := received["attachments"].([]interface{})[0].(map[string]interface{})
+				gomega.Expect(attachment["color"]).To(gomega.Equal("danger"))
+
+				firstField := attachment["fields"].([]interface{})[0].(map[string]interface{})
+				gomega.Expect(firstField["title"]).To(gomega.Equal("account"))
+				gomega.Expect(firstField["value"]).To(gomega.Equal("123456789012"))
+			})
+		})
+
+		ginkgo.When("the format is teams", func() {
+			ginkgo.It("should post a teams connector card", func() {
+				notifier, err := New(server.URL, TeamsFormat)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				gomega.Expect(notifier.Notify(event)).To(gomega.Succeed())
+				gomega.Expect(received["@type"]).To(gomega.Equal("MessageCard"))
+				gomega.Expect(received["title"]).To(gomega.Equal("aftctl deploy-failed"))
+				gomega.Expect(received["themeColor"]).To(gomega.Equal("A30200"))
+
+				facts := received["sections"].([]interface{})[0].(map[string]interface{})["facts"].([]interface{})
+				gomega.Expect(facts).To(gomega.HaveLen(2))
+			})
+		})
+
+		ginkgo.When("the webhook rejects the request", func() {
+			ginkgo.It("should return an error", func() {
+				status = http.StatusForbidden
+
+				notifier, err := New(server.URL, SlackFormat)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+				err = notifier.Notify(event)
+				gomega.Expect(err).To(gomega.MatchError("failed to post deploy-failed notification: webhook returned 403 Forbidden"))
+			})
+		})
+	})
+})
@@ -0,0 +1,118 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package output renders the results of the commands as a table, JSON, YAML or a Go template.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// output formats
+const (
+	TableFormat      = "table"
+	JSONFormat       = "json"
+	YAMLFormat       = "yaml"
+	GoTemplateFormat = "go-template"
+)
+
+// Formats lists the accepted output formats, go-template takes the template after an equal sign.
+var Formats = []string{TableFormat, JSONFormat, YAMLFormat, GoTemplateFormat + "=TEMPLATE"}
+
+// Result is the outcome of a command, rendered as JSON and YAML through its tags.
+type Result interface {
+	// PrintTable writes the result for humans.
+	PrintTable(w io.Writer) error
+}
+
+var format string
+
+// AddFlag adds the output flag to the given set of command line flags.
+func AddFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(
+		&format,
+		"output",
+		"o",
+		TableFormat,
+		fmt.Sprintf("Format of the command result. Allowed options are %s", Formats),
+	)
+
+	cmd.RegisterFlagCompletionFunc("output", completion)
+}
+
+func completion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{TableFormat, JSONFormat, YAMLFormat, GoTemplateFormat + "="}, cobra.ShellCompDirectiveNoSpace
+}
+
+// Format returns the output format given on the command line.
+func Format() string {
+	return format
+}
+
+// IsTable reports whether the result is written for humans, other formats keep the standard output machine-readable.
+func IsTable() bool {
+	return format == "" || format == TableFormat
+}
+
+// Print writes the result in the format given on the command line.
+func Print(w io.Writer, result Result) error {
+	return Write(w, result, format)
+}
+
+// Write writes the result in the given format.
+func Write(w io.Writer, result Result, format string) error {
+	switch {
+	case format == "" || format == TableFormat:
+		return result.PrintTable(w)
+	case format == JSONFormat:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case format == YAMLFormat:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case strings.HasPrefix(format, GoTemplateFormat+"="):
+		tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, GoTemplateFormat+"="))
+		if err != nil {
+			return fmt.Errorf("error parsing the output template: %w", err)
+		}
+		return tmpl.Execute(w, result)
+	}
+
+	return fmt.Errorf("invalid output format %q, allowed options are %s", format, Formats)
+}
+
+// CheckFormat returns an error when the output format given on the command line is unknown.
+func CheckFormat() error {
+	switch {
+	case IsTable(), format == JSONFormat, format == YAMLFormat, strings.HasPrefix(format, GoTemplateFormat+"="):
+		return nil
+	}
+
+	return fmt.Errorf("invalid output format %q, allowed options are %s", format, Formats)
+}
+
+// Table writes the rows aligned under the headers.
+func Table(w io.Writer, headers []string, rows [][]string) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
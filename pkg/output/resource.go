@@ -0,0 +1,65 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Action is what a command did with a resource.
+type Action string
+
+// actions taken on the resources
+const (
+	// Ensured resources were created, or left as they were when they already existed.
+	Ensured   Action = "ensured"
+	Generated Action = "generated"
+	Uploaded  Action = "uploaded"
+	Exported  Action = "exported"
+	Failed    Action = "failed"
+)
+
+// Duration is a time.Duration rendered as text, e.g. 1.5s.
+type Duration time.Duration
+
+// Since returns the time elapsed since start, rounded to the millisecond.
+func Since(start time.Time) Duration {
+	return Duration(time.Since(start).Round(time.Millisecond))
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON renders the duration as text.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// MarshalYAML renders the duration as text.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// Resource is a resource handled by a command.
+type Resource struct {
+	Type     string   `json:"type" yaml:"type"`
+	Name     string   `json:"name" yaml:"name"`
+	Action   Action   `json:"action" yaml:"action"`
+	Duration Duration `json:"duration" yaml:"duration"`
+	Error    string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ResourcesTable writes one row per resource.
+func ResourcesTable(w io.Writer, resources []Resource) error {
+	rows := make([][]string, 0, len(resources))
+	for _, resource := range resources {
+		rows = append(rows, []string{resource.Type, resource.Name, string(resource.Action), resource.Duration.String(), resource.Error})
+	}
+
+	return Table(w, []string{"TYPE", "NAME", "ACTION", "DURATION", "ERROR"}, rows)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package output
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+// testResult is a result with one resource
+type testResult struct {
+	Account   string     `json:"account" yaml:"account"`
+	Resources []Resource `json:"resources" yaml:"resources"`
+}
+
+func (r *testResult) PrintTable(w io.Writer) error {
+	return ResourcesTable(w, r.Resources)
+}
+
+var _ = ginkgo.Describe("Printing the command results", func() {
+
+	result := &testResult{
+		Account: "123456789012",
+		Resources: []Resource{
+			{Type: "s3-bucket", Name: "tfstate", Action: Ensured, Duration: Duration(1500 * time.Millisecond)},
+		},
+	}
+
+	ginkgo.AfterEach(func() {
+		format = TableFormat
+	})
+
+	ginkgo.Context("testing the Write function", func() {
+		ginkgo.When("the format is table", func() {
+			ginkgo.It("should write the rows under the headers", func() {
+				var buf bytes.Buffer
+				gomega.Expect(Write(&buf, result, TableFormat)).To(gomega.Succeed())
+				gomega.Expect(buf.String()).To(gomega.Equal(
+					"TYPE        NAME      ACTION    DURATION   ERROR\n" +
+						"s3-bucket   tfstate   ensured   1.5s       \n"))
+			})
+		})
+
+		ginkgo.When("the format is json", func() {
+			ginkgo.It("should render the duration as text", func() {
+				var buf bytes.Buffer
+				gomega.Expect(Write(&buf, result, JSONFormat)).To(gomega.Succeed())
+				gomega.Expect(buf.String()).To(gomega.MatchJSON(`{
+					"account": "123456789012",
+					"resources": [{"type": "s3-bucket", "name": "tfstate", "action": "ensured", "duration": "1.5s"}]
+				}`))
+			})
+		})
+
+		ginkgo.When("the format is yaml", func() {
+			ginkgo.It("should render the duration as text", func() {
+				var buf bytes.Buffer
+				gomega.Expect(Write(&buf, result, YAMLFormat)).To(gomega.Succeed())
+				gomega.Expect(buf.String()).To(gomega.MatchYAML(`
+account: "123456789012"
+resources:
+  - type: s3-bucket
+    name: tfstate
+    action: ensured
+    duration: 1.5s
+`))
+			})
+		})
+
+		ginkgo.When("the format is a go template", func() {
+			ginkgo.It("should execute the template with the result", func() {
+				var buf bytes.Buffer
+				gomega.Expect(Write(&buf, result, "go-template={{range .Resources}}{{.Name}} {{.Duration}}{{end}}")).To(gomega.Succeed())
+				gomega.Expect(buf.String()).To(gomega.Equal("tfstate 1.5s"))
+			})
+		})
+
+		ginkgo.When("the format is unknown", func() {
+			ginkgo.It("should return an error", func() {
+				err := Write(io.Discard, result, "xml")
+				gomega.Expect(err).To(gomega.MatchError(`invalid output format "xml", allowed options are [table json yaml go-template=TEMPLATE]`))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the CheckFormat function", func() {
+		ginkgo.When("the format given on the command line is unknown", func() {
+			ginkgo.It("should return an error", func() {
+				format = "go-template"
+				gomega.Expect(CheckFormat()).To(gomega.HaveOccurred())
+			})
+		})
+
+		ginkgo.When("the format given on the command line is json", func() {
+			ginkgo.It("should not be a table", func() {
+				format = JSONFormat
+				gomega.Expect(CheckFormat()).To(gomega.Succeed())
+				gomega.Expect(IsTable()).To(gomega.BeFalse())
+			})
+		})
+	})
+})
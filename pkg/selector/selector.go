@@ -0,0 +1,145 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+// Package selector selects the target accounts by OU, tags, name, email or AFT account customization.
+package selector
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	"github.com/edgarsilva948/aftctl/pkg/cache"
+	"github.com/edgarsilva948/aftctl/pkg/logging"
+)
+
+// Keys of the selector terms, the tags are selected with tag:KEY.
+const (
+	OUKey            = "ou"
+	NameKey          = "name"
+	EmailKey         = "email"
+	CustomizationKey = "customization"
+	TagPrefix        = "tag:"
+)
+
+// Term is a KEY=VALUE condition on the accounts.
+type Term struct {
+	Key   string
+	Value string
+}
+
+// Selector selects the accounts matching all its terms, e.g. ou=Root/Sandbox,tag:env=dev.
+type Selector []Term
+
+// Parse reads the comma separated terms of a selector.
+func Parse(s string) (Selector, error) {
+	var selector Selector
+
+	for _, part := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(part, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if !found || value == "" {
+			return nil, fmt.Errorf("invalid selector term %q, expected KEY=VALUE", part)
+		}
+
+		switch {
+		case key == OUKey, key == CustomizationKey:
+		case key == NameKey, key == EmailKey, strings.HasPrefix(key, TagPrefix) && len(key) > len(TagPrefix):
+			if _, err := path.Match(value, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q in the selector term %q", value, part)
+			}
+		default:
+			return nil, fmt.Errorf("invalid selector key %q, accepted keys are %s, %s, %s, %s and %sKEY",
+				key, OUKey, NameKey, EmailKey, CustomizationKey, TagPrefix)
+		}
+
+		selector = append(selector, Term{Key: key, Value: value})
+	}
+
+	return selector, nil
+}
+
+// Matches reports whether the account matches every term of the selector. The OU selects the accounts of the
+// organizational unit and of its children, the name, email and tag values accept * and ? wildcards.
+func (s Selector) Matches(account aws.Account) bool {
+	for _, term := range s {
+		if !term.matches(account) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t Term) matches(account aws.Account) bool {
+	switch t.Key {
+	case OUKey:
+		ou := strings.TrimSuffix(t.Value, "/")
+		return strings.EqualFold(account.OUPath, ou) || strings.HasPrefix(strings.ToLower(account.OUPath), strings.ToLower(ou)+"/")
+	case NameKey:
+		return matchPattern(t.Value, account.Name)
+	case EmailKey:
+		return matchPattern(t.Value, account.Email)
+	case CustomizationKey:
+		return account.CustomizationsName != "" && strings.EqualFold(account.CustomizationsName, t.Value)
+	}
+
+	value, ok := account.Tags[strings.TrimPrefix(t.Key, TagPrefix)]
+	return ok && matchPattern(t.Value, value)
+}
+
+// matchPattern matches the value against the wildcard pattern ignoring the case
+func matchPattern(pattern string, value string) bool {
+	matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return matched
+}
+
+// Select returns the IDs of the accounts matching one of the selectors, in the order of the accounts.
+func Select(accounts []aws.Account, selectors []Selector) []string {
+	var ids []string
+
+	for _, account := range accounts {
+		for _, selector := range selectors {
+			if selector.Matches(account) {
+				ids = append(ids, account.ID)
+				break
+			}
+		}
+	}
+
+	return ids
+}
+
+// Accounts returns the cached accounts while they are younger than the ttl, else it lists them again and caches
+// them. A zero ttl always lists them.
+func Accounts(cacheName string, ttl time.Duration, list func() ([]aws.Account, error)) ([]aws.Account, error) {
+	var accounts []aws.Account
+
+	if ttl > 0 {
+		found, err := cache.Load(cacheName, ttl, &accounts)
+		if err != nil {
+			logging.Warnf("%v", err)
+		}
+		if found {
+			logging.Debugf("using the accounts cached in %s", cacheName)
+			return accounts, nil
+		}
+	}
+
+	accounts, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		if err := cache.Save(cacheName, accounts); err != nil {
+			logging.Warnf("%v", err)
+		}
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2023 Edgar Costa edgarsilva948@gmail.com
+*/
+
+package selector
+
+import (
+	"errors"
+	"time"
+
+	"github.com/edgarsilva948/aftctl/pkg/aws"
+	"github.com/edgarsilva948/aftctl/pkg/cache"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Selecting the accounts", func() {
+	accounts := []aws.Account{
+		{ID: "111111111111", Name: "Management", Email: "root@example.com", OUPath: "Root"},
+		{ID: "222222222222", Name: "sandbox-a", Email: "a@example.com", OUPath: "Root/Workloads/Sandbox",
+			Tags: map[string]string{"env": "dev"}, CustomizationsName: "sandbox"},
+		{ID: "333333333333", Name: "sandbox-b", Email: "b@example.com", OUPath: "Root/Workloads/Sandbox/Team",
+			Tags: map[string]string{"env": "prod"}},
+		{ID: "444444444444", Name: "production", Email: "prod@example.com", OUPath: "Root/Workloads/Production",
+			Tags: map[string]string{"env": "prod"}, CustomizationsName: "production"},
+	}
+
+	selectIDs := func(selectors ...string) []string {
+		parsed := make([]Selector, 0, len(selectors))
+		for _, s := range selectors {
+			selector, err := Parse(s)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			parsed = append(parsed, selector)
+		}
+		return Select(accounts, parsed)
+	}
+
+	ginkgo.Context("testing the Parse function", func() {
+		ginkgo.When("the selector is invalid", func() {
+			ginkgo.It("should return an error", func() {
+				for _, s := range []string{"sandbox", "ou=", "owner=team", "tag:=dev", "name=[a"} {
+					_, err := Parse(s)
+					gomega.Expect(err).To(gomega.HaveOccurred(), s)
+				}
+			})
+		})
+	})
+
+	ginkgo.Context("testing the Select function", func() {
+		ginkgo.When("selecting by OU", func() {
+			ginkgo.It("should select the accounts of the OU and of its children", func() {
+				gomega.Expect(selectIDs("ou=Root/Workloads/Sandbox")).To(gomega.Equal([]string{"222222222222", "333333333333"}))
+				gomega.Expect(selectIDs("ou=root/workloads/sandbox/team/")).To(gomega.Equal([]string{"333333333333"}))
+				gomega.Expect(selectIDs("ou=Root/Workloads/Sand")).To(gomega.BeEmpty())
+			})
+		})
+
+		ginkgo.When("selecting by name, email, tag and customization", func() {
+			ginkgo.It("should match the patterns", func() {
+				gomega.Expect(selectIDs("name=Sandbox-*")).To(gomega.Equal([]string{"222222222222", "333333333333"}))
+				gomega.Expect(selectIDs("email=prod@example.com")).To(gomega.Equal([]string{"444444444444"}))
+				gomega.Expect(selectIDs("tag:env=prod")).To(gomega.Equal([]string{"333333333333", "444444444444"}))
+				gomega.Expect(selectIDs("customization=sandbox")).To(gomega.Equal([]string{"222222222222"}))
+			})
+		})
+
+		ginkgo.When("several terms and selectors are given", func() {
+			ginkgo.It("should select the accounts matching all the terms of one of the selectors", func() {
+				gomega.Expect(selectIDs("ou=Root/Workloads, tag:env=prod")).To(gomega.Equal([]string{"333333333333", "444444444444"}))
+				gomega.Expect(selectIDs("ou=Root/Workloads/Sandbox,tag:env=prod", "name=management")).
+					To(gomega.Equal([]string{"111111111111", "333333333333"}))
+			})
+		})
+	})
+
+	ginkgo.Context("testing the Accounts function", func() {
+		ginkgo.BeforeEach(func() {
+			ginkgo.GinkgoT().Setenv(cache.EnvVar, ginkgo.GinkgoT().TempDir())
+		})
+
+		ginkgo.When("the accounts are cached", func() {
+			ginkgo.It("should only list them once", func() {
+				calls := 0
+				list := func() ([]aws.Account, error) {
+					calls++
+					return accounts, nil
+				}
+
+				for i := 0; i < 2; i++ {
+					found, err := Accounts("accounts.json", time.Hour, list)
+					gomega.Expect(err).ToNot(gomega.HaveOccurred())
+					gomega.Expect(found).To(gomega.Equal(accounts))
+				}
+				gomega.Expect(calls).To(gomega.Equal(1))
+
+				_, err := Accounts("accounts.json", 0, list)
+				gomega.Expect(err).ToNot(gomega.HaveOccurred())
+				gomega.Expect(calls).To(gomega.Equal(2))
+			})
+		})
+
+		ginkgo.When("the accounts can't be listed", func() {
+			ginkgo.It("should return the error", func() {
+				_, err := Accounts("accounts.json", time.Hour, func() ([]aws.Account, error) {
+					return nil, errors.New("access denied")
+				})
+				gomega.Expect(err).To(gomega.MatchError("access denied"))
+			})
+		})
+	})
+})
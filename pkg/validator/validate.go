@@ -7,64 +7,211 @@ package validate
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// TerraformDistributions lists the Terraform distributions supported by AFT
+var TerraformDistributions = []string{"oss", "tfc", "tfe"}
+
+// patterns of the checked values, also published in the JSON Schema of the deployment manifest
+const (
+	AccountIDPattern        = `^[0-9]{12}$`
+	RegionPattern           = `^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-[0-9]$`
+	TerraformVersionPattern = `^[0-9]+\.[0-9]+\.[0-9]+$`
+)
+
+var (
+	regionPattern           = regexp.MustCompile(RegionPattern)
+	terraformVersionPattern = regexp.MustCompile(TerraformVersionPattern)
+	sessionNamePattern      = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+	mfaTokenPattern         = regexp.MustCompile(`^[0-9]{6}$`)
 )
 
 // CheckAWSAccountID checks if a string represents a valid AWS account id
 func CheckAWSAccountID(accountID string) (bool, error) {
-	var err error
 	if len(accountID) != 12 {
-		fmt.Printf("error: account id must be 12 characters long\n")
-		return false, err
+		return false, errors.New("account id must be 12 characters long")
 	}
 
 	// Check if all characters are digits
-	_, err = strconv.ParseUint(accountID, 10, 64)
-	if err != nil {
-		return false, err
+	if _, err := strconv.ParseUint(accountID, 10, 64); err != nil {
+		return false, errors.New("account id must only contain digits")
+	}
+
+	return true, nil
+}
+
+// CheckAWSRegion checks if a string represents a valid AWS region name, e.g. us-east-1
+func CheckAWSRegion(region string) (bool, error) {
+	if !regionPattern.MatchString(region) {
+		return false, fmt.Errorf("region %q is not a valid AWS region name, e.g. us-east-1", region)
 	}
 
 	return true, nil
 }
 
-// CheckTerraformCommand checks if a string represents a valid AWS account id
-func CheckTerraformCommand(command string) (bool, error) {
-
-	if command == "" {
-		return false, errors.New("error: terraform command is required")
-	}
-
-	acceptedCommands := []string{
-		"plan",
-		"apply",
-		"apply --auto-approve",
-		"destroy",
-		"init",
-		"import",
-		"validate",
-		"output",
-		"fmt",
-		"force-unlock",
-		"get",
-		"providers",
-		"refresh",
-		"show",
-		"state",
-		"taint",
-		"untaint",
-		"version",
-		"workspace",
-	}
-
-	for _, acceptedCommand := range acceptedCommands {
-		// Check if the beginning of the command string matches an accepted command
-		if strings.HasPrefix(command, acceptedCommand) {
+// CheckTerraformVersion checks if a string represents a Terraform release, e.g. 1.5.6
+func CheckTerraformVersion(version string) (bool, error) {
+	if !terraformVersionPattern.MatchString(version) {
+		return false, fmt.Errorf("terraform version %q must be a release version, e.g. 1.5.6", version)
+	}
+
+	return true, nil
+}
+
+// CheckTerraformDistribution checks if a string is one of the Terraform distributions supported by AFT
+func CheckTerraformDistribution(distribution string) (bool, error) {
+	for _, accepted := range TerraformDistributions {
+		if distribution == accepted {
 			return true, nil
 		}
 	}
 
-	fmt.Printf("command is invalid: accepted commands are %s\n", acceptedCommands)
-	return false, errors.New("invalid command")
+	return false, fmt.Errorf("terraform distribution %q is invalid, accepted distributions are %s", distribution, TerraformDistributions)
+}
+
+// CheckSessionName checks if a string is a valid role session name
+func CheckSessionName(name string) (bool, error) {
+	if !sessionNamePattern.MatchString(name) {
+		return false, fmt.Errorf("session name %q must be 2 to 64 letters, digits or +=,.@_- characters", name)
+	}
+
+	return true, nil
+}
+
+// CheckSessionDuration checks if a duration is accepted for a role session, from 15 minutes to 12 hours
+func CheckSessionDuration(duration time.Duration) (bool, error) {
+	if duration < 15*time.Minute || duration > 12*time.Hour {
+		return false, fmt.Errorf("session duration %s must be from 15m to 12h", duration)
+	}
+
+	return true, nil
+}
+
+// CheckMFAToken checks if a string is an MFA code of 6 digits
+func CheckMFAToken(token string) (bool, error) {
+	if !mfaTokenPattern.MatchString(token) {
+		return false, errors.New("MFA token must be 6 digits")
+	}
+
+	return true, nil
+}
+
+// destroyingCommands delete resources of the target account or change its state without a reviewed plan
+var destroyingCommands = [][]string{
+	{"destroy"},
+	{"import"},
+	{"taint"},
+	{"untaint"},
+	{"force-unlock"},
+	{"state", "rm"},
+	{"state", "mv"},
+	{"state", "push"},
+	{"state", "replace-provider"},
+	{"workspace", "delete"},
+}
+
+// valueOptions are the Terraform options which may take their value as the next argument
+var valueOptions = map[string]bool{
+	"-backup":       true,
+	"-lock-timeout": true,
+	"-parallelism":  true,
+	"-replace":      true,
+	"-state":        true,
+	"-state-out":    true,
+	"-target":       true,
+	"-var":          true,
+	"-var-file":     true,
+}
+
+// TerraformSubcommand returns the Terraform subcommand of the arguments, skipping the global options such as -chdir
+func TerraformSubcommand(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+
+	return ""
+}
+
+// terraformPositionals returns the arguments which aren't options, e.g. state rm ADDRESS or apply PLAN
+func terraformPositionals(args []string) []string {
+	var positionals []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return append(positionals, args[i+1:]...)
+		case strings.HasPrefix(arg, "-"):
+			if valueOptions["-"+strings.TrimLeft(arg, "-")] {
+				i++
+			}
+		default:
+			positionals = append(positionals, arg)
+		}
+	}
+
+	return positionals
+}
+
+// isDestroyOption reports whether the argument is the -destroy option with a value Terraform reads as true, an
+// unreadable value is reported too
+func isDestroyOption(arg string) bool {
+	name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "-"), "=")
+	if name != "-destroy" && name != "destroy" {
+		return false
+	}
 
+	if !hasValue {
+		return true
+	}
+
+	destroy, err := strconv.ParseBool(value)
+
+	return err != nil || destroy
+}
+
+// CheckTerraformArgs checks the Terraform arguments against the local run policy, the commands destroying
+// resources or changing the state are denied unless allowed, and so is applying a saved plan as it may destroy
+// resources
+func CheckTerraformArgs(args []string, allowDestroy bool) (bool, error) {
+	if len(args) == 0 {
+		return false, errors.New("terraform arguments are required, e.g. aftctl local -a ACCOUNT -- plan")
+	}
+
+	if allowDestroy {
+		return true, nil
+	}
+
+	positionals := terraformPositionals(args)
+
+	for _, denied := range destroyingCommands {
+		if len(positionals) >= len(denied) && slices.Equal(positionals[:len(denied)], denied) {
+			return false, fmt.Errorf("terraform %s is denied, use --allow-destroy to run it", strings.Join(denied, " "))
+		}
+	}
+
+	if len(positionals) == 0 || positionals[0] != "apply" {
+		return true, nil
+	}
+
+	// apply -destroy is the same as destroy
+	for _, arg := range args {
+		if isDestroyOption(arg) {
+			return false, errors.New("terraform apply -destroy is denied, use --allow-destroy to run it")
+		}
+	}
+
+	if len(positionals) > 1 {
+		return false, fmt.Errorf("terraform apply of the saved plan %s is denied as it may destroy resources, use --allow-destroy to run it", positionals[1])
+	}
+
+	return true, nil
 }